@@ -5,23 +5,31 @@ import (
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"log"
 	"os"
 	"path/filepath"
 	"regexp"
 	"strings"
 
+	componentspkg "github.com/ContainerSolutions/helm-convert/pkg/components"
 	"github.com/ContainerSolutions/helm-convert/pkg/generators"
+	gitopspkg "github.com/ContainerSolutions/helm-convert/pkg/gitops"
 	"github.com/ContainerSolutions/helm-convert/pkg/helm"
+	overlayspkg "github.com/ContainerSolutions/helm-convert/pkg/overlays"
 	"github.com/ContainerSolutions/helm-convert/pkg/transformers"
 	"github.com/ContainerSolutions/helm-convert/pkg/types"
+	"github.com/ContainerSolutions/helm-convert/pkg/utils"
+	"github.com/ghodss/yaml"
 	"github.com/golang/glog"
 	"github.com/spf13/cobra"
 	"google.golang.org/grpc/status"
+	yamlv2 "gopkg.in/yaml.v2"
 	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
 	helm_env "k8s.io/helm/pkg/helm/environment"
 	"k8s.io/helm/pkg/helm/helmpath"
 	"k8s.io/helm/pkg/hooks"
+	"k8s.io/helm/pkg/proto/hapi/chart"
 	"sigs.k8s.io/kustomize/k8sdeps/kunstruct"
 	"sigs.k8s.io/kustomize/pkg/resource"
 	ktypes "sigs.k8s.io/kustomize/pkg/types"
@@ -49,8 +57,37 @@ type convertCmd struct {
 	skipTransformers []string
 	version          string
 	depUp            bool
+	plainHTTP        bool
 	forceGen         bool
 	comments         bool
+	maxLiteralLength int
+	dataSourceConfig string
+	layout           string
+	filenameTemplate string
+	onExists         string
+	output           string
+	outputFormat     string
+	components       []string
+	kustomizeVersion string
+	mode             string
+	gitops           string
+	gitopsRepo       string
+	gitopsPath       string
+	lifecycle        string
+	reportFile       string
+	originAnnotation bool
+	transformerOpts  []string
+	kubeVersion      string
+	kubeVersions     []string
+	apiVersions      []string
+	apiVersionsFile  string
+	skipSchema       bool
+	clusterVersions  []string
+	lookupFixtures   string
+	fixtures         *helm.LookupFixtures
+	explain          bool
+	explainDir       string
+	report           *types.Report
 
 	username string
 	password string
@@ -76,11 +113,26 @@ const convertExample = `
   # convert chart from a url
   helm convert https://s3-eu-west-1.amazonaws.com/coreos-charts/stable/prometheus-operator
 
+  # convert a chart stored in an OCI registry
+  helm convert --version 1.2.3 oci://registry.example.com/charts/app
+
   # convert the stable/mongodb chart with a given values.yaml file
   helm convert -f values.yaml stable/mongodb
 
   # convert the stable/mongodb chart and override values using --set flag:
   helm convert --set persistence.enabled=true stable/mongodb
+
+  # stream the converted stable/mongodb chart as a tar archive to stdout
+  helm convert --output - stable/mongodb | tar -x -C mongodb
+
+  # upgrade a converted chart to a new version, keeping local edits
+  helm convert --destination mongodb --on-exists upgrade --version 5.0.0 stable/mongodb
+
+  # generate a component enabling the metrics of the chart
+  helm convert --component metrics=metrics.enabled=true stable/mongodb
+
+  # write the converted stable/mongodb chart into a gzipped tar archive
+  helm convert --output mongodb.tgz stable/mongodb
 `
 
 // NewConvertCommand constructs a new convert command
@@ -90,7 +142,7 @@ func NewConvertCommand() *cobra.Command {
 	}
 
 	c := &cobra.Command{
-		Use:     "convert [flag] [chart URL | repo/chartname] [...]",
+		Use:     "convert [flag] [chart URL | repo/chartname | oci://registry/repository] [...]",
 		Short:   "convert a chart",
 		Long:    convertDesc,
 		Example: convertExample,
@@ -123,6 +175,12 @@ func NewConvertCommand() *cobra.Command {
 	f.BoolVar(&k.verify, "verify", false, "verify the package against its signature")
 	f.BoolVar(&k.verifyLater, "prov", false, "fetch the provenance file, but don't perform verification")
 	f.StringVar(&k.namespace, "namespace", "default", "global namespace to use for the manifests")
+	f.StringVar(&k.kubeVersion, "kube-version", "", "Kubernetes version used for Capabilities.KubeVersion, ie: 1.25 or v1.25.3 (default depends on the chart apiVersion)")
+	f.StringSliceVar(&k.kubeVersions, "kube-versions", []string{}, fmt.Sprintf("render the chart for each Kubernetes version, ie: 1.21,1.25,1.28. The resources identical for all the versions are written in %s/, the other ones in an overlay per version in %s/k8s-<version>/", overlayspkg.BaseDirectory, overlayspkg.Directory))
+	f.StringArrayVarP(&k.apiVersions, "api-versions", "a", []string{}, "Kubernetes API versions used for Capabilities.APIVersions, in addition to the defaults (can specify multiple)")
+	f.BoolVar(&k.skipSchema, "skip-schema-validation", false, "don't validate the values against the values.schema.json of the chart and its subcharts")
+	f.StringVar(&k.lookupFixtures, "lookup-fixtures", "", "file or directory of YAML objects the lookup template function resolves against, ie: the output of kubectl get secret -o yaml. Without this, lookup returns empty objects")
+	f.StringVar(&k.apiVersionsFile, "api-versions-file", "", "file listing the API versions of a cluster used for Capabilities.APIVersions instead of the defaults, either the output of kubectl api-versions or a discovery document such as the output of kubectl get --raw /apis")
 	f.StringVar(&k.version, "version", "", "specific version of a chart. Without this, the latest version is fetched")
 	f.StringVar(&k.keyring, "keyring", defaultKeyring(), "keyring containing public keys")
 	f.StringVarP(&k.destination, "destination", "d", "", "location to write the chart. If this and tardir are specified, tardir is appended to this")
@@ -131,10 +189,30 @@ func NewConvertCommand() *cobra.Command {
 	f.StringVar(&k.keyFile, "key-file", "", "identify HTTPS client using this SSL key file")
 	f.StringVar(&k.caFile, "ca-file", "", "verify certificates of HTTPS-enabled servers using this CA bundle")
 	f.BoolVar(&k.depUp, "dep-up", false, "run helm dependency update before installing the chart")
-	f.BoolVar(&k.forceGen, "force", false, "convert chart even if the destination directory already exists")
+	f.BoolVar(&k.plainHTTP, "plain-http", false, "use insecure HTTP connections to pull charts from an OCI registry")
+	f.BoolVar(&k.forceGen, "force", false, "convert chart even if the destination directory already exists, same as --on-exists=overwrite")
+	f.StringVar(&k.onExists, "on-exists", string(generators.OnExistsPrompt), fmt.Sprintf("what to do if the destination directory already exists, one of %v. With clean, files generated by a previous conversion which are not generated anymore are removed. With upgrade, local edits are three-way merged with the new conversion", generators.OnExistsPolicies))
 	f.StringVar(&k.username, "username", "", "chart repository username")
 	f.StringVar(&k.password, "password", "", "chart repository password")
 	f.BoolVar(&k.comments, "comments", true, "add default comments to kustomization.yaml file")
+	f.IntVar(&k.maxLiteralLength, "max-literal-length", 0, "maximum length of a configmap or secret value stored as literal, longer values are stored as file (0 means no limit)")
+	f.StringVar(&k.layout, "layout", string(utils.LayoutFlat), fmt.Sprintf("layout of the manifests in the destination directory, one of %v", utils.Layouts))
+	f.StringVar(&k.filenameTemplate, "filename-template", utils.DefaultFilenameTemplate, "Go template used to name manifest files, available fields: .Kind, .Abbreviation, .Name, .Namespace, .Group, .Version, .Template and .DocumentIndex")
+	f.StringVarP(&k.output, "output", "o", "", "write the package as an archive instead of a directory, '-' stream it to stdout. The format is deduced from the extension (.tar, .tgz, .tar.gz, .zip, .yaml) unless --output-format is set")
+	f.StringVar(&k.outputFormat, "output-format", "", fmt.Sprintf("format of the package written with --output, one of %v (default tar when streaming to stdout)", generators.OutputFormats))
+	f.StringArrayVar(&k.components, "component", []string{}, "generate a kustomize Component from the difference between the chart rendered with and without a value, using the format name=key=value, ie: metrics=metrics.enabled=true (can specify multiple)")
+	f.StringVar(&k.mode, "mode", string(generators.ModeKustomize), fmt.Sprintf("kind of package to generate, one of %v. A kpt package contains a Kptfile with a function pipeline instead of a kustomization.yaml, configmaps and secrets are kept as resources. With inflate, the chart is inflated by kustomize using the helmCharts field and the cleanups are applied as patches", generators.Modes))
+	f.StringVar(&k.gitops, "gitops", "", fmt.Sprintf("generate the objects deploying the package in %s, one of %v. CRDs and Helm hooks are written in their own kustomization, applied before or after the other resources", gitopspkg.Directory, gitopspkg.Tools))
+	f.StringVar(&k.gitopsRepo, "gitops-repo", "", fmt.Sprintf("URL of the git repository with --gitops=argocd, name of the GitRepository with --gitops=flux (default %s)", gitopspkg.DefaultFluxSource))
+	f.StringVar(&k.gitopsPath, "gitops-path", "", "path of the package in the git repository (default the destination directory)")
+	f.StringVar(&k.lifecycle, "lifecycle", "", fmt.Sprintf("translate the Helm resource policy, hook, hook weight and hook delete policy annotations for one of %v instead of only removing them (default the --gitops tool)", transformers.Lifecycles))
+	f.StringVar(&k.reportFile, "report", "", "write a report of the conversion: the labels and annotations removed, the common labels, namespace and images moved to the kustomization, the configmaps and secrets converted to generators, the relocated hooks, the skipped templates and the warnings. The format is deduced from the extension, .json or .md")
+	f.BoolVar(&k.originAnnotation, "origin-annotation", false, fmt.Sprintf("annotate each resource with the path of the template which rendered it and its document index (%s)", transformers.OriginAnnotation))
+	f.StringArrayVar(&k.transformerOpts, "transformer-option", []string{}, "option given to the transformers in their context, using the format key=value (can specify multiple)")
+	f.BoolVar(&k.explain, "explain", false, "print the unified diff of the kustomization and the resources made by each transformer, with its duration")
+	f.StringVar(&k.explainDir, "explain-dir", "", "write the diff made by each transformer in the given directory instead of printing it, implies --explain")
+	f.StringVar(&k.kustomizeVersion, "kustomize-version", generators.KustomizeV2.String(), fmt.Sprintf("major version of kustomize the kustomization files are written for, one of %v. v3 and later add apiVersion and kind and use resources, patches and envs instead of bases, patchesStrategicMerge, patchesJson6902 and env, v4 use labels and replacements instead of commonLabels and vars, v5 add sortOptions to keep the Helm install order", generators.KustomizeVersions))
+	f.StringVar(&k.dataSourceConfig, "datasource-config", "", "YAML file defining per resource literal length threshold and key overrides (literal, file or env) for configmaps and secrets")
 
 	// log to stderr by default,
 	flag.Set("logtostderr", "true")
@@ -146,7 +224,47 @@ func NewConvertCommand() *cobra.Command {
 }
 
 func (k *convertCmd) run() error {
-	h := helm.NewHelm(settings, k.out)
+	h := helm.NewHelm(settings, k.messages())
+	h.SetPlainHTTP(k.plainHTTP)
+
+	namer, err := utils.NewFileNamer(utils.Layout(k.layout), k.filenameTemplate)
+	if err != nil {
+		return err
+	}
+
+	kustomizeVersion, err := generators.ParseKustomizeVersion(k.kustomizeVersion)
+	if err != nil {
+		return err
+	}
+
+	if k.reportFile != "" {
+		if _, err := reportFormat(k.reportFile); err != nil {
+			return err
+		}
+	}
+
+	if len(k.kubeVersions) > 0 {
+		if err := k.checkKubeVersions(); err != nil {
+			return err
+		}
+	}
+
+	if k.apiVersionsFile != "" {
+		k.clusterVersions, err = helm.LoadAPIVersions(k.apiVersionsFile)
+		if err != nil {
+			return err
+		}
+	}
+
+	if k.lookupFixtures != "" {
+		if generators.Mode(k.mode) == generators.ModeInflate {
+			return fmt.Errorf("--lookup-fixtures is not supported with --mode %s", generators.ModeInflate)
+		}
+		k.fixtures, err = helm.LoadLookupFixtures(k.lookupFixtures)
+		if err != nil {
+			return err
+		}
+	}
 
 	glog.V(8).Infof("Using settings %#v", settings)
 
@@ -178,102 +296,542 @@ func (k *convertCmd) run() error {
 		k.name = chartRequested.Metadata.Name
 	}
 
+	if k.reportFile != "" {
+		k.report = types.NewReport(chartRequested.Metadata.Name, chartRequested.Metadata.Version)
+	}
+
+	dataSourceOptions, err := k.loadDataSourceOptions()
+	if err != nil {
+		return err
+	}
+
+	if generators.Mode(k.mode) == generators.ModeInflate {
+		if len(k.components) > 0 {
+			return fmt.Errorf("components cannot be generated with --mode %s", generators.ModeInflate)
+		}
+		return k.inflate(h, chartRequested, namer, kustomizeVersion, dataSourceOptions)
+	}
+
+	if len(k.kubeVersions) > 0 {
+		return k.convertVersions(h, chartRequested, namer, kustomizeVersion, dataSourceOptions)
+	}
+
+	// convert the chart with the given values
+	config, resources, result, err := k.convert(h, chartRequested, namer, dataSourceOptions, k.kubeVersion, nil)
+	if err != nil {
+		return err
+	}
+
+	// convert the chart with each feature enabled and keep the difference as
+	// a component
+	var components []*types.Component
+	for _, c := range k.components {
+		feature, err := componentspkg.ParseFeature(c)
+		if err != nil {
+			return err
+		}
+
+		glog.V(4).Infof("Converting chart with %s to build component %s", feature.Value, feature.Name)
+
+		featureConfig, featureResources, _, err := k.convert(h, chartRequested, namer, dataSourceOptions,
+			k.kubeVersion, []string{feature.Value})
+		if err != nil {
+			return err
+		}
+
+		component, err := componentspkg.Diff(feature.Name, config, featureConfig, resources, featureResources, namer)
+		if err != nil {
+			return err
+		}
+		components = append(components, component)
+	}
+
+	// write to disk or archive
+	generator, err := k.newGenerator(namer, kustomizeVersion)
+	if err != nil {
+		return err
+	}
+	generator.SetArtifacts(result.Artifacts)
+
+	err = k.setGitOps(generator, config, resources)
+	if err != nil {
+		return err
+	}
+
+	if k.gitops != "" {
+		k.reportHooks(resources)
+	}
+
+	err = generator.Render(config, chartRequested.Metadata, resources, components, k.comments)
+	if err != nil {
+		return err
+	}
+
+	return k.writeReport()
+}
+
+// inflate write a kustomization inflating the chart with kustomize, the
+// changes made by the transformers are written as patches
+func (k *convertCmd) inflate(h *helm.Helm, chartRequested *chart.Chart, namer *utils.FileNamer,
+	kustomizeVersion generators.KustomizeVersion, dataSourceOptions *transformers.DataSourceOptions) error {
+	resources, err := k.render(h, chartRequested, k.kubeVersion, nil)
+	if err != nil {
+		return err
+	}
+
+	// keep a copy of the rendered resources, transformers modify them
+	rendered := types.NewResources()
+	for id, res := range resources.ResMap {
+		rendered.ResMap[id] = res.DeepCopy()
+		rendered.Origins[id] = resources.Origins[id]
+		rendered.FieldOrders[id] = resources.FieldOrders[id]
+	}
+
+	config, result, err := k.transform(chartRequested.Metadata, resources, namer, dataSourceOptions, true)
+	if err != nil {
+		return err
+	}
+
+	inflation, err := k.inflation(h, chartRequested)
+	if err != nil {
+		return err
+	}
+	inflation.Rendered = rendered
+
+	generator, err := k.newGenerator(namer, kustomizeVersion)
+	if err != nil {
+		return err
+	}
+	generator.SetArtifacts(result.Artifacts)
+
+	err = k.setGitOps(generator, config, resources)
+	if err != nil {
+		return err
+	}
+
+	err = generator.RenderInflation(config, chartRequested.Metadata, inflation, resources, k.comments)
+	if err != nil {
+		return err
+	}
+
+	return k.writeReport()
+}
+
+// convertVersions convert the chart for each Kubernetes version of
+// --kube-versions, the resources shared by all the versions are written in the
+// base and the other ones in an overlay per version
+func (k *convertCmd) convertVersions(h *helm.Helm, chartRequested *chart.Chart, namer *utils.FileNamer,
+	kustomizeVersion generators.KustomizeVersion, dataSourceOptions *transformers.DataSourceOptions) error {
+	var conversions []*overlayspkg.Conversion
+	var result *transformers.Result
+	for _, kubeVersion := range k.kubeVersions {
+		glog.V(4).Infof("Converting chart for Kubernetes %s", kubeVersion)
+
+		config, resources, r, err := k.convert(h, chartRequested, namer, dataSourceOptions, kubeVersion, nil)
+		if err != nil {
+			return err
+		}
+		if result == nil {
+			result = r
+		}
+		conversions = append(conversions, &overlayspkg.Conversion{
+			KubeVersion: kubeVersion,
+			Config:      config,
+			Resources:   resources,
+		})
+	}
+
+	config, resources, overlays, err := overlayspkg.Split(conversions, namer)
+	if err != nil {
+		return err
+	}
+
+	generator, err := k.newGenerator(namer, kustomizeVersion)
+	if err != nil {
+		return err
+	}
+	generator.SetArtifacts(result.Artifacts)
+	generator.SetOverlays(overlays)
+
+	err = generator.Render(config, chartRequested.Metadata, resources, nil, k.comments)
+	if err != nil {
+		return err
+	}
+
+	return k.writeReport()
+}
+
+// checkKubeVersions parse --kube-versions and return an error if it is used
+// with an option which doesn't support overlays
+func (k *convertCmd) checkKubeVersions() error {
+	versions, err := overlayspkg.ParseVersions(k.kubeVersions)
+	if err != nil {
+		return err
+	}
+	k.kubeVersions = versions
+
+	switch {
+	case k.kubeVersion != "":
+		return fmt.Errorf("--kube-version and --kube-versions cannot be used together")
+	case len(k.components) > 0:
+		return fmt.Errorf("components cannot be generated with --kube-versions")
+	case k.gitops != "":
+		return fmt.Errorf("--gitops is not supported with --kube-versions")
+	case k.apiVersionsFile != "":
+		return fmt.Errorf("--api-versions-file lists the API versions of a single cluster, it cannot be used with --kube-versions")
+	case generators.Mode(k.mode) != generators.ModeKustomize:
+		return fmt.Errorf("--kube-versions is not supported with --mode %s", k.mode)
+	}
+	return nil
+}
+
+// primary return true for the conversion which is reported and explained, the
+// one with the given values and the first Kubernetes version of --kube-versions
+func (k *convertCmd) primary(kubeVersion string, extraValues []string) bool {
+	if len(extraValues) > 0 {
+		return false
+	}
+	return len(k.kubeVersions) == 0 || kubeVersion == k.kubeVersions[0]
+}
+
+// setGitOps configure the generation of the GitOps objects, the resources are
+// deployed to the namespace found by the transformers, the namespace shared by
+// the resources when the namespace transformer is skipped, or the release
+// namespace
+func (k *convertCmd) setGitOps(generator *generators.Generator, config *ktypes.Kustomization,
+	resources *types.Resources) error {
+	if k.gitops == "" {
+		return nil
+	}
+
+	tool, err := gitopspkg.ParseTool(k.gitops)
+	if err != nil {
+		return err
+	}
+	if generators.Mode(k.mode) == generators.ModeKpt {
+		return fmt.Errorf("--gitops is not supported with --mode %s", generators.ModeKpt)
+	}
+
+	options := &gitopspkg.Options{
+		Tool:      tool,
+		Name:      k.name,
+		Namespace: config.Namespace,
+		Repo:      k.gitopsRepo,
+		Path:      k.gitopsPath,
+	}
+	if options.Namespace == "" {
+		options.Namespace = transformers.CommonNamespace(resources)
+	}
+	if options.Namespace == "" {
+		options.Namespace = k.namespace
+	}
+	if options.Path == "" {
+		options.Path = filepath.ToSlash(filepath.Clean(k.destination))
+	}
+	if options.Repo == "" {
+		if tool == gitopspkg.ArgoCD {
+			return fmt.Errorf("--gitops-repo is required with --gitops=%s", tool)
+		}
+		options.Repo = gitopspkg.DefaultFluxSource
+	}
+
+	generator.SetGitOps(options)
+	return nil
+}
+
+// inflation return the helmCharts entry inflating the chart with the merged
+// values given on the command line
+func (k *convertCmd) inflation(h *helm.Helm, chartRequested *chart.Chart) (*types.Inflation, error) {
+	repo, err := h.ChartRepository(k.repoURL, k.chart)
+	if err != nil {
+		return nil, err
+	}
+
+	rawVals, err := h.Vals(k.valueFiles, k.values, k.stringValues, k.fileValues, k.certFile, k.keyFile, k.caFile)
+	if err != nil {
+		return nil, err
+	}
+
+	values := map[string]interface{}{}
+	if err := yaml.Unmarshal(rawVals, &values); err != nil {
+		return nil, err
+	}
+
+	inflation := &types.Inflation{
+		Chart: &types.HelmChart{
+			Name:         chartRequested.Metadata.Name,
+			Repo:         repo,
+			Version:      chartRequested.Metadata.Version,
+			ReleaseName:  k.name,
+			Namespace:    k.namespace,
+			ValuesInline: values,
+			KubeVersion:  k.kubeVersion,
+			APIVersions:  append(append([]string{}, k.clusterVersions...), k.apiVersions...),
+			// the CRDs of the crds directories are in the rendered
+			// resources, kustomize must inflate them too
+			IncludeCRDs: helm.HasCRDs(chartRequested),
+		},
+	}
+
+	// local charts are looked up in the chart home, relative to the
+	// kustomization
+	if repo == "" {
+		chartHome, err := localChartHome(k.chart, k.destination)
+		if err != nil {
+			return nil, err
+		}
+		if filepath.Base(filepath.Clean(k.chart)) != chartRequested.Metadata.Name {
+			glog.Warningf("Kustomize looks up local charts by name, rename %s to %s",
+				k.chart, chartRequested.Metadata.Name)
+		}
+		inflation.Globals = &types.HelmGlobals{ChartHome: chartHome}
+	}
+
+	return inflation, nil
+}
+
+// newGenerator return a generator writing to the destination directory or
+// archive
+func (k *convertCmd) newGenerator(namer *utils.FileNamer, kustomizeVersion generators.KustomizeVersion) (*generators.Generator, error) {
+	fs, err := generators.NewFileSystem(k.destination, k.output, generators.OutputFormat(k.outputFormat), k.out)
+	if err != nil {
+		return nil, err
+	}
+
+	onExists := generators.OnExists(k.onExists)
+	if k.forceGen && onExists == generators.OnExistsPrompt {
+		onExists = generators.OnExistsOverwrite
+	}
+
+	return generators.NewGenerator(fs, onExists, namer, kustomizeVersion, generators.Mode(k.mode))
+}
+
+// convert render the chart for a Kubernetes version with the given values
+// plus the extra --set values, then gather the kustomization config via
+// transformers
+func (k *convertCmd) convert(h *helm.Helm, chartRequested *chart.Chart, namer *utils.FileNamer,
+	dataSourceOptions *transformers.DataSourceOptions, kubeVersion string, extraValues []string) (*ktypes.Kustomization,
+	*types.Resources, *transformers.Result, error) {
+	resources, err := k.render(h, chartRequested, kubeVersion, extraValues)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	config, result, err := k.transform(chartRequested.Metadata, resources, namer, dataSourceOptions,
+		k.primary(kubeVersion, extraValues))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return config, resources, result, nil
+}
+
+// render the chart for a Kubernetes version with the given values plus the
+// extra --set values
+func (k *convertCmd) render(h *helm.Helm, chartRequested *chart.Chart, kubeVersion string,
+	extraValues []string) (*types.Resources, error) {
 	// render charts with given values
 	renderedManifests, err := h.RenderChart(&helm.RenderChartConfig{
-		ChartRequested: chartRequested,
-		Name:           k.name,
-		Namespace:      k.namespace,
-		ValueFiles:     k.valueFiles,
-		Values:         k.values,
-		StringValues:   k.stringValues,
-		FileValues:     k.fileValues,
+		ChartRequested:       chartRequested,
+		Name:                 k.name,
+		Namespace:            k.namespace,
+		ValueFiles:           k.valueFiles,
+		Values:               append(append([]string{}, k.values...), extraValues...),
+		StringValues:         k.stringValues,
+		FileValues:           k.fileValues,
+		KubeVersion:          kubeVersion,
+		ClusterAPIVersions:   k.clusterVersions,
+		APIVersions:          k.apiVersions,
+		SkipSchemaValidation: k.skipSchema,
+		LookupFixtures:       k.fixtures,
 	})
 	if err != nil {
-		return prettyError(err)
+		return nil, prettyError(err)
 	}
 
-	// convert Yaml to resource
+	// convert Yaml to resource, only the conversion with the given values is
+	// reported, not the ones building components or the overlays of the other
+	// Kubernetes versions
 	resources := types.NewResources()
+	if k.primary(kubeVersion, extraValues) {
+		resources.Report = k.report
+	}
 	for _, m := range renderedManifests {
 		data := m.Content
 		b := filepath.Base(m.Name)
 		if b == "NOTES.txt" {
+			resources.Report.Skip(m.Name, "notes")
 			continue
 		}
 		if whitespaceRegex.MatchString(data) {
+			resources.Report.Skip(m.Name, "empty")
 			continue
 		}
 		if strings.HasPrefix(b, "_") {
+			resources.Report.Skip(m.Name, "partial")
 			continue
 		}
 
-		resList, err := newResources([]byte(data))
+		resList, documents, err := newResources([]byte(data))
+		if err != nil {
+			glog.Fatalf("Error converting yaml of template %s to resources: %v", m.Name, err)
+		}
+
+		fieldOrders, err := newFieldOrders([]byte(data))
 		if err != nil {
-			glog.Fatalf("Error converting yaml to resources: %v", err)
+			resources.Report.Warningf("Couldn't retrieve the field order of template %s: %v", m.Name, err)
 		}
-		for _, r := range resList {
+		for i, r := range resList {
+			origin := &types.Origin{
+				Template:      m.Name,
+				DocumentIndex: documents[i],
+				Hook:          r.GetAnnotations()[hooks.HookAnno],
+			}
+			if other, exists := resources.Origins[r.Id()]; exists {
+				return nil, fmt.Errorf("duplicate resource %s rendered by %s and %s",
+					types.ResourceRef(r.Id()), other, origin)
+			}
 			resources.ResMap[r.Id()] = r
+			resources.Origins[r.Id()] = origin
+			resources.FieldOrders[r.Id()] = fieldOrders[resourceKey(r.Map())]
+			resources.Report.Resource(types.ResourceRef(r.Id()), origin)
 		}
 	}
 
+	return resources, nil
+}
+
+// transform gather the kustomization config and the artifacts via
+// transformers, the changes of each transformer are explained if requested
+func (k *convertCmd) transform(metadata *chart.Metadata, resources *types.Resources, namer *utils.FileNamer,
+	dataSourceOptions *transformers.DataSourceOptions, explain bool) (*ktypes.Kustomization, *transformers.Result, error) {
 	config := &ktypes.Kustomization{}
 
+	options, err := parseOptions(k.transformerOpts)
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx := transformers.NewContext(metadata, k.name, k.namespace, options)
+
+	lifecycle := k.lifecycle
+	if lifecycle == "" {
+		lifecycle = k.gitops
+	}
+	lifecycleTarget, err := transformers.ParseLifecycle(lifecycle)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	defaultTransfomers := []transformers.Transformer{
 		transformers.NewLabelsTransformer([]string{"chart", "release", "heritage"}),
 		transformers.NewAnnotationsTransformer([]string{
 			hooks.HookAnno,
 			hooks.HookWeightAnno,
 			hooks.HookDeleteAnno,
-		}),
+		}, lifecycleTarget),
+		transformers.NewNamespaceTransformer(),
 		transformers.NewImageTransformer(),
-		transformers.NewConfigMapTransformer(),
-		transformers.NewSecretTransformer(),
+		transformers.NewConfigMapTransformer(dataSourceOptions),
+		transformers.NewSecretTransformer(dataSourceOptions),
 		transformers.NewNamePrefixTransformer(),
-		transformers.NewResourcesTransformer(),
+		transformers.NewResourcesTransformer(namer),
+		transformers.NewNormalizeTransformer(),
 		transformers.NewEmptyTransformer(),
 	}
+	if k.originAnnotation {
+		defaultTransfomers = append(defaultTransfomers, transformers.NewOriginTransformer())
+	}
 
-	// load transformers
-	var r []transformers.Transformer
-	if len(k.skipTransformers) > 0 {
-		skipMap := make(map[string]struct{}, len(k.skipTransformers))
-		for _, s := range k.skipTransformers {
-			skipMap[strings.ToLower(s)] = struct{}{}
-		}
+	// kpt packages don't support generators, configmaps and secrets are kept
+	// as resources. Inflated charts are rendered by kustomize with the
+	// resources of the chart, their original names and the release namespace.
+	skipTransformers := k.skipTransformers
+	switch generators.Mode(k.mode) {
+	case generators.ModeKpt:
+		skipTransformers = append(append([]string{}, skipTransformers...), "configmap", "secret")
+	case generators.ModeInflate:
+		skipTransformers = append(append([]string{}, skipTransformers...), "configmap", "secret", "nameprefix", "resources", "namespace")
+	}
 
-		r = make([]transformers.Transformer, 0, len(defaultTransfomers))
-		for _, dt := range defaultTransfomers {
-			if _, ok := skipMap[transformerName(dt)]; !ok {
-				r = append(r, dt)
-			}
+	// load transformers, the existing ones are adapted to receive the context
+	skipMap := make(map[string]struct{}, len(skipTransformers))
+	for _, s := range skipTransformers {
+		skipMap[strings.ToLower(s)] = struct{}{}
+	}
+
+	r := make([]transformers.TransformerV2, 0, len(defaultTransfomers))
+	for _, dt := range defaultTransfomers {
+		if _, ok := skipMap[transformers.Name(dt)]; !ok {
+			r = append(r, transformers.AdaptTransformer(dt))
 		}
-	} else {
-		r = defaultTransfomers
 	}
 
 	// gather kustomization config via transformers
-	err = transformers.NewMultiTransformer(r).Transform(config, resources)
+	var explainer transformers.Explainer
+	if explain && (k.explain || k.explainDir != "") {
+		explainer = k.explainer()
+	}
+	result, err := transformers.NewMultiTransformerV2(r, explainer).Apply(ctx, config, resources)
 	if err != nil {
 		log.Fatalf("Error: %v", err)
 	}
 
-	// write to disk
-	generator := generators.NewGenerator(k.forceGen)
-	err = generator.Render(k.destination, config, chartRequested.Metadata, resources, k.comments)
+	return config, result, nil
+}
+
+// localChartHome return the directory containing a local chart, relative to
+// the destination directory
+func localChartHome(chartPath, destination string) (string, error) {
+	home, err := filepath.Abs(filepath.Dir(filepath.Clean(chartPath)))
 	if err != nil {
-		return err
+		return "", err
+	}
+	dest, err := filepath.Abs(destination)
+	if err != nil {
+		return "", err
 	}
+	return filepath.Rel(dest, home)
+}
 
-	return nil
+// loadDataSourceOptions load the datasource config file, if any, and apply
+// the global literal length threshold
+func (k *convertCmd) loadDataSourceOptions() (*transformers.DataSourceOptions, error) {
+	options := &transformers.DataSourceOptions{}
+
+	if k.dataSourceConfig != "" {
+		data, err := ioutil.ReadFile(k.dataSourceConfig)
+		if err != nil {
+			return nil, err
+		}
+		if err := yaml.Unmarshal(data, options); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %s", k.dataSourceConfig, err)
+		}
+	}
+
+	if k.maxLiteralLength > 0 {
+		options.MaxLiteralLength = k.maxLiteralLength
+	}
+
+	if err := options.Validate(); err != nil {
+		if k.dataSourceConfig != "" {
+			return nil, fmt.Errorf("invalid %s: %v", k.dataSourceConfig, err)
+		}
+		return nil, err
+	}
+
+	return options, nil
 }
 
-func newResources(in []byte) ([]*resource.Resource, error) {
+// newResources return the resources of a multi-document YAML and the index
+// of the document of each resource, items of a list share the same index
+func newResources(in []byte) ([]*resource.Resource, []int, error) {
 	decoder := k8syaml.NewYAMLOrJSONDecoder(bytes.NewReader(in), 1024)
 	rf := resource.NewFactory(kunstruct.NewKunstructuredFactoryImpl())
 
 	var result []*resource.Resource
+	var documents []int
 	var err error
-	for err == nil || isEmptyYamlError(err) {
+	for index := 0; err == nil || isEmptyYamlError(err); index++ {
 		var out map[string]interface{}
 		err = decoder.Decode(&out)
 		if err == nil {
@@ -286,17 +844,82 @@ func newResources(in []byte) ([]*resource.Resource, error) {
 				for _, i := range list {
 					if item, ok := i.(map[string]interface{}); ok {
 						result = append(result, rf.FromMap(item))
+						documents = append(documents, index)
 					}
 				}
 			} else {
 				result = append(result, rf.FromMap(out))
+				documents = append(documents, index)
 			}
 		}
 	}
 	if err != io.EOF {
-		return nil, err
+		return nil, nil, err
+	}
+	return result, documents, nil
+}
+
+// newFieldOrders return the order in which fields are declared for each
+// document of a manifest, indexed by resourceKey
+func newFieldOrders(in []byte) (map[string]*types.FieldOrder, error) {
+	decoder := yamlv2.NewDecoder(bytes.NewReader(in))
+
+	result := make(map[string]*types.FieldOrder)
+	for {
+		var doc yamlv2.MapSlice
+		err := decoder.Decode(&doc)
+		if err == io.EOF {
+			return result, nil
+		}
+		if err != nil {
+			return result, err
+		}
+
+		docs := []yamlv2.MapSlice{doc}
+		for _, item := range doc {
+			if item.Key != "items" {
+				continue
+			}
+			if list, ok := item.Value.([]interface{}); ok {
+				docs = docs[:0]
+				for _, i := range list {
+					if m, ok := i.(yamlv2.MapSlice); ok {
+						docs = append(docs, m)
+					}
+				}
+			}
+		}
+
+		for _, d := range docs {
+			metadata, _ := mapSliceValue(d, "metadata").(yamlv2.MapSlice)
+			obj := map[string]interface{}{
+				"apiVersion": mapSliceValue(d, "apiVersion"),
+				"kind":       mapSliceValue(d, "kind"),
+				"metadata": map[string]interface{}{
+					"name":      mapSliceValue(metadata, "name"),
+					"namespace": mapSliceValue(metadata, "namespace"),
+				},
+			}
+			result[resourceKey(obj)] = types.NewFieldOrder(d)
+		}
 	}
-	return result, nil
+}
+
+// mapSliceValue return the value of a key from an ordered map
+func mapSliceValue(m yamlv2.MapSlice, key string) interface{} {
+	for _, item := range m {
+		if item.Key == key {
+			return item.Value
+		}
+	}
+	return nil
+}
+
+// resourceKey return a key identifying a resource by its apiVersion, kind,
+// namespace and name
+func resourceKey(obj map[string]interface{}) string {
+	metadata, _ := obj["metadata"].(map[string]interface{})
+	return fmt.Sprintf("%v|%v|%v|%v", obj["apiVersion"], obj["kind"], metadata["namespace"], metadata["name"])
 }
 
 func isEmptyYamlError(err error) bool {
@@ -328,11 +951,94 @@ func isList(res map[string]interface{}) ([]interface{}, bool) {
 	return items, ok
 }
 
-func transformerName(t transformers.Transformer) string {
-	return strings.ToLower(
-		strings.TrimSuffix(
-			strings.TrimPrefix(
-				fmt.Sprintf("%T", t),
-				"*transformers."),
-			"Transformer"))
+// reportHooks record the hooks written in their own directory with --gitops
+func (k *convertCmd) reportHooks(resources *types.Resources) {
+	for _, stage := range gitopspkg.Split(resources) {
+		if stage.Directory == "" {
+			continue
+		}
+		for _, id := range utils.SortedResIds(stage.Resources.ResMap) {
+			if origin := stage.Resources.Origins[id]; origin != nil && origin.Hook != "" {
+				k.report.Hook(types.ResourceRef(id), origin.Hook, stage.Directory)
+			}
+		}
+	}
+}
+
+// writeReport write the conversion report, if requested
+func (k *convertCmd) writeReport() error {
+	if k.report == nil {
+		return nil
+	}
+
+	format, err := reportFormat(k.reportFile)
+	if err != nil {
+		return err
+	}
+
+	data := k.report.Markdown()
+	if format == "json" {
+		data, err = k.report.JSON()
+		if err != nil {
+			return err
+		}
+	}
+
+	return ioutil.WriteFile(k.reportFile, data, 0644)
+}
+
+// reportFormat return the format of the report deduced from its extension
+func reportFormat(filename string) (string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".json":
+		return "json", nil
+	case ".md", ".markdown":
+		return "markdown", nil
+	}
+	return "", fmt.Errorf("unknown report format '%s', expected a .json or .md file", filename)
+}
+
+// messages return the writer of the messages, stderr when the package is
+// streamed to stdout so that it is kept clean
+func (k *convertCmd) messages() io.Writer {
+	if k.output == generators.StdoutOutput {
+		return os.Stderr
+	}
+	return k.out
+}
+
+// explainer return the explainer printing the diff of each transformer, or
+// writing it in the explain directory
+func (k *convertCmd) explainer() transformers.Explainer {
+	return func(e *transformers.Explanation) error {
+		header := fmt.Sprintf("# %d. %s (%s)\n", e.Step, e.Name, e.Duration)
+		diff := e.Diff
+		if diff == "" {
+			diff = "# no changes\n"
+		}
+
+		if k.explainDir == "" {
+			_, err := fmt.Fprint(k.messages(), header+diff)
+			return err
+		}
+
+		if err := os.MkdirAll(k.explainDir, 0755); err != nil {
+			return err
+		}
+		filename := filepath.Join(k.explainDir, fmt.Sprintf("%02d-%s.diff", e.Step, e.Name))
+		return ioutil.WriteFile(filename, []byte(header+diff), 0644)
+	}
+}
+
+// parseOptions parse a list of key=value options
+func parseOptions(list []string) (map[string]string, error) {
+	options := make(map[string]string, len(list))
+	for _, o := range list {
+		parts := strings.SplitN(o, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid option '%s', expected key=value", o)
+		}
+		options[parts[0]] = parts[1]
+	}
+	return options, nil
 }
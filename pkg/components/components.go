@@ -0,0 +1,239 @@
+// Package components build kustomize Components from the difference between
+// two conversions of the same chart
+package components
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+
+	"github.com/ContainerSolutions/helm-convert/pkg/merge"
+	"github.com/ContainerSolutions/helm-convert/pkg/types"
+	"github.com/ContainerSolutions/helm-convert/pkg/utils"
+	"github.com/golang/glog"
+	"sigs.k8s.io/kustomize/pkg/image"
+	"sigs.k8s.io/kustomize/pkg/resid"
+	ktypes "sigs.k8s.io/kustomize/pkg/types"
+)
+
+// PatchesDirectory is the directory containing the patches of a component
+const PatchesDirectory = "patches"
+
+// Feature is a chart feature toggled by a value, ie: metrics=metrics.enabled=true
+type Feature struct {
+	// Name of the component
+	Name string
+
+	// Value set to enable the feature, using the --set syntax
+	Value string
+}
+
+// ParseFeature parse a feature definition: name=key=value
+func ParseFeature(s string) (*Feature, error) {
+	parts := strings.SplitN(s, "=", 2)
+	if len(parts) != 2 || parts[0] == "" || !strings.Contains(parts[1], "=") {
+		return nil, fmt.Errorf("invalid component '%s', expected name=key=value", s)
+	}
+	if strings.ContainsAny(parts[0], "/\\") || parts[0] == "." || parts[0] == ".." {
+		return nil, fmt.Errorf("invalid component name '%s'", parts[0])
+	}
+	return &Feature{Name: parts[0], Value: parts[1]}, nil
+}
+
+// Diff build a component containing the changes between the base conversion
+// and the conversion with the feature enabled: added resources, patches of
+// modified and removed resources, added or modified generators and images.
+func Diff(name string, baseConfig, config *ktypes.Kustomization, base, resources *types.Resources,
+	namer *utils.FileNamer) (*types.Component, error) {
+	component := types.NewComponent(name)
+
+	// common labels and annotations are removed from the manifests, applying
+	// them again to the base resources is a no-op
+	component.Config.CommonLabels = config.CommonLabels
+	component.Config.CommonAnnotations = config.CommonAnnotations
+
+	if err := diffResources(component, base, resources, namer); err != nil {
+		return nil, err
+	}
+
+	diffGenerators(component, baseConfig, config, resources)
+
+	for _, image := range config.Images {
+		if !containsImage(baseConfig.Images, image) {
+			component.Config.Images = append(component.Config.Images, image)
+		}
+	}
+
+	return component, nil
+}
+
+func diffResources(component *types.Component, base, resources *types.Resources, namer *utils.FileNamer) error {
+	// patches are named after the resource they apply to, whatever the
+	// layout, so that each patch has its own file
+	patchFilenames, err := utils.GetResourceFileNames(base.ResMap)
+	if err != nil {
+		return err
+	}
+	filenames, err := filePaths(resources, namer)
+	if err != nil {
+		return err
+	}
+
+	added := make(map[string]struct{})
+	for _, id := range utils.SortedResIds(resources.ResMap) {
+		res := resources.ResMap[id]
+		baseRes, ok := base.ResMap[id]
+		if !ok {
+			component.Resources.ResMap[id] = res
+			component.Resources.Origins[id] = resources.Origins[id]
+			component.Resources.FieldOrders[id] = resources.FieldOrders[id]
+			if _, ok := added[filenames[id]]; !ok {
+				added[filenames[id]] = struct{}{}
+				component.Config.Resources = append(component.Config.Resources, filenames[id])
+			}
+			continue
+		}
+
+		ops := merge.Diff(baseRes.Map(), res.Map())
+		if len(ops) == 0 {
+			continue
+		}
+
+		filename := path.Join(PatchesDirectory, patchFilenames[id])
+		component.Patches[filename] = ops
+		component.Config.Patches = append(component.Config.Patches, types.Patch{
+			Path:   filename,
+			Target: patchTarget(id),
+		})
+	}
+
+	for _, id := range utils.SortedResIds(base.ResMap) {
+		if _, ok := resources.ResMap[id]; ok {
+			continue
+		}
+
+		apiVersion, err := base.ResMap[id].GetFieldValue("apiVersion")
+		if err != nil {
+			return err
+		}
+
+		metadata := map[string]interface{}{"name": id.Name()}
+		if id.Namespace() != "" {
+			metadata["namespace"] = id.Namespace()
+		}
+
+		filename := path.Join(PatchesDirectory, patchFilenames[id])
+		component.Patches[filename] = map[string]interface{}{
+			"apiVersion": apiVersion,
+			"kind":       id.Gvk().Kind,
+			"metadata":   metadata,
+			"$patch":     "delete",
+		}
+		component.Config.Patches = append(component.Config.Patches, types.Patch{Path: filename})
+	}
+
+	return nil
+}
+
+// diffGenerators add the configmap and secret generators which are new or
+// different, modified generators replace the ones of the base
+func diffGenerators(component *types.Component, baseConfig, config *ktypes.Kustomization, resources *types.Resources) {
+	for _, args := range config.ConfigMapGenerator {
+		baseArgs, found := findGenerator(baseConfig.ConfigMapGenerator, args.GeneratorArgs)
+		if found && reflect.DeepEqual(baseArgs, args.GeneratorArgs) {
+			continue
+		}
+		if found {
+			args.Behavior = "replace"
+		}
+		component.Config.ConfigMapGenerator = append(component.Config.ConfigMapGenerator, args)
+		copySourceFiles(component, args.DataSources, resources)
+	}
+
+	for _, args := range config.SecretGenerator {
+		baseArgs, found := findSecretGenerator(baseConfig.SecretGenerator, args.GeneratorArgs)
+		if found && reflect.DeepEqual(baseArgs, args) {
+			continue
+		}
+		if found {
+			args.Behavior = "replace"
+		}
+		component.Config.SecretGenerator = append(component.Config.SecretGenerator, args)
+		copySourceFiles(component, args.DataSources, resources)
+	}
+
+	for _, args := range baseConfig.ConfigMapGenerator {
+		if _, found := findGenerator(config.ConfigMapGenerator, args.GeneratorArgs); !found {
+			glog.Warningf("Component %s removes the configmap generator %s which cannot be expressed as a patch",
+				component.Name, args.Name)
+		}
+	}
+	for _, args := range baseConfig.SecretGenerator {
+		if _, found := findSecretGenerator(config.SecretGenerator, args.GeneratorArgs); !found {
+			glog.Warningf("Component %s removes the secret generator %s which cannot be expressed as a patch",
+				component.Name, args.Name)
+		}
+	}
+}
+
+func findGenerator(list []ktypes.ConfigMapArgs, args ktypes.GeneratorArgs) (ktypes.GeneratorArgs, bool) {
+	for _, item := range list {
+		if item.Name == args.Name && item.Namespace == args.Namespace {
+			return item.GeneratorArgs, true
+		}
+	}
+	return ktypes.GeneratorArgs{}, false
+}
+
+func findSecretGenerator(list []ktypes.SecretArgs, args ktypes.GeneratorArgs) (ktypes.SecretArgs, bool) {
+	for _, item := range list {
+		if item.Name == args.Name && item.Namespace == args.Namespace {
+			return item, true
+		}
+	}
+	return ktypes.SecretArgs{}, false
+}
+
+// copySourceFiles add the files referenced by a generator to the component
+func copySourceFiles(component *types.Component, sources ktypes.DataSources, resources *types.Resources) {
+	paths := append([]string{}, sources.FileSources...)
+	if sources.EnvSource != "" {
+		paths = append(paths, sources.EnvSource)
+	}
+
+	for _, p := range paths {
+		if i := strings.Index(p, "="); i >= 0 {
+			p = p[i+1:]
+		}
+		if data, ok := resources.SourceFiles[p]; ok {
+			component.Resources.SourceFiles[p] = data
+		}
+	}
+}
+
+func containsImage(list []image.Image, item image.Image) bool {
+	for _, i := range list {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
+
+func patchTarget(id resid.ResId) *types.PatchTarget {
+	return &types.PatchTarget{
+		Group:     id.Gvk().Group,
+		Version:   id.Gvk().Version,
+		Kind:      id.Gvk().Kind,
+		Name:      id.Name(),
+		Namespace: id.Namespace(),
+	}
+}
+
+func filePaths(resources *types.Resources, namer *utils.FileNamer) (map[resid.ResId]string, error) {
+	if namer != nil {
+		return namer.FilePaths(resources)
+	}
+	return utils.GetResourceFileNames(resources.ResMap)
+}
@@ -0,0 +1,151 @@
+package components
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ContainerSolutions/helm-convert/pkg/merge"
+	"github.com/ContainerSolutions/helm-convert/pkg/types"
+	"github.com/kylelemons/godebug/pretty"
+	"sigs.k8s.io/kustomize/k8sdeps/kunstruct"
+	"sigs.k8s.io/kustomize/pkg/gvk"
+	"sigs.k8s.io/kustomize/pkg/image"
+	"sigs.k8s.io/kustomize/pkg/resid"
+	"sigs.k8s.io/kustomize/pkg/resmap"
+	"sigs.k8s.io/kustomize/pkg/resource"
+	ktypes "sigs.k8s.io/kustomize/pkg/types"
+)
+
+func TestDiff(t *testing.T) {
+	var rf = resource.NewFactory(kunstruct.NewKunstructuredFactoryImpl())
+	var deploy = gvk.Gvk{Group: "apps", Version: "v1", Kind: "Deployment"}
+	var service = gvk.Gvk{Version: "v1", Kind: "Service"}
+
+	newDeployment := func(replicas int) *resource.Resource {
+		return rf.FromMap(map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata":   map[string]interface{}{"name": "web"},
+			"spec":       map[string]interface{}{"replicas": replicas},
+		})
+	}
+	newService := func(name string) *resource.Resource {
+		return rf.FromMap(map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Service",
+			"metadata":   map[string]interface{}{"name": name},
+		})
+	}
+
+	for _, test := range []struct {
+		name          string
+		baseConfig    *ktypes.Kustomization
+		config        *ktypes.Kustomization
+		base          *types.Resources
+		resources     *types.Resources
+		expected      *types.ComponentConfig
+		expectedFiles map[string]interface{}
+	}{
+		{
+			name:       "it should add resources and patch modified ones",
+			baseConfig: &ktypes.Kustomization{},
+			config:     &ktypes.Kustomization{CommonLabels: map[string]string{"app": "web"}},
+			base: &types.Resources{
+				ResMap: resmap.ResMap{
+					resid.NewResId(deploy, "web"):  newDeployment(1),
+					resid.NewResId(service, "old"): newService("old"),
+				},
+			},
+			resources: &types.Resources{
+				ResMap: resmap.ResMap{
+					resid.NewResId(deploy, "web"):      newDeployment(2),
+					resid.NewResId(service, "metrics"): newService("metrics"),
+				},
+			},
+			expected: &types.ComponentConfig{
+				APIVersion:   types.ComponentAPIVersion,
+				Kind:         types.ComponentKind,
+				CommonLabels: map[string]string{"app": "web"},
+				Resources:    []string{"metrics-svc.yaml"},
+				Patches: []types.Patch{
+					{
+						Path: "patches/web-deploy.yaml",
+						Target: &types.PatchTarget{
+							Group:   "apps",
+							Version: "v1",
+							Kind:    "Deployment",
+							Name:    "web",
+						},
+					},
+					{Path: "patches/old-svc.yaml"},
+				},
+			},
+			expectedFiles: map[string]interface{}{
+				"patches/web-deploy.yaml": []merge.Operation{
+					{Op: "replace", Path: "/spec/replicas", Value: 2},
+				},
+				"patches/old-svc.yaml": map[string]interface{}{
+					"apiVersion": "v1",
+					"kind":       "Service",
+					"metadata":   map[string]interface{}{"name": "old"},
+					"$patch":     "delete",
+				},
+			},
+		},
+		{
+			name: "it should add new and modified generators and images",
+			baseConfig: &ktypes.Kustomization{
+				ConfigMapGenerator: []ktypes.ConfigMapArgs{
+					{GeneratorArgs: ktypes.GeneratorArgs{Name: "web", DataSources: ktypes.DataSources{
+						LiteralSources: []string{"metrics=false"},
+					}}},
+				},
+				Images: []image.Image{{Name: "nginx", NewTag: "1.15"}},
+			},
+			config: &ktypes.Kustomization{
+				ConfigMapGenerator: []ktypes.ConfigMapArgs{
+					{GeneratorArgs: ktypes.GeneratorArgs{Name: "web", DataSources: ktypes.DataSources{
+						LiteralSources: []string{"metrics=true"},
+					}}},
+					{GeneratorArgs: ktypes.GeneratorArgs{Name: "exporter", DataSources: ktypes.DataSources{
+						FileSources: []string{"files/exporter/config.yaml"},
+					}}},
+				},
+				Images: []image.Image{{Name: "nginx", NewTag: "1.15"}, {Name: "exporter", NewTag: "0.8"}},
+			},
+			base: &types.Resources{ResMap: resmap.ResMap{}},
+			resources: &types.Resources{
+				ResMap:      resmap.ResMap{},
+				SourceFiles: map[string]string{"files/exporter/config.yaml": "port: 9090"},
+			},
+			expected: &types.ComponentConfig{
+				APIVersion: types.ComponentAPIVersion,
+				Kind:       types.ComponentKind,
+				ConfigMapGenerator: []ktypes.ConfigMapArgs{
+					{GeneratorArgs: ktypes.GeneratorArgs{Name: "web", Behavior: "replace", DataSources: ktypes.DataSources{
+						LiteralSources: []string{"metrics=true"},
+					}}},
+					{GeneratorArgs: ktypes.GeneratorArgs{Name: "exporter", DataSources: ktypes.DataSources{
+						FileSources: []string{"files/exporter/config.yaml"},
+					}}},
+				},
+				Images: []image.Image{{Name: "exporter", NewTag: "0.8"}},
+			},
+			expectedFiles: map[string]interface{}{},
+		},
+	} {
+		t.Run(fmt.Sprintf("%s", test.name), func(t *testing.T) {
+			component, err := Diff("metrics", test.baseConfig, test.config, test.base, test.resources, nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if diff := pretty.Compare(component.Config, test.expected); diff != "" {
+				t.Errorf("%s, diff: (-got +want)\n%s", test.name, diff)
+			}
+			if diff := pretty.Compare(component.Patches, test.expectedFiles); diff != "" {
+				t.Errorf("%s, diff: (-got +want)\n%s", test.name, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package generators
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/ContainerSolutions/helm-convert/pkg/types"
+	"github.com/ghodss/yaml"
+	"sigs.k8s.io/kustomize/pkg/gvk"
+	"sigs.k8s.io/kustomize/pkg/patch"
+	ktypes "sigs.k8s.io/kustomize/pkg/types"
+)
+
+// SetArtifacts add the files, patches and components produced by the
+// transformers to the package
+func (g *Generator) SetArtifacts(artifacts *types.Artifacts) {
+	g.artifacts = artifacts
+}
+
+// writeArtifacts write the files and patches produced by the transformers,
+// a copy of the kustomization applying the patches is returned. Artifacts
+// cannot overwrite generated files.
+func (g *Generator) writeArtifacts(fs *stagingFileSystem, config *ktypes.Kustomization) (*ktypes.Kustomization, error) {
+	if g.artifacts.Empty() {
+		return config, nil
+	}
+
+	write := func(p string, data []byte) error {
+		p = path.Clean(p)
+		if path.IsAbs(p) || p == ".." || strings.HasPrefix(p, "../") {
+			return fmt.Errorf("artifact %s is outside of the package", p)
+		}
+		if _, exists := fs.files[path.Join(fs.dir, p)]; exists || isReservedPath(p) {
+			return fmt.Errorf("artifact %s would overwrite a generated file", p)
+		}
+		return writeFile(fs, p, data, 0644)
+	}
+
+	files := make([]string, 0, len(g.artifacts.Files))
+	for p := range g.artifacts.Files {
+		files = append(files, p)
+	}
+	sort.Strings(files)
+	for _, p := range files {
+		if err := write(p, g.artifacts.Files[p]); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(g.artifacts.Patches) == 0 {
+		return config, nil
+	}
+	if g.mode == ModeKpt {
+		return nil, fmt.Errorf("patches cannot be added to a kpt package")
+	}
+
+	c := *config
+	c.PatchesStrategicMerge = append([]patch.StrategicMerge{}, config.PatchesStrategicMerge...)
+	c.PatchesJson6902 = append([]patch.Json6902{}, config.PatchesJson6902...)
+	for _, a := range g.artifacts.Patches {
+		data, err := yaml.Marshal(a.Patch)
+		if err != nil {
+			return nil, err
+		}
+		if err := write(a.Path, data); err != nil {
+			return nil, err
+		}
+
+		if a.Target == nil {
+			c.PatchesStrategicMerge = append(c.PatchesStrategicMerge, patch.StrategicMerge(a.Path))
+			continue
+		}
+		c.PatchesJson6902 = append(c.PatchesJson6902, patch.Json6902{
+			Target: &patch.Target{
+				Gvk:       gvk.Gvk{Group: a.Target.Group, Version: a.Target.Version, Kind: a.Target.Kind},
+				Namespace: a.Target.Namespace,
+				Name:      a.Target.Name,
+			},
+			Path: a.Path,
+		})
+	}
+	return &c, nil
+}
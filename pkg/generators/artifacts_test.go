@@ -0,0 +1,145 @@
+package generators
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ContainerSolutions/helm-convert/pkg/types"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"sigs.k8s.io/kustomize/k8sdeps/kunstruct"
+	"sigs.k8s.io/kustomize/pkg/gvk"
+	"sigs.k8s.io/kustomize/pkg/resid"
+	"sigs.k8s.io/kustomize/pkg/resource"
+	ktypes "sigs.k8s.io/kustomize/pkg/types"
+)
+
+func TestRenderArtifacts(t *testing.T) {
+	var rf = resource.NewFactory(kunstruct.NewKunstructuredFactoryImpl())
+	var service = gvk.Gvk{Version: "v1", Kind: "Service"}
+
+	for _, test := range []struct {
+		name          string
+		artifacts     *types.Artifacts
+		expected      map[string]string
+		expectedError string
+	}{
+		{
+			name: "it should write the files and add the patches to the kustomization",
+			artifacts: &types.Artifacts{
+				Files: map[string][]byte{"files/logo.png": {0x89, 'P', 'N', 'G'}},
+				Patches: []types.PatchArtifact{
+					{
+						Path: "patches/web-svc.yaml",
+						Patch: map[string]interface{}{
+							"apiVersion": "v1",
+							"kind":       "Service",
+							"metadata":   map[string]interface{}{"name": "web"},
+							"spec":       map[string]interface{}{"type": "NodePort"},
+						},
+					},
+					{
+						Path: "patches/web-svc-json.yaml",
+						Target: &types.PatchTarget{
+							Version: "v1",
+							Kind:    "Service",
+							Name:    "web",
+						},
+						Patch: []interface{}{
+							map[string]interface{}{"op": "remove", "path": "/metadata/labels/app"},
+						},
+					},
+				},
+			},
+			expected: map[string]string{
+				"kustomization.yaml": `patchesStrategicMerge:
+  - patches/web-svc.yaml
+
+patchesJson6902:
+  - target:
+      version: v1
+      kind: Service
+      name: web
+    path: patches/web-svc-json.yaml
+`,
+				"files/logo.png":            "\x89PNG",
+				"patches/web-svc.yaml":      "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\nspec:\n  type: NodePort\n",
+				"patches/web-svc-json.yaml": "- op: remove\n  path: /metadata/labels/app\n",
+			},
+		},
+		{
+			name: "it should not overwrite a generated file",
+			artifacts: &types.Artifacts{
+				Files: map[string][]byte{"web-svc.yaml": []byte("")},
+			},
+			expectedError: "artifact web-svc.yaml would overwrite a generated file",
+		},
+		{
+			name: "it should not overwrite the kustomization",
+			artifacts: &types.Artifacts{
+				Files: map[string][]byte{"kustomization.yaml": []byte("resources: []\n")},
+			},
+			expectedError: "artifact kustomization.yaml would overwrite a generated file",
+		},
+		{
+			name: "it should not write in the components directory",
+			artifacts: &types.Artifacts{
+				Patches: []types.PatchArtifact{
+					{Path: "components/metrics/kustomization.yaml", Patch: map[string]interface{}{}},
+				},
+			},
+			expectedError: "artifact components/metrics/kustomization.yaml would overwrite a generated file",
+		},
+		{
+			name: "it should not write outside of the package",
+			artifacts: &types.Artifacts{
+				Files: map[string][]byte{"../logo.png": []byte("")},
+			},
+			expectedError: "artifact ../logo.png is outside of the package",
+		},
+	} {
+		t.Run(fmt.Sprintf("%s", test.name), func(t *testing.T) {
+			fs := NewArchiveFileSystem(OutputFormatTar, "test", func() (io.WriteCloser, error) {
+				return nil, nil
+			}).(*memoryFileSystem)
+
+			g, err := NewGenerator(fs, OnExistsOverwrite, nil, KustomizeV2, ModeKustomize)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			g.SetArtifacts(test.artifacts)
+
+			resources := types.NewResources()
+			resources.ResMap[resid.NewResId(service, "web")] = rf.FromMap(map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Service",
+				"metadata": map[string]interface{}{
+					"name":   "web",
+					"labels": map[string]interface{}{"app": "web"},
+				},
+			})
+
+			err = g.render(&ktypes.Kustomization{}, &chart.Metadata{Name: "web"}, resources, nil, false)
+			if test.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedError) {
+					t.Fatalf("%s: expected error %q, got %v", test.name, test.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for p, expected := range test.expected {
+				data, err := fs.ReadFile(p)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if string(data) != expected {
+					t.Errorf("%s, %s: got\n%s\nwant\n%s", test.name, p, data, expected)
+				}
+			}
+		})
+	}
+}
@@ -5,17 +5,22 @@ var commentsMapping = map[string]string{
 	"namePrefix": "# Value of this field is prepended to the\n" +
 		"# names of all resources",
 	"commonLabels": "# Labels to add to all resources and selectors.",
+	"labels": "# Labels to add to all resources, includeSelectors\n" +
+		"# also add them to selectors.",
 	"commonAnnotations": "# Annotations (non-identifying metadata)\n" +
 		"# to add to all resources. Like labels,\n" +
 		"# these are key value pairs.",
 	"resources": "# List of resource files that kustomize reads, modifies\n" +
 		"# and emits as a YAML string",
+	"helmCharts": "# Charts inflated by kustomize build --enable-helm.",
 	"configMapGenerator": "# Each entry in this list results in the creation of\n" +
 		"# one ConfigMap resource (it's a generator of n maps).",
 	"secretGenerator": "# Each entry in this list results in the creation of\n" +
 		"# one Secret resource (it's a generator of n secrets).",
 	"generatorOptions": "# generatorOptions modify behavior of all ConfigMap\n" +
 		"# and Secret generators",
+	"patchesStrategicMerge": "# Each entry in this list should resolve to\n" +
+		"# a partial or complete resource definition file.",
 	"patches": "# Each entry in this list should resolve to\n" +
 		"# a partial or complete resource definition file.",
 	"patchesJson6902": "# Each entry in this list should resolve to\n" +
@@ -25,6 +30,10 @@ var commentsMapping = map[string]string{
 		"# a file for custom resource definition(CRD).",
 	"vars": "# Vars are used to insert values from resources that cannot\n" +
 		"# be referenced otherwise.",
+	"replacements": "# Replacements copy fields of a resource\n" +
+		"# to fields of other resources.",
+	"sortOptions": "# sortOptions define the order of the resources\n" +
+		"# in the output, legacy keeps the Helm install order.",
 	"images": "# Images modify the tags for images without\n" +
 		"# creating patches.",
 }
@@ -0,0 +1,161 @@
+package generators
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/ContainerSolutions/helm-convert/pkg/merge"
+	"github.com/golang/glog"
+)
+
+// commit write the staged files to the destination depending on the
+// on-exists policy, store a pristine copy of each file in the base directory
+// and update the manifest of generated files of a directory destination
+func (g *Generator) commit(staged *stagingFileSystem) error {
+	previous, err := readFilesManifest(g.fs)
+	if err != nil {
+		return err
+	}
+
+	// the pristine copies are the base of the next upgrade, which only
+	// applies to directory destinations
+	metadata := keepsMetadata(g.fs)
+
+	var conflicts []string
+	for _, p := range sortedPaths(staged.staged()) {
+		data := staged.files[p]
+
+		if g.onExists == OnExistsUpgrade {
+			merged, conflict, err := g.upgrade(p, data)
+			if err != nil {
+				return fmt.Errorf("couldn't upgrade %s: %v", p, err)
+			}
+			if conflict {
+				glog.Warningf("Merge conflict in %s", p)
+				conflicts = append(conflicts, p)
+			}
+			data = merged
+		}
+
+		if data != nil {
+			if err := writeFile(g.fs, p, data, 0644); err != nil {
+				return err
+			}
+		}
+		if !metadata {
+			continue
+		}
+		if err := writeFile(g.fs, path.Join(BaseDirectory, p), staged.files[p], 0644); err != nil {
+			return err
+		}
+	}
+
+	// files generated by the previous conversion but not anymore
+	if previous == nil && (g.onExists == OnExistsClean || g.onExists == OnExistsUpgrade) {
+		glog.Warningf("No %s found in %s, stale files cannot be detected", FilesManifestFilename, g.fs)
+	}
+	for _, p := range sortedPaths(previous) {
+		if _, ok := staged.files[p]; ok {
+			continue
+		}
+
+		base := path.Join(BaseDirectory, p)
+		unchanged := g.fs.Exists(p) && g.fs.Exists(base) && sameContent(g.fs, p, base)
+
+		switch {
+		case !g.fs.Exists(p):
+		case g.onExists == OnExistsClean, g.onExists == OnExistsUpgrade && unchanged:
+			glog.V(4).Infof("Removing stale file %s", p)
+			if err := g.fs.Remove(p); err != nil {
+				return err
+			}
+		case g.onExists == OnExistsUpgrade:
+			glog.Warningf("%s is not generated anymore but was modified locally, keeping it", p)
+		}
+
+		if g.fs.Exists(base) {
+			if err := g.fs.Remove(base); err != nil {
+				return err
+			}
+		}
+	}
+
+	if metadata {
+		if err := writeFilesManifest(g.fs, staged.staged()); err != nil {
+			return err
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return fmt.Errorf("upgrade of %s has conflicts in %s, resolve the conflict markers",
+			g.fs, strings.Join(conflicts, ", "))
+	}
+
+	return nil
+}
+
+// upgrade three-way merge the pristine copy of the previous conversion, the
+// local file and the newly generated one. A nil result means the file was
+// removed locally and must not be written.
+func (g *Generator) upgrade(p string, data []byte) ([]byte, bool, error) {
+	var base []byte
+	basePath := path.Join(BaseDirectory, p)
+	if g.fs.Exists(basePath) {
+		var err error
+		if base, err = g.fs.ReadFile(basePath); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if !g.fs.Exists(p) {
+		// removed locally and unchanged in the chart
+		if base != nil && bytes.Equal(base, data) {
+			return nil, false, nil
+		}
+		return data, false, nil
+	}
+
+	local, err := g.fs.ReadFile(p)
+	if err != nil {
+		return nil, false, err
+	}
+
+	switch ext := path.Ext(p); {
+	case path.Base(p) == DefaultKustomizationFilename:
+		merged, conflict, err := merge.Documents(base, local, data, &merge.Options{SetLists: true})
+		if err != nil {
+			glog.Warningf("Couldn't merge %s field by field: %v", p, err)
+			break
+		}
+		if conflict {
+			return merged, true, nil
+		}
+		formatted, err := formatKustomization(merged)
+		return formatted, false, err
+	case ext == ".yaml" || ext == ".yml":
+		merged, conflict, err := merge.Documents(base, local, data, nil)
+		if err != nil {
+			glog.Warningf("Couldn't merge %s resource by resource: %v", p, err)
+			break
+		}
+		return merged, conflict, nil
+	}
+
+	merged, conflict := merge.Text(base, local, data)
+	return merged, conflict, nil
+}
+
+// sameContent return true if two files have the same content
+func sameContent(fs FileSystem, a, b string) bool {
+	dataA, err := fs.ReadFile(a)
+	if err != nil {
+		return false
+	}
+	dataB, err := fs.ReadFile(b)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(dataA, dataB)
+}
@@ -0,0 +1,189 @@
+package generators
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kylelemons/godebug/pretty"
+)
+
+func TestCommit(t *testing.T) {
+	for _, test := range []struct {
+		name          string
+		onExists      OnExists
+		archive       bool
+		existing      map[string]string
+		staged        map[string]string
+		expected      map[string]string
+		expectedError string
+	}{
+		{
+			name:     "it should remove files which are not generated anymore",
+			onExists: OnExistsClean,
+			existing: map[string]string{
+				FilesManifestFilename:               "kustomization.yaml\nmetrics-svc.yaml\n",
+				"kustomization.yaml":                "resources: []\n",
+				"metrics-svc.yaml":                  "kind: Service\n",
+				"patch.yaml":                        "kind: Service\n",
+				BaseDirectory + "/metrics-svc.yaml": "kind: Service\n",
+			},
+			staged: map[string]string{
+				"kustomization.yaml": "resources: []\n",
+			},
+			expected: map[string]string{
+				FilesManifestFilename:                 "kustomization.yaml\n",
+				"kustomization.yaml":                  "resources: []\n",
+				"patch.yaml":                          "kind: Service\n",
+				BaseDirectory + "/kustomization.yaml": "resources: []\n",
+			},
+		},
+		{
+			name:     "it should merge local edits when upgrading",
+			onExists: OnExistsUpgrade,
+			existing: map[string]string{
+				FilesManifestFilename:               "metrics-svc.yaml\nold-svc.yaml\nweb-svc.yaml\n",
+				"web-svc.yaml":                      "kind: Service\nmetadata:\n  name: web\nspec:\n  type: NodePort\n  port: 80\n",
+				"old-svc.yaml":                      "kind: Service\nmetadata:\n  name: old\n",
+				"metrics-svc.yaml":                  "kind: Service\nmetadata:\n  name: metrics\n# edited\n",
+				BaseDirectory + "/web-svc.yaml":     "kind: Service\nmetadata:\n  name: web\nspec:\n  type: ClusterIP\n  port: 80\n",
+				BaseDirectory + "/old-svc.yaml":     "kind: Service\nmetadata:\n  name: old\n",
+				BaseDirectory + "/metrics-svc.yaml": "kind: Service\nmetadata:\n  name: metrics\n",
+			},
+			staged: map[string]string{
+				"web-svc.yaml": "kind: Service\nmetadata:\n  name: web\nspec:\n  type: ClusterIP\n  port: 8080\n",
+			},
+			expected: map[string]string{
+				FilesManifestFilename:           "web-svc.yaml\n",
+				"web-svc.yaml":                  "kind: Service\nmetadata:\n  name: web\nspec:\n  type: NodePort\n  port: 8080\n",
+				"metrics-svc.yaml":              "kind: Service\nmetadata:\n  name: metrics\n# edited\n",
+				BaseDirectory + "/web-svc.yaml": "kind: Service\nmetadata:\n  name: web\nspec:\n  type: ClusterIP\n  port: 8080\n",
+			},
+		},
+		{
+			name:     "it should merge the kustomizations of the overlays as sets when upgrading",
+			onExists: OnExistsUpgrade,
+			existing: map[string]string{
+				FilesManifestFilename:                                   "overlays/k8s-1.28/kustomization.yaml\n",
+				"overlays/k8s-1.28/kustomization.yaml":                  "resources:\n  - web-ing.yaml\n  - patch.yaml\n",
+				BaseDirectory + "/overlays/k8s-1.28/kustomization.yaml": "resources:\n  - web-ing.yaml\n",
+			},
+			staged: map[string]string{
+				"overlays/k8s-1.28/kustomization.yaml": "resources:\n  - web-ing.yaml\n  - web-psp.yaml\n",
+			},
+			expected: map[string]string{
+				FilesManifestFilename:                                   "overlays/k8s-1.28/kustomization.yaml\n",
+				"overlays/k8s-1.28/kustomization.yaml":                  "resources:\n  - web-ing.yaml\n  - patch.yaml\n  - web-psp.yaml\n",
+				BaseDirectory + "/overlays/k8s-1.28/kustomization.yaml": "resources:\n  - web-ing.yaml\n  - web-psp.yaml\n",
+			},
+		},
+		{
+			name:     "it should reject a manifest listing files outside of the package",
+			onExists: OnExistsClean,
+			existing: map[string]string{
+				FilesManifestFilename: "kustomization.yaml\nbase/../../outside.yaml\n",
+				"kustomization.yaml":  "resources: []\n",
+			},
+			staged: map[string]string{
+				"kustomization.yaml": "resources: []\n",
+			},
+			expectedError: "base/../../outside.yaml is not a file of the package",
+		},
+		{
+			name:     "it should reject a manifest listing absolute paths",
+			onExists: OnExistsUpgrade,
+			existing: map[string]string{
+				FilesManifestFilename: "/etc/hosts\n",
+			},
+			expectedError: "/etc/hosts is not a file of the package",
+		},
+		{
+			name:     "it should reject a manifest listing the metadata",
+			onExists: OnExistsClean,
+			existing: map[string]string{
+				FilesManifestFilename: FilesManifestFilename + "\n",
+			},
+			expectedError: FilesManifestFilename + " is not a file of the package",
+		},
+		{
+			name:     "it should not write the metadata in an archive",
+			onExists: OnExistsOverwrite,
+			archive:  true,
+			staged: map[string]string{
+				"kustomization.yaml": "resources: []\n",
+			},
+			expected: map[string]string{
+				"kustomization.yaml": "resources: []\n",
+			},
+		},
+	} {
+		t.Run(fmt.Sprintf("%s", test.name), func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "helm-convert-commit")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer os.RemoveAll(dir)
+
+			fs := NewDirFileSystem(dir)
+			if test.archive {
+				fs = NewArchiveFileSystem(OutputFormatTar, "test", func() (io.WriteCloser, error) {
+					return nil, nil
+				})
+			}
+			for p, data := range test.existing {
+				fs.WriteFile(p, []byte(data), 0644)
+			}
+
+			staged := newStagingFileSystem(fs)
+			for p, data := range test.staged {
+				staged.WriteFile(p, []byte(data), 0644)
+			}
+
+			g, err := NewGenerator(fs, test.onExists, nil, KustomizeV2, ModeKustomize)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			err = g.commit(staged)
+			if test.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedError) {
+					t.Fatalf("%s: expected error %q, got %v", test.name, test.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			output := make(map[string]string)
+			if memory, ok := fs.(*memoryFileSystem); ok {
+				for p, f := range memory.files {
+					output[p] = string(f.data)
+				}
+			} else {
+				err = filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+					if err != nil || info.IsDir() {
+						return err
+					}
+					data, err := ioutil.ReadFile(p)
+					if err != nil {
+						return err
+					}
+					rel, _ := filepath.Rel(dir, p)
+					output[filepath.ToSlash(rel)] = string(data)
+					return nil
+				})
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+
+			if diff := pretty.Compare(output, test.expected); diff != "" {
+				t.Errorf("%s, diff: (-got +want)\n%s", test.name, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,599 @@
+package generators
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ContainerSolutions/helm-convert/pkg/utils"
+	"github.com/golang/glog"
+	yaml "gopkg.in/yaml.v3"
+)
+
+// OutputFormat define how a package is serialised when it is not written to a
+// directory
+type OutputFormat string
+
+const (
+	// OutputFormatTar write a tar archive
+	OutputFormatTar OutputFormat = "tar"
+
+	// OutputFormatTgz write a gzipped tar archive
+	OutputFormatTgz OutputFormat = "tgz"
+
+	// OutputFormatZip write a zip archive
+	OutputFormatZip OutputFormat = "zip"
+
+	// OutputFormatBundle write a multi-document YAML file, the path of each
+	// document is stored in the PathAnnotation annotation
+	OutputFormatBundle OutputFormat = "bundle"
+)
+
+// OutputFormats is the list of supported output formats
+var OutputFormats = []OutputFormat{OutputFormatTar, OutputFormatTgz, OutputFormatZip, OutputFormatBundle}
+
+// StdoutOutput is the output used to stream the package to stdout
+const StdoutOutput = "-"
+
+const (
+	// PathAnnotation is the annotation containing the path of a document in a
+	// bundle
+	PathAnnotation = "config.kubernetes.io/path"
+
+	// IndexAnnotation is the annotation containing the position of a document
+	// in its file when the file contains multiple documents
+	IndexAnnotation = "config.kubernetes.io/index"
+
+	// bundleFileAPIVersion and bundleFileKind identify the documents wrapping
+	// files which are not YAML manifests in a bundle
+	bundleFileAPIVersion = "helm-convert/v1"
+	bundleFileKind       = "File"
+)
+
+// archiveModTime is the modification time of archive entries, a fixed time
+// makes archives reproducible. Zip doesn't support dates before 1980.
+var archiveModTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// FileSystem is the destination of a generated package, paths are relative to
+// the root of the package
+type FileSystem interface {
+	// Exists return true if the path exists, an empty path refer to the root
+	// of the package
+	Exists(path string) bool
+
+	// ReadFile read an existing file
+	ReadFile(path string) ([]byte, error)
+
+	// WriteFile write a file, parent directories are created if they don't
+	// exist
+	WriteFile(path string, data []byte, perm os.FileMode) error
+
+	// Remove remove a file, parent directories left empty are removed
+	Remove(path string) error
+
+	// Close flush the package to its destination
+	Close() error
+
+	// String describe the destination
+	String() string
+}
+
+var _ FileSystem = &dirFileSystem{}
+var _ FileSystem = &memoryFileSystem{}
+
+// NewFileSystem constructs the file system corresponding to an output. An
+// empty output means the directory destination, "-" stream the package to
+// stdout using the given format (tar by default), otherwise the format is
+// deduced from the extension of the output file if not provided.
+func NewFileSystem(destination, output string, format OutputFormat, stdout io.Writer) (FileSystem, error) {
+	if output == "" {
+		return NewDirFileSystem(destination), nil
+	}
+
+	if format == "" {
+		if output == StdoutOutput {
+			format = OutputFormatTar
+		} else {
+			format = outputFormatFromFilename(output)
+		}
+	}
+
+	valid := false
+	for _, f := range OutputFormats {
+		if f == format {
+			valid = true
+		}
+	}
+	if !valid {
+		return nil, fmt.Errorf("unknown output format '%s' for %s, expected one of %v",
+			format, output, OutputFormats)
+	}
+
+	if output == StdoutOutput {
+		return NewArchiveFileSystem(format, "stdout", func() (io.WriteCloser, error) {
+			return nopWriteCloser{stdout}, nil
+		}), nil
+	}
+
+	return NewArchiveFileSystem(format, output, func() (io.WriteCloser, error) {
+		if err := os.MkdirAll(filepath.Dir(output), os.ModePerm); err != nil {
+			return nil, err
+		}
+		return os.Create(output)
+	}), nil
+}
+
+// outputFormatFromFilename return the format matching a file extension
+func outputFormatFromFilename(filename string) OutputFormat {
+	switch {
+	case strings.HasSuffix(filename, ".tgz"), strings.HasSuffix(filename, ".tar.gz"):
+		return OutputFormatTgz
+	case strings.HasSuffix(filename, ".tar"):
+		return OutputFormatTar
+	case strings.HasSuffix(filename, ".zip"):
+		return OutputFormatZip
+	case strings.HasSuffix(filename, ".yaml"), strings.HasSuffix(filename, ".yml"):
+		return OutputFormatBundle
+	}
+	return OutputFormat(strings.TrimPrefix(filepath.Ext(filename), "."))
+}
+
+// dirFileSystem write files in a directory
+type dirFileSystem struct {
+	root string
+}
+
+// NewDirFileSystem constructs a file system writing files in a directory
+func NewDirFileSystem(root string) FileSystem {
+	return &dirFileSystem{root}
+}
+
+func (fs *dirFileSystem) Exists(p string) bool {
+	ok, _ := utils.PathExists(filepath.Join(fs.root, p))
+	return ok
+}
+
+func (fs *dirFileSystem) ReadFile(p string) ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join(fs.root, p))
+}
+
+func (fs *dirFileSystem) WriteFile(p string, data []byte, perm os.FileMode) error {
+	filePath := filepath.Join(fs.root, p)
+	glog.V(4).Infof("Writing %s", filePath)
+
+	err := os.MkdirAll(filepath.Dir(filePath), os.ModePerm)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(filePath, data, perm)
+}
+
+func (fs *dirFileSystem) Remove(p string) error {
+	filePath := filepath.Join(fs.root, p)
+	glog.V(4).Infof("Removing %s", filePath)
+
+	if err := os.Remove(filePath); err != nil {
+		return err
+	}
+
+	// remove empty parent directories, os.Remove fails on non-empty ones
+	for dir := filepath.Dir(p); dir != "." && dir != "/"; dir = filepath.Dir(dir) {
+		if err := os.Remove(filepath.Join(fs.root, dir)); err != nil {
+			break
+		}
+	}
+	return nil
+}
+
+func (fs *dirFileSystem) Close() error {
+	return nil
+}
+
+func (fs *dirFileSystem) String() string {
+	return fs.root
+}
+
+type memoryFile struct {
+	data []byte
+	perm os.FileMode
+}
+
+// memoryFileSystem keep files in memory, they are serialised in path order
+// when the file system is closed
+type memoryFileSystem struct {
+	files  map[string]*memoryFile
+	format OutputFormat
+	name   string
+	open   func() (io.WriteCloser, error)
+}
+
+// NewArchiveFileSystem constructs a file system writing a package as an
+// archive or a bundle, open is called when the file system is closed
+func NewArchiveFileSystem(format OutputFormat, name string, open func() (io.WriteCloser, error)) FileSystem {
+	return &memoryFileSystem{
+		files:  make(map[string]*memoryFile),
+		format: format,
+		name:   name,
+		open:   open,
+	}
+}
+
+func (fs *memoryFileSystem) Exists(p string) bool {
+	if p == "" {
+		return false
+	}
+	_, ok := fs.files[path.Clean(p)]
+	return ok
+}
+
+func (fs *memoryFileSystem) ReadFile(p string) ([]byte, error) {
+	if f, ok := fs.files[path.Clean(p)]; ok {
+		return f.data, nil
+	}
+	return nil, &os.PathError{Op: "open", Path: p, Err: os.ErrNotExist}
+}
+
+func (fs *memoryFileSystem) WriteFile(p string, data []byte, perm os.FileMode) error {
+	glog.V(4).Infof("Adding %s to %s", p, fs.name)
+	fs.files[path.Clean(p)] = &memoryFile{data, perm}
+	return nil
+}
+
+func (fs *memoryFileSystem) Remove(p string) error {
+	if _, ok := fs.files[path.Clean(p)]; !ok {
+		return &os.PathError{Op: "remove", Path: p, Err: os.ErrNotExist}
+	}
+	delete(fs.files, path.Clean(p))
+	return nil
+}
+
+func (fs *memoryFileSystem) String() string {
+	return fs.name
+}
+
+// paths return the sorted list of files
+func (fs *memoryFileSystem) paths() []string {
+	paths := make([]string, 0, len(fs.files))
+	for p := range fs.files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+func (fs *memoryFileSystem) Close() error {
+	w, err := fs.open()
+	if err != nil {
+		return err
+	}
+
+	switch fs.format {
+	case OutputFormatTar:
+		err = fs.writeTar(w)
+	case OutputFormatTgz:
+		gw := gzip.NewWriter(w)
+		err = fs.writeTar(gw)
+		if err == nil {
+			err = gw.Close()
+		}
+	case OutputFormatZip:
+		err = fs.writeZip(w)
+	case OutputFormatBundle:
+		err = fs.writeBundle(w)
+	default:
+		err = fmt.Errorf("unknown output format '%s'", fs.format)
+	}
+
+	if closeErr := w.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (fs *memoryFileSystem) writeTar(w io.Writer) error {
+	tw := tar.NewWriter(w)
+	for _, p := range fs.paths() {
+		f := fs.files[p]
+		err := tw.WriteHeader(&tar.Header{
+			Name:     p,
+			Mode:     int64(f.perm),
+			Size:     int64(len(f.data)),
+			ModTime:  archiveModTime,
+			Typeflag: tar.TypeReg,
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := tw.Write(f.data); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+func (fs *memoryFileSystem) writeZip(w io.Writer) error {
+	zw := zip.NewWriter(w)
+	for _, p := range fs.paths() {
+		f := fs.files[p]
+		header := &zip.FileHeader{
+			Name:     p,
+			Method:   zip.Deflate,
+			Modified: archiveModTime,
+		}
+		header.SetMode(f.perm)
+
+		fw, err := zw.CreateHeader(header)
+		if err != nil {
+			return err
+		}
+		if _, err := fw.Write(f.data); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// writeBundle write all the files as a multi-document YAML stream. Each
+// document is annotated with its path and index. Files which are not YAML
+// maps, ie: configmap data sources, are wrapped in a File document.
+func (fs *memoryFileSystem) writeBundle(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+
+	for _, p := range fs.paths() {
+		documents, err := bundleDocuments(p, fs.files[p].data)
+		if err != nil {
+			return err
+		}
+		for _, doc := range documents {
+			if err := encoder.Encode(doc); err != nil {
+				return err
+			}
+		}
+	}
+
+	return encoder.Close()
+}
+
+// bundleDocuments return the documents of a file annotated with their path
+func bundleDocuments(p string, data []byte) ([]*yaml.Node, error) {
+	var documents []*yaml.Node
+
+	ext := path.Ext(p)
+	if ext == ".yaml" || ext == ".yml" {
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		for {
+			var doc yaml.Node
+			err := decoder.Decode(&doc)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				// not a valid YAML file, wrap it
+				documents = nil
+				break
+			}
+			root := documentContent(&doc)
+			if root == nil || root.Kind != yaml.MappingNode {
+				documents = nil
+				break
+			}
+			documents = append(documents, root)
+		}
+	}
+
+	if len(documents) == 0 {
+		return []*yaml.Node{bundleFile(p, data)}, nil
+	}
+
+	for i, root := range documents {
+		annotations := map[string]string{PathAnnotation: p}
+		if len(documents) > 1 {
+			annotations[IndexAnnotation] = fmt.Sprint(i)
+		}
+		setAnnotations(root, annotations)
+	}
+
+	return documents, nil
+}
+
+// bundleFile wrap the content of a file in a YAML document, binary content is
+// base64 encoded in binaryData like in a configmap
+func bundleFile(p string, data []byte) *yaml.Node {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	appendScalar(root, "apiVersion", bundleFileAPIVersion)
+	appendScalar(root, "kind", bundleFileKind)
+
+	metadata := &yaml.Node{Kind: yaml.MappingNode}
+	appendScalar(metadata, "name", p)
+	root.Content = append(root.Content, scalarNode("metadata"), metadata)
+	setAnnotations(root, map[string]string{PathAnnotation: p})
+
+	if !utf8.Valid(data) {
+		appendScalar(root, "binaryData", base64.StdEncoding.EncodeToString(data))
+		return root
+	}
+
+	appendScalar(root, "data", string(data))
+	if value := root.Content[len(root.Content)-1]; strings.Contains(value.Value, "\n") {
+		value.Style = yaml.LiteralStyle
+	}
+
+	return root
+}
+
+// ReadBundle return the files of a bundle. The content of the File documents
+// is unwrapped, the other documents are grouped by path in the order of their
+// index and re-encoded without the path and index annotations.
+func ReadBundle(r io.Reader) (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	documents := make(map[string][]*yaml.Node)
+	indexes := make(map[*yaml.Node]int)
+
+	decoder := yaml.NewDecoder(r)
+	for i := 0; ; i++ {
+		var doc yaml.Node
+		err := decoder.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		root := documentContent(&doc)
+		if root == nil || root.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("document #%d of the bundle is not a map", i)
+		}
+
+		annotations := mappingField(mappingField(root, "metadata"), "annotations")
+		p := mappingValue(annotations, PathAnnotation)
+		if p == "" {
+			return nil, fmt.Errorf("document #%d of the bundle has no %s annotation", i, PathAnnotation)
+		}
+
+		if mappingValue(root, "apiVersion") == bundleFileAPIVersion && mappingValue(root, "kind") == bundleFileKind {
+			if binary := mappingField(root, "binaryData"); binary != nil {
+				data, err := base64.StdEncoding.DecodeString(binary.Value)
+				if err != nil {
+					return nil, fmt.Errorf("invalid binaryData of %s: %v", p, err)
+				}
+				files[p] = data
+			} else {
+				files[p] = []byte(mappingValue(root, "data"))
+			}
+			continue
+		}
+
+		indexes[root], _ = strconv.Atoi(mappingValue(annotations, IndexAnnotation))
+		removeKeys(annotations, PathAnnotation, IndexAnnotation)
+		if len(annotations.Content) == 0 {
+			removeKeys(mappingField(root, "metadata"), "annotations")
+		}
+		if metadata := mappingField(root, "metadata"); metadata != nil && len(metadata.Content) == 0 {
+			removeKeys(root, "metadata")
+		}
+		documents[p] = append(documents[p], root)
+	}
+
+	for p, roots := range documents {
+		sort.SliceStable(roots, func(i, j int) bool {
+			return indexes[roots[i]] < indexes[roots[j]]
+		})
+
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		for _, root := range roots {
+			if err := encoder.Encode(root); err != nil {
+				return nil, err
+			}
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, err
+		}
+		files[p] = buf.Bytes()
+	}
+
+	return files, nil
+}
+
+// mappingField return the value of a key of a map, nil if it doesn't exist
+func mappingField(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// removeKeys remove keys and their value from a map
+func removeKeys(node *yaml.Node, keys ...string) {
+	if node == nil {
+		return
+	}
+	content := node.Content[:0]
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		remove := false
+		for _, key := range keys {
+			remove = remove || node.Content[i].Value == key
+		}
+		if !remove {
+			content = append(content, node.Content[i], node.Content[i+1])
+		}
+	}
+	node.Content = content
+}
+
+// setAnnotations set annotations on a resource node, creating the metadata
+// and annotations maps if they don't exist
+func setAnnotations(root *yaml.Node, annotations map[string]string) {
+	metadata := mappingChild(root, "metadata")
+	target := mappingChild(metadata, "annotations")
+
+	keys := make([]string, 0, len(annotations))
+	for key := range annotations {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		found := false
+		for i := 0; i+1 < len(target.Content); i += 2 {
+			if target.Content[i].Value == key {
+				target.Content[i+1] = scalarNode(annotations[key])
+				found = true
+			}
+		}
+		if !found {
+			appendScalar(target, key, annotations[key])
+		}
+	}
+}
+
+// mappingChild return the map of a key, creating it if it doesn't exist or
+// isn't a map
+func mappingChild(node *yaml.Node, key string) *yaml.Node {
+	child := &yaml.Node{Kind: yaml.MappingNode}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			if node.Content[i+1].Kind != yaml.MappingNode {
+				node.Content[i+1] = child
+			}
+			return node.Content[i+1]
+		}
+	}
+	node.Content = append(node.Content, scalarNode(key), child)
+	return child
+}
+
+func appendScalar(node *yaml.Node, key, value string) {
+	node.Content = append(node.Content, scalarNode(key), scalarNode(value))
+}
+
+func scalarNode(value string) *yaml.Node {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error { return nil }
@@ -0,0 +1,203 @@
+package generators
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"testing"
+
+	"github.com/kylelemons/godebug/pretty"
+)
+
+type bufferCloser struct {
+	*bytes.Buffer
+}
+
+func (bufferCloser) Close() error { return nil }
+
+func TestArchiveFileSystem(t *testing.T) {
+	files := map[string]string{
+		"kustomization.yaml":     "resources:\n  - web-svc.yaml\n",
+		"web-svc.yaml":           "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n",
+		"files/web/nginx.conf":   "server {\n  listen 80;\n}\n",
+		"files/web/logo.png":     "\x89PNG\xff\x00",
+		"templates/web-all.yaml": "kind: Service\nmetadata:\n  name: a\n---\nkind: Service\nmetadata:\n  name: b\n",
+	}
+
+	for _, test := range []struct {
+		name     string
+		format   OutputFormat
+		read     func([]byte) (interface{}, error)
+		expected interface{}
+	}{
+		{
+			name:     "it should write a tar archive",
+			format:   OutputFormatTar,
+			read:     readTar,
+			expected: files,
+		},
+		{
+			name:     "it should write a zip archive",
+			format:   OutputFormatZip,
+			read:     readZip,
+			expected: files,
+		},
+		{
+			name:   "it should write a multi-document bundle",
+			format: OutputFormatBundle,
+			read: func(data []byte) (interface{}, error) {
+				return string(data), nil
+			},
+			expected: `apiVersion: helm-convert/v1
+kind: File
+metadata:
+  name: files/web/logo.png
+  annotations:
+    config.kubernetes.io/path: files/web/logo.png
+binaryData: iVBOR/8A
+---
+apiVersion: helm-convert/v1
+kind: File
+metadata:
+  name: files/web/nginx.conf
+  annotations:
+    config.kubernetes.io/path: files/web/nginx.conf
+data: |
+  server {
+    listen 80;
+  }
+---
+resources:
+  - web-svc.yaml
+metadata:
+  annotations:
+    config.kubernetes.io/path: kustomization.yaml
+---
+kind: Service
+metadata:
+  name: a
+  annotations:
+    config.kubernetes.io/index: "0"
+    config.kubernetes.io/path: templates/web-all.yaml
+---
+kind: Service
+metadata:
+  name: b
+  annotations:
+    config.kubernetes.io/index: "1"
+    config.kubernetes.io/path: templates/web-all.yaml
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: web
+  annotations:
+    config.kubernetes.io/path: web-svc.yaml
+`,
+		},
+	} {
+		t.Run(fmt.Sprintf("%s", test.name), func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			fs := NewArchiveFileSystem(test.format, "test", func() (io.WriteCloser, error) {
+				return bufferCloser{buf}, nil
+			})
+
+			for p, data := range files {
+				if err := fs.WriteFile(p, []byte(data), 0644); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+			}
+			if err := fs.Close(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			output, err := test.read(buf.Bytes())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if diff := pretty.Compare(output, test.expected); diff != "" {
+				t.Errorf("%s, diff: (-got +want)\n%s", test.name, diff)
+			}
+		})
+	}
+}
+
+func TestReadBundle(t *testing.T) {
+	files := map[string]string{
+		"kustomization.yaml":     "resources:\n  - web-svc.yaml\n",
+		"web-svc.yaml":           "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n",
+		"files/web/nginx.conf":   "server {\n  listen 80;\n}\n",
+		"files/web/logo.png":     "\x89PNG\xff\x00",
+		"templates/web-all.yaml": "kind: Service\nmetadata:\n  name: a\n---\nkind: Service\nmetadata:\n  name: b\n",
+	}
+
+	buf := &bytes.Buffer{}
+	fs := NewArchiveFileSystem(OutputFormatBundle, "test", func() (io.WriteCloser, error) {
+		return bufferCloser{buf}, nil
+	})
+	for p, data := range files {
+		if err := fs.WriteFile(p, []byte(data), 0644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	if err := fs.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	read, err := ReadBundle(buf)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	output := make(map[string]string, len(read))
+	for p, data := range read {
+		output[p] = string(data)
+	}
+
+	if diff := pretty.Compare(output, files); diff != "" {
+		t.Errorf("diff: (-got +want)\n%s", diff)
+	}
+}
+
+func readTar(data []byte) (interface{}, error) {
+	files := make(map[string]string)
+	tr := tar.NewReader(bytes.NewReader(data))
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[header.Name] = string(content)
+	}
+}
+
+func readZip(data []byte) (interface{}, error) {
+	files := make(map[string]string)
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range zr.File {
+		r, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		content, err := ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			return nil, err
+		}
+		files[f.Name] = string(content)
+	}
+	return files, nil
+}
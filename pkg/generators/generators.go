@@ -8,9 +8,14 @@ import (
 	"path"
 	"strings"
 
+	"github.com/ContainerSolutions/helm-convert/pkg/gitops"
+	"github.com/ContainerSolutions/helm-convert/pkg/kpt"
+	"github.com/ContainerSolutions/helm-convert/pkg/overlays"
 	"github.com/ContainerSolutions/helm-convert/pkg/types"
 	"github.com/ContainerSolutions/helm-convert/pkg/utils"
+	"github.com/golang/glog"
 	"k8s.io/helm/pkg/proto/hapi/chart"
+	"sigs.k8s.io/kustomize/pkg/resid"
 	ktypes "sigs.k8s.io/kustomize/pkg/types"
 )
 
@@ -20,79 +25,332 @@ const (
 
 	// DefaultKustomizationFilename is the name of the kustomization config file
 	DefaultKustomizationFilename = "kustomization.yaml"
+
+	// ComponentsDirectory is the directory containing the kustomize components
+	ComponentsDirectory = "components"
+)
+
+// OnExists define what to do when the destination already exists
+type OnExists string
+
+const (
+	// OnExistsPrompt ask the user to confirm before overwriting files
+	OnExistsPrompt OnExists = "prompt"
+
+	// OnExistsFail return an error
+	OnExistsFail OnExists = "fail"
+
+	// OnExistsOverwrite overwrite files without asking
+	OnExistsOverwrite OnExists = "overwrite"
+
+	// OnExistsClean overwrite files and remove the files generated by a
+	// previous conversion which are not generated anymore
+	OnExistsClean OnExists = "clean"
+
+	// OnExistsUpgrade three-way merge the files generated by the previous
+	// conversion, the local edits and the newly generated files
+	OnExistsUpgrade OnExists = "upgrade"
+)
+
+// OnExistsPolicies is the list of supported policies
+var OnExistsPolicies = []OnExists{OnExistsPrompt, OnExistsFail, OnExistsOverwrite, OnExistsClean, OnExistsUpgrade}
+
+// Mode define the kind of package which is generated
+type Mode string
+
+const (
+	// ModeKustomize generate a kustomization
+	ModeKustomize Mode = "kustomize"
+
+	// ModeKpt generate a kpt package
+	ModeKpt Mode = "kpt"
+
+	// ModeInflate generate a kustomization inflating the chart with the
+	// helmCharts field instead of vendoring the rendered manifests
+	ModeInflate Mode = "inflate"
 )
 
+// Modes is the list of supported modes
+var Modes = []Mode{ModeKustomize, ModeKpt, ModeInflate}
+
 // Generator type
 type Generator struct {
-	force bool
+	fs        FileSystem
+	onExists  OnExists
+	namer     *utils.FileNamer
+	version   KustomizeVersion
+	mode      Mode
+	gitops    *gitops.Options
+	artifacts *types.Artifacts
+	overlays  []*types.Overlay
 }
 
-// NewGenerator contructs a new generator
-func NewGenerator(force bool) *Generator {
-	return &Generator{force}
-}
+// NewGenerator contructs a new generator writing to the given file system, the
+// file namer define the path of each manifest, nil means the flat layout with
+// default filenames. The kustomization files are written in the schema of the
+// given kustomize version.
+func NewGenerator(fs FileSystem, onExists OnExists, namer *utils.FileNamer,
+	version KustomizeVersion, mode Mode) (*Generator, error) {
+	if onExists == "" {
+		onExists = OnExistsPrompt
+	}
 
-// Render to disk the kustomization.yaml, Kube-descriptor.yaml and associated resources
-func (g *Generator) Render(destination string, config *ktypes.Kustomization,
-	metadata *chart.Metadata, resources *types.Resources, addConfigComments bool) error {
-	var err error
+	valid := false
+	for _, p := range OnExistsPolicies {
+		if p == onExists {
+			valid = true
+		}
+	}
+	if !valid {
+		return nil, fmt.Errorf("unknown on-exists policy '%s', expected one of %v", onExists, OnExistsPolicies)
+	}
 
-	// chech if destination path already exist, prompt user to confirm override
-	if ok, _ := utils.PathExists(destination); ok {
-		if !g.force {
-			reader := bufio.NewReader(os.Stdin)
-			fmt.Printf("Destination directory '%s' already exist, override? [y/n] ", destination)
-			approve, _ := reader.ReadString('\n')
-			approve = strings.Trim(approve, " \n")
+	if version == 0 {
+		version = KustomizeV2
+	}
+	if version < KustomizeV2 || version > KustomizeV5 {
+		return nil, fmt.Errorf("unsupported kustomize version %s, expected one of %v", version, KustomizeVersions)
+	}
 
-			if approve != "y" && approve != "yes" {
-				return nil
-			}
+	if mode == "" {
+		mode = ModeKustomize
+	}
+	valid = false
+	for _, m := range Modes {
+		if m == mode {
+			valid = true
 		}
+	}
+	if !valid {
+		return nil, fmt.Errorf("unknown mode '%s', expected one of %v", mode, Modes)
+	}
+
+	return &Generator{fs: fs, onExists: onExists, namer: namer, version: version, mode: mode}, nil
+}
+
+// Render the kustomization.yaml, Kube-descriptor.yaml, associated resources and
+// components to the file system, the file system is closed once all the files
+// are written
+func (g *Generator) Render(config *ktypes.Kustomization, metadata *chart.Metadata,
+	resources *types.Resources, components []*types.Component, addConfigComments bool) error {
+	var err error
+	if g.mode == ModeInflate {
+		err = fmt.Errorf("the %s mode is rendered with RenderInflation", ModeInflate)
 	} else {
-		os.MkdirAll(destination, os.ModePerm)
+		err = g.render(config, metadata, resources, components, addConfigComments)
+	}
+	if closeErr := g.fs.Close(); err == nil {
+		err = closeErr
 	}
+	return err
+}
 
-	// render all manifests
-	for id, res := range resources.ResMap {
-		filename, err := utils.GetResourceFileName(id, res)
-		if err != nil {
-			return err
-		}
+func (g *Generator) render(config *ktypes.Kustomization, metadata *chart.Metadata,
+	resources *types.Resources, components []*types.Component, addConfigComments bool) error {
+	if err := g.checkOverlays(); err != nil {
+		return err
+	}
+
+	proceed, err := g.checkDestination()
+	if err != nil || !proceed {
+		return err
+	}
+
+	// generated files are staged then committed to the destination, the
+	// package is written in the base directory of the overlays if any
+	fs := newStagingFileSystem(g.fs)
+	if len(g.overlays) > 0 {
+		fs.dir = overlays.BaseDirectory
+	}
 
-		err = writeYamlFile(path.Join(destination, filename), res)
+	// CRDs and hooks are deployed separately by the GitOps tools
+	stages := []gitops.Stage{{Resources: resources}}
+	if g.gitops != nil && g.mode == ModeKustomize {
+		stages = gitops.Split(resources)
+		config, resources, err = g.writeStages(fs, config, stages, addConfigComments)
 		if err != nil {
 			return err
 		}
 	}
 
-	// render all config and env files
-	for filename, data := range resources.SourceFiles {
-		// TODO: prevent overwriting of file, filename can be similar from one
-		// resource to another
-		err = writeFile(path.Join(destination, filename), []byte(data), 0644)
+	err = g.writeResources(fs, "", resources)
+	if err != nil {
+		return err
+	}
+
+	config, err = g.writeArtifacts(fs, config)
+	if err != nil {
+		return err
+	}
+	if g.artifacts != nil {
+		components = append(append([]*types.Component{}, components...), g.artifacts.Components...)
+	}
+
+	if g.mode == ModeKpt {
+		if len(components) > 0 {
+			return fmt.Errorf("components cannot be generated in a kpt package")
+		}
+		err = writeKptPackage(fs, config, metadata, resources)
 		if err != nil {
 			return err
 		}
+		return g.commit(fs)
 	}
 
 	// render kustomization.yaml
-	err = writeYamlFile(path.Join(destination, DefaultKustomizationFilename), config)
+	err = writeKustomizationFile(fs, DefaultKustomizationFilename,
+		convertKustomization(config, resources, g.version), addConfigComments)
 	if err != nil {
 		return err
 	}
 
-	// format and write kustomization.yaml
-	err = writeAndFormatKustomizationConfig(path.Join(destination, DefaultKustomizationFilename), addConfigComments)
+	// render Kube-descriptor.yaml
+	err = writeYamlFile(fs, DefaultKubeDescriptorFilename, metadata)
 	if err != nil {
 		return err
 	}
 
-	// render Kube-descriptor.yaml
-	err = writeYamlFile(path.Join(destination, DefaultKubeDescriptorFilename), metadata)
+	// render components
+	if len(components) > 0 && g.version < KustomizeV3 {
+		glog.Warningf("Components require kustomize v3.7 or later, use --kustomize-version to target it")
+	}
+	for _, component := range components {
+		dir := path.Join(ComponentsDirectory, component.Name)
+
+		err = g.writeResources(fs, dir, component.Resources)
+		if err != nil {
+			return err
+		}
+
+		for filename, patch := range component.Patches {
+			err = writeYamlFile(fs, path.Join(dir, filename), patch)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = writeKustomizationFile(fs, path.Join(dir, DefaultKustomizationFilename),
+			convertComponentConfig(component.Config, g.version), addConfigComments)
+		if err != nil {
+			return err
+		}
+	}
+
+	fs.dir = ""
+	err = g.writeOverlays(fs, addConfigComments)
+	if err != nil {
+		return err
+	}
+
+	if g.gitops != nil {
+		err = g.writeGitOps(fs, metadata, stages)
+		if err != nil {
+			return err
+		}
+	}
+
+	return g.commit(fs)
+}
+
+// checkDestination apply the on-exists policy if the destination already
+// exists, false is returned if the user declined to overwrite it
+func (g *Generator) checkDestination() (bool, error) {
+	if !g.fs.Exists("") {
+		return true, nil
+	}
+
+	switch g.onExists {
+	case OnExistsFail:
+		return false, fmt.Errorf("destination directory '%s' already exist", g.fs)
+	case OnExistsPrompt:
+		return confirm(fmt.Sprintf("Destination directory '%s' already exist, override?", g.fs))
+	}
+	return true, nil
+}
+
+// writeResources write the manifests and source files of a list of resources
+// in a directory
+func (g *Generator) writeResources(fs FileSystem, dir string, resources *types.Resources) error {
+	filenames, err := g.filePaths(resources)
 	if err != nil {
 		return err
 	}
 
+	// prevent a file from being overwritten by another one
+	for id, filename := range filenames {
+		if _, ok := resources.SourceFiles[filename]; ok {
+			return fmt.Errorf("resource %s and a source file would be written to the same file %s",
+				resources.Describe(id), filename)
+		}
+		if isReservedPath(filename) {
+			return fmt.Errorf("resource %s would overwrite %s", resources.Describe(id), filename)
+		}
+	}
+
+	// render all manifests, resources sharing the same file are written as a
+	// multi-document YAML file
+	manifests := make(map[string][]resid.ResId, len(filenames))
+	for _, id := range utils.SortedResIds(resources.ResMap) {
+		manifests[filenames[id]] = append(manifests[filenames[id]], id)
+	}
+
+	for filename, ids := range manifests {
+		data := make([]interface{}, 0, len(ids))
+		orders := make([]*types.FieldOrder, 0, len(ids))
+		for _, id := range ids {
+			data = append(data, resources.ResMap[id])
+			orders = append(orders, resources.FieldOrders[id])
+		}
+
+		err = writeYamlDocuments(fs, path.Join(dir, filename), data, orders)
+		if err != nil {
+			return err
+		}
+	}
+
+	// render all config and env files
+	for filename, data := range resources.SourceFiles {
+		err = writeFile(fs, path.Join(dir, filename), []byte(data), 0644)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
+
+// isReservedPath return true if a path is written by the generator after the
+// resources and artifacts: the kustomization, the package metadata, the
+// components and the GitOps objects
+func isReservedPath(p string) bool {
+	return p == DefaultKustomizationFilename || p == DefaultKubeDescriptorFilename ||
+		p == kpt.KptfileName || p == kpt.SettersFilename || p == kpt.ReplacementsFilename ||
+		strings.HasPrefix(p, MetadataDirectory+"/") ||
+		strings.HasPrefix(p, ComponentsDirectory+"/") ||
+		strings.HasPrefix(p, gitops.Directory+"/")
+}
+
+// filePaths return the path of the manifest of each resource
+func (g *Generator) filePaths(resources *types.Resources) (map[resid.ResId]string, error) {
+	if g.namer != nil {
+		return g.namer.FilePaths(resources)
+	}
+	return utils.GetResourceFileNames(resources.ResMap)
+}
+
+// confirm ask the user to confirm an action on stdin, an error is returned if
+// stdin is not a terminal
+func confirm(question string) (bool, error) {
+	stat, err := os.Stdin.Stat()
+	if err != nil || stat.Mode()&os.ModeCharDevice == 0 {
+		return false, fmt.Errorf("%s cannot prompt, stdin is not a terminal: use --on-exists with one of %v",
+			question, OnExistsPolicies[1:])
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	fmt.Fprintf(os.Stderr, "%s [y/n] ", question)
+	approve, _ := reader.ReadString('\n')
+	approve = strings.Trim(approve, " \n")
+
+	return approve == "y" || approve == "yes", nil
+}
@@ -0,0 +1,97 @@
+package generators
+
+import (
+	"path"
+	"sort"
+
+	"github.com/ContainerSolutions/helm-convert/pkg/gitops"
+	"github.com/ContainerSolutions/helm-convert/pkg/types"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	ktypes "sigs.k8s.io/kustomize/pkg/types"
+)
+
+// SetGitOps generate the objects deploying the package with a GitOps tool,
+// the CRDs and hooks are written in their own kustomization
+func (g *Generator) SetGitOps(options *gitops.Options) {
+	g.gitops = options
+}
+
+// writeStages write the resources and kustomization of each stage but the
+// main one, the config and resources of the main stage are returned
+func (g *Generator) writeStages(fs FileSystem, config *ktypes.Kustomization, stages []gitops.Stage,
+	addConfigComments bool) (*ktypes.Kustomization, *types.Resources, error) {
+	var main *types.Resources
+	for _, stage := range stages {
+		if stage.Directory == "" {
+			main = stage.Resources
+			continue
+		}
+
+		err := g.writeResources(fs, stage.Directory, stage.Resources)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		stageConfig := &ktypes.Kustomization{
+			CommonLabels:      config.CommonLabels,
+			CommonAnnotations: config.CommonAnnotations,
+			Images:            config.Images,
+		}
+		if !stage.ClusterScoped {
+			stageConfig.Namespace = config.Namespace
+			stageConfig.NamePrefix = config.NamePrefix
+			stageConfig.NameSuffix = config.NameSuffix
+		}
+		stageConfig.Resources, err = g.manifestPaths(stage.Resources)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		err = writeKustomizationFile(fs, path.Join(stage.Directory, DefaultKustomizationFilename),
+			convertKustomization(stageConfig, stage.Resources, g.version), addConfigComments)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	// the main kustomization only lists the manifests of the main stage
+	mainConfig := *config
+	if len(config.Resources) > 0 {
+		var err error
+		mainConfig.Resources, err = g.manifestPaths(main)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return &mainConfig, main, nil
+}
+
+// writeGitOps write the objects deploying the stages of the package
+func (g *Generator) writeGitOps(fs FileSystem, metadata *chart.Metadata, stages []gitops.Stage) error {
+	objects := gitops.Objects(g.gitops, metadata, stages)
+	orders := make([]*types.FieldOrder, len(objects))
+
+	return writeYamlDocuments(fs, path.Join(gitops.Directory, string(g.gitops.Tool)+".yaml"), objects, orders)
+}
+
+// manifestPaths return the sorted list of the manifests of the resources
+func (g *Generator) manifestPaths(resources *types.Resources) ([]string, error) {
+	filenames, err := g.filePaths(resources)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]struct{}, len(filenames))
+	var paths []string
+	for _, filename := range filenames {
+		if _, ok := seen[filename]; ok {
+			continue
+		}
+		seen[filename] = struct{}{}
+		paths = append(paths, filename)
+	}
+	sort.Strings(paths)
+
+	return paths, nil
+}
@@ -0,0 +1,99 @@
+package generators
+
+import (
+	"fmt"
+
+	"github.com/ContainerSolutions/helm-convert/pkg/components"
+	"github.com/ContainerSolutions/helm-convert/pkg/gitops"
+	"github.com/ContainerSolutions/helm-convert/pkg/types"
+	"github.com/golang/glog"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	ktypes "sigs.k8s.io/kustomize/pkg/types"
+)
+
+// RenderInflation render a kustomization inflating the chart with the
+// helmCharts field instead of vendoring the manifests. The changes made by
+// the transformers to the rendered resources are written as patches. The
+// file system is closed once all the files are written.
+func (g *Generator) RenderInflation(config *ktypes.Kustomization, metadata *chart.Metadata,
+	inflation *types.Inflation, resources *types.Resources, addConfigComments bool) error {
+	err := g.renderInflation(config, metadata, inflation, resources, addConfigComments)
+	if closeErr := g.fs.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (g *Generator) renderInflation(config *ktypes.Kustomization, metadata *chart.Metadata,
+	inflation *types.Inflation, resources *types.Resources, addConfigComments bool) error {
+	proceed, err := g.checkDestination()
+	if err != nil || !proceed {
+		return err
+	}
+
+	fs := newStagingFileSystem(g.fs)
+
+	// the difference between the rendered and the transformed resources is
+	// expressed the same way as a component: patches of the modified and
+	// removed resources, added resources are written as manifests
+	diff, err := components.Diff("inflate", config, config, inflation.Rendered, resources, g.namer)
+	if err != nil {
+		return err
+	}
+
+	err = g.writeResources(fs, "", diff.Resources)
+	if err != nil {
+		return err
+	}
+
+	for filename, patch := range diff.Patches {
+		err = writeYamlFile(fs, filename, patch)
+		if err != nil {
+			return err
+		}
+	}
+
+	config, err = g.writeArtifacts(fs, config)
+	if err != nil {
+		return err
+	}
+	if g.artifacts != nil && len(g.artifacts.Components) > 0 {
+		return fmt.Errorf("components cannot be generated with the %s mode", ModeInflate)
+	}
+
+	// helmCharts is only supported by kustomize v4 and later
+	version := g.version
+	if version < KustomizeV4 {
+		glog.V(4).Infof("Writing the kustomization for kustomize %s, helmCharts is not supported by %s",
+			KustomizeV4, version)
+		version = KustomizeV4
+	}
+
+	k := convertKustomization(config, resources, version).(*types.Kustomization)
+	k.Resources = diff.Config.Resources
+	k.HelmGlobals = inflation.Globals
+	k.HelmCharts = []types.HelmChart{*inflation.Chart}
+	k.Patches = append(k.Patches, diff.Config.Patches...)
+
+	err = writeKustomizationFile(fs, DefaultKustomizationFilename, k, addConfigComments)
+	if err != nil {
+		return err
+	}
+
+	err = writeYamlFile(fs, DefaultKubeDescriptorFilename, metadata)
+	if err != nil {
+		return err
+	}
+
+	if g.gitops != nil {
+		if g.gitops.Tool == gitops.Flux {
+			glog.Warningf("Flux doesn't inflate the helmCharts field of kustomizations")
+		}
+		err = g.writeGitOps(fs, metadata, []gitops.Stage{{Resources: resources}})
+		if err != nil {
+			return err
+		}
+	}
+
+	return g.commit(fs)
+}
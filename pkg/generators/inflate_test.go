@@ -0,0 +1,165 @@
+package generators
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/ContainerSolutions/helm-convert/pkg/types"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"sigs.k8s.io/kustomize/k8sdeps/kunstruct"
+	"sigs.k8s.io/kustomize/pkg/gvk"
+	"sigs.k8s.io/kustomize/pkg/resid"
+	"sigs.k8s.io/kustomize/pkg/resource"
+	ktypes "sigs.k8s.io/kustomize/pkg/types"
+)
+
+func TestRenderInflation(t *testing.T) {
+	var rf = resource.NewFactory(kunstruct.NewKunstructuredFactoryImpl())
+	var service = gvk.Gvk{Version: "v1", Kind: "Service"}
+
+	newService := func(labels map[string]interface{}) *resource.Resource {
+		return rf.FromMap(map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "Service",
+			"metadata":   map[string]interface{}{"name": "web", "labels": labels},
+		})
+	}
+
+	var crd = gvk.Gvk{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition"}
+
+	newCRD := func() *resource.Resource {
+		return rf.FromMap(map[string]interface{}{
+			"apiVersion": "apiextensions.k8s.io/v1",
+			"kind":       "CustomResourceDefinition",
+			"metadata":   map[string]interface{}{"name": "widgets.example.com"},
+		})
+	}
+
+	for _, test := range []struct {
+		name     string
+		config   *ktypes.Kustomization
+		rendered map[string]interface{}
+		crds     bool
+		expected map[string]string
+	}{
+		{
+			name:     "it should inflate the chart and patch the rendered resources",
+			config:   &ktypes.Kustomization{},
+			rendered: map[string]interface{}{"app": "web", "heritage": "Tiller"},
+			expected: map[string]string{
+				"kustomization.yaml": `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+
+helmCharts:
+  - name: web
+    repo: https://charts.example.com
+    version: 1.0.0
+    releaseName: web
+    valuesInline:
+      replicas: 2
+
+patches:
+  - path: patches/web-svc.yaml
+    target:
+      version: v1
+      kind: Service
+      name: web
+`,
+				"patches/web-svc.yaml": "- op: remove\n  path: /metadata/labels/heritage\n",
+			},
+		},
+		{
+			name:     "it should inflate the CRDs of the chart",
+			config:   &ktypes.Kustomization{},
+			rendered: map[string]interface{}{"app": "web"},
+			crds:     true,
+			expected: map[string]string{
+				"kustomization.yaml": `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+
+helmCharts:
+  - name: web
+    repo: https://charts.example.com
+    version: 1.0.0
+    releaseName: web
+    valuesInline:
+      replicas: 2
+    includeCRDs: true
+`,
+			},
+		},
+		{
+			name:     "it should not write patches when the transformers didn't change anything",
+			config:   &ktypes.Kustomization{Namespace: "web"},
+			rendered: map[string]interface{}{"app": "web"},
+			expected: map[string]string{
+				"kustomization.yaml": `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+
+namespace: web
+
+helmCharts:
+  - name: web
+    repo: https://charts.example.com
+    version: 1.0.0
+    releaseName: web
+    valuesInline:
+      replicas: 2
+`,
+			},
+		},
+	} {
+		t.Run(fmt.Sprintf("%s", test.name), func(t *testing.T) {
+			fs := NewArchiveFileSystem(OutputFormatTar, "test", func() (io.WriteCloser, error) {
+				return nil, nil
+			}).(*memoryFileSystem)
+
+			g, err := NewGenerator(fs, OnExistsOverwrite, nil, KustomizeV4, ModeInflate)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			id := resid.NewResId(service, "web")
+			rendered := types.NewResources()
+			rendered.ResMap[id] = newService(test.rendered)
+			resources := types.NewResources()
+			resources.ResMap[id] = newService(map[string]interface{}{"app": "web"})
+			if test.crds {
+				crdID := resid.NewResId(crd, "widgets.example.com")
+				rendered.ResMap[crdID] = newCRD()
+				resources.ResMap[crdID] = newCRD()
+			}
+
+			inflation := &types.Inflation{
+				Chart: &types.HelmChart{
+					Name:         "web",
+					Repo:         "https://charts.example.com",
+					Version:      "1.0.0",
+					ReleaseName:  "web",
+					ValuesInline: map[string]interface{}{"replicas": 2},
+					IncludeCRDs:  test.crds,
+				},
+				Rendered: rendered,
+			}
+
+			err = g.renderInflation(test.config, &chart.Metadata{Name: "web"}, inflation, resources, false)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			for p, expected := range test.expected {
+				data, err := fs.ReadFile(p)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if string(data) != expected {
+					t.Errorf("%s, %s: got\n%s\nwant\n%s", test.name, p, data, expected)
+				}
+			}
+			if fs.Exists("patches/web-svc.yaml") != (test.expected["patches/web-svc.yaml"] != "") {
+				t.Errorf("%s, unexpected patches/web-svc.yaml", test.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package generators
+
+import (
+	"github.com/ContainerSolutions/helm-convert/pkg/kpt"
+	"github.com/ContainerSolutions/helm-convert/pkg/types"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	ktypes "sigs.k8s.io/kustomize/pkg/types"
+)
+
+// writeKptPackage write the Kptfile, the setters and the apply-replacements
+// config of a kpt package. The metadata directory is listed in .krmignore so
+// that kpt doesn't read the pristine copies of the resources.
+func writeKptPackage(fs FileSystem, config *ktypes.Kustomization, metadata *chart.Metadata,
+	resources *types.Resources) error {
+	pkg, err := kpt.NewPackage(config, metadata, resources)
+	if err != nil {
+		return err
+	}
+
+	err = writeYamlDocuments(fs, kpt.KptfileName, []interface{}{pkg.Kptfile}, []*types.FieldOrder{nil})
+	if err != nil {
+		return err
+	}
+
+	if pkg.Replacements != nil {
+		err = writeYamlDocuments(fs, kpt.SettersFilename, []interface{}{pkg.Setters}, []*types.FieldOrder{nil})
+		if err != nil {
+			return err
+		}
+
+		err = writeYamlDocuments(fs, kpt.ReplacementsFilename, []interface{}{pkg.Replacements}, []*types.FieldOrder{nil})
+		if err != nil {
+			return err
+		}
+	}
+
+	return writeFile(fs, kpt.KrmIgnoreFilename, []byte(MetadataDirectory+"/\n"), 0644)
+}
@@ -0,0 +1,302 @@
+package generators
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+// kustomizationFields is the canonical order of the top level fields of a
+// kustomization.yaml file, unknown fields are written at the end in
+// alphabetical order
+var kustomizationFields = []string{
+	"apiVersion",
+	"kind",
+	"namespace",
+	"namePrefix",
+	"nameSuffix",
+	"commonLabels",
+	"labels",
+	"commonAnnotations",
+	"resources",
+	"bases",
+	"helmGlobals",
+	"helmCharts",
+	"crds",
+	"configurations",
+	"configMapGenerator",
+	"secretGenerator",
+	"generatorOptions",
+	"patchesStrategicMerge",
+	"patchesJson6902",
+	"patches",
+	"images",
+	"vars",
+	"replacements",
+	"sortOptions",
+}
+
+// identityKeys are the keys used to match the items of a list of maps when
+// carrying over comments from an existing file
+var identityKeys = []string{"name", "path", "newName"}
+
+// marshalKustomization marshal a kustomization config into YAML. Top level
+// fields are written in canonical order separated by a blank line. If comments
+// is true, default comments are attached to each top level field. Comments
+// found in the existing file, if any, are preserved and take precedence.
+func marshalKustomization(config interface{}, existing []byte, comments bool) ([]byte, error) {
+	j, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(j, &doc); err != nil {
+		return nil, err
+	}
+
+	root := documentContent(&doc)
+	if root == nil || root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("kustomization config is not a map")
+	}
+
+	// JSON doesn't carry any style, use the block style
+	resetStyle(root)
+	sortMappingNode(root, kustomizationFields)
+
+	if comments {
+		for i := 0; i < len(root.Content); i += 2 {
+			if comment, ok := commentsMapping[root.Content[i].Value]; ok {
+				root.Content[i].HeadComment = comment
+			}
+		}
+	}
+
+	if len(existing) > 0 {
+		var old yaml.Node
+		if err := yaml.Unmarshal(existing, &old); err != nil {
+			return nil, fmt.Errorf("couldn't parse existing kustomization: %v", err)
+		}
+		doc.HeadComment = old.HeadComment
+		doc.FootComment = old.FootComment
+		if oldRoot := documentContent(&old); oldRoot != nil {
+			copyComments(root, oldRoot)
+		}
+	}
+
+	return encodeMappingBlocks(&doc, root)
+}
+
+// formatKustomization rewrite a kustomization file with top level fields in
+// canonical order separated by a blank line, comments are preserved
+func formatKustomization(data []byte) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	root := documentContent(&doc)
+	if root == nil || root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("kustomization is not a map")
+	}
+
+	sortMappingNode(root, kustomizationFields)
+	return encodeMappingBlocks(&doc, root)
+}
+
+// encodeMappingBlocks encode each top level field of a map separately, fields
+// are separated by a blank line
+func encodeMappingBlocks(doc, root *yaml.Node) ([]byte, error) {
+	var blocks [][]byte
+
+	if doc.HeadComment != "" {
+		blocks = append(blocks, []byte(doc.HeadComment+"\n"))
+	}
+
+	for i := 0; i < len(root.Content); i += 2 {
+		// apiVersion and kind are kept together
+		end := i + 2
+		for end < len(root.Content) && isHeaderField(root.Content[i].Value) &&
+			isHeaderField(root.Content[end].Value) {
+			end += 2
+		}
+
+		block, err := encodeNode(&yaml.Node{
+			Kind:    yaml.MappingNode,
+			Content: root.Content[i:end],
+		})
+		if err != nil {
+			return nil, err
+		}
+		blocks = append(blocks, block)
+		i = end - 2
+	}
+
+	if doc.FootComment != "" {
+		blocks = append(blocks, []byte(doc.FootComment+"\n"))
+	}
+
+	return bytes.Join(blocks, []byte("\n")), nil
+}
+
+func isHeaderField(key string) bool {
+	return key == "apiVersion" || key == "kind"
+}
+
+func encodeNode(node *yaml.Node) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(node); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// documentContent return the root node of a YAML document
+func documentContent(doc *yaml.Node) *yaml.Node {
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		return doc.Content[0]
+	}
+	return nil
+}
+
+// resetStyle recursively set the style of maps and lists to block style
+func resetStyle(node *yaml.Node) {
+	if node.Kind == yaml.MappingNode || node.Kind == yaml.SequenceNode {
+		node.Style = 0
+	}
+	if node.Kind == yaml.ScalarNode && node.Style == yaml.DoubleQuotedStyle &&
+		node.Tag == "!!str" {
+		// let the encoder decide if a string needs to be quoted
+		node.Style = 0
+	}
+	for _, child := range node.Content {
+		resetStyle(child)
+	}
+}
+
+// sortMappingNode sort the key/value pairs of a map following the given
+// order, unknown keys are sorted alphabetically after known ones
+func sortMappingNode(node *yaml.Node, order []string) {
+	type pair struct {
+		key, value *yaml.Node
+	}
+
+	pairs := make([]pair, 0, len(node.Content)/2)
+	for i := 0; i < len(node.Content); i += 2 {
+		pairs = append(pairs, pair{node.Content[i], node.Content[i+1]})
+	}
+
+	rank := func(key string) int {
+		if i := indexOf(order, key); i >= 0 {
+			return i
+		}
+		return len(order)
+	}
+
+	sort.SliceStable(pairs, func(i, j int) bool {
+		ri, rj := rank(pairs[i].key.Value), rank(pairs[j].key.Value)
+		if ri != rj {
+			return ri < rj
+		}
+		return pairs[i].key.Value < pairs[j].key.Value
+	})
+
+	node.Content = node.Content[:0]
+	for _, p := range pairs {
+		node.Content = append(node.Content, p.key, p.value)
+	}
+}
+
+// copyComments recursively copy the comments of the old node to the matching
+// nodes of the new one
+func copyComments(node, old *yaml.Node) {
+	copyNodeComments(node, old)
+
+	switch {
+	case node.Kind == yaml.MappingNode && old.Kind == yaml.MappingNode:
+		for i := 0; i < len(node.Content); i += 2 {
+			key := node.Content[i]
+			for j := 0; j < len(old.Content); j += 2 {
+				if old.Content[j].Value == key.Value {
+					copyNodeComments(key, old.Content[j])
+					copyComments(node.Content[i+1], old.Content[j+1])
+					break
+				}
+			}
+		}
+	case node.Kind == yaml.SequenceNode && old.Kind == yaml.SequenceNode:
+		for i, item := range node.Content {
+			if match := matchSequenceItem(item, i, old); match != nil {
+				copyComments(item, match)
+			}
+		}
+	}
+}
+
+// copyNodeComments copy the comments of a node, comments defined on the new
+// node are replaced by the user defined ones
+func copyNodeComments(node, old *yaml.Node) {
+	if old.HeadComment != "" {
+		node.HeadComment = old.HeadComment
+	}
+	if old.LineComment != "" {
+		node.LineComment = old.LineComment
+	}
+	if old.FootComment != "" {
+		node.FootComment = old.FootComment
+	}
+}
+
+// matchSequenceItem find the item of an old list corresponding to an item of
+// the new list. Scalars are matched by value, maps by identity key, falling
+// back to the position in the list.
+func matchSequenceItem(item *yaml.Node, index int, old *yaml.Node) *yaml.Node {
+	switch item.Kind {
+	case yaml.ScalarNode:
+		for _, o := range old.Content {
+			if o.Kind == yaml.ScalarNode && o.Value == item.Value {
+				return o
+			}
+		}
+		return nil
+	case yaml.MappingNode:
+		for _, key := range identityKeys {
+			value := mappingValue(item, key)
+			if value == "" {
+				continue
+			}
+			for _, o := range old.Content {
+				if o.Kind == yaml.MappingNode && mappingValue(o, key) == value {
+					return o
+				}
+			}
+			return nil
+		}
+	}
+
+	if index < len(old.Content) && old.Content[index].Kind == item.Kind {
+		return old.Content[index]
+	}
+	return nil
+}
+
+// mappingValue return the value of a scalar field of a map
+func mappingValue(node *yaml.Node, key string) string {
+	if node == nil {
+		return ""
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key && node.Content[i+1].Kind == yaml.ScalarNode {
+			return node.Content[i+1].Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,119 @@
+package generators
+
+import (
+	"fmt"
+	"testing"
+
+	ktypes "sigs.k8s.io/kustomize/pkg/types"
+)
+
+func TestMarshalKustomization(t *testing.T) {
+	config := &ktypes.Kustomization{
+		NamePrefix: "demo-",
+		Resources:  []string{"web-deploy.yaml", "web-svc.yaml"},
+		ConfigMapGenerator: []ktypes.ConfigMapArgs{
+			{
+				GeneratorArgs: ktypes.GeneratorArgs{
+					Name: "web",
+					DataSources: ktypes.DataSources{
+						LiteralSources: []string{"debug=true"},
+					},
+				},
+			},
+		},
+	}
+
+	for _, test := range []struct {
+		name     string
+		comments bool
+		existing string
+		expected string
+	}{
+		{
+			name: "it should write fields in canonical order separated by a blank line",
+			expected: `namePrefix: demo-
+
+resources:
+  - web-deploy.yaml
+  - web-svc.yaml
+
+configMapGenerator:
+  - name: web
+    literals:
+      - debug=true
+`,
+		},
+		{
+			name:     "it should add default comments",
+			comments: true,
+			expected: `# Value of this field is prepended to the
+# names of all resources
+namePrefix: demo-
+
+# List of resource files that kustomize reads, modifies
+# and emits as a YAML string
+resources:
+  - web-deploy.yaml
+  - web-svc.yaml
+
+# Each entry in this list results in the creation of
+# one ConfigMap resource (it's a generator of n maps).
+configMapGenerator:
+  - name: web
+    literals:
+      - debug=true
+`,
+		},
+		{
+			name:     "it should preserve comments of the existing file",
+			comments: true,
+			existing: `# Generated from the web chart
+
+# resources are listed by hand
+resources:
+- web-svc.yaml # the service
+- old-deploy.yaml
+configMapGenerator:
+- name: web
+  # enable debug
+  literals:
+  - debug=false
+# end of file
+`,
+			expected: `# Generated from the web chart
+
+# Value of this field is prepended to the
+# names of all resources
+namePrefix: demo-
+
+# resources are listed by hand
+resources:
+  - web-deploy.yaml
+  - web-svc.yaml # the service
+
+# Each entry in this list results in the creation of
+# one ConfigMap resource (it's a generator of n maps).
+configMapGenerator:
+  - name: web
+    # enable debug
+    literals:
+      - debug=true
+# end of file
+`,
+		},
+	} {
+		t.Run(fmt.Sprintf("%s", test.name), func(t *testing.T) {
+			output, err := marshalKustomization(config, []byte(test.existing), test.comments)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(output) != test.expected {
+				t.Fatalf(
+					"expected: \n%v\ngot:\n%v",
+					test.expected,
+					string(output),
+				)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package generators
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	// MetadataDirectory is the directory containing the metadata of a
+	// conversion
+	MetadataDirectory = ".helm-convert"
+
+	// FilesManifestFilename is the list of files generated by the last
+	// conversion, one path per line
+	FilesManifestFilename = MetadataDirectory + "/files"
+
+	// BaseDirectory contains a pristine copy of the files generated by the
+	// last conversion, used as base of the three-way merge when upgrading
+	BaseDirectory = MetadataDirectory + "/base"
+)
+
+// stagingFileSystem keep generated files in memory until they are
+// committed, files which are not staged are read from the destination. Paths
+// are relative to dir, the root of the package by default.
+type stagingFileSystem struct {
+	FileSystem
+	files map[string][]byte
+	dir   string
+}
+
+func newStagingFileSystem(fs FileSystem) *stagingFileSystem {
+	return &stagingFileSystem{FileSystem: fs, files: make(map[string][]byte)}
+}
+
+func (fs *stagingFileSystem) Exists(p string) bool {
+	p = path.Join(fs.dir, p)
+	if _, ok := fs.files[p]; ok {
+		return true
+	}
+	return fs.FileSystem.Exists(p)
+}
+
+func (fs *stagingFileSystem) ReadFile(p string) ([]byte, error) {
+	p = path.Join(fs.dir, p)
+	if data, ok := fs.files[p]; ok {
+		return data, nil
+	}
+	return fs.FileSystem.ReadFile(p)
+}
+
+func (fs *stagingFileSystem) WriteFile(p string, data []byte, perm os.FileMode) error {
+	fs.files[path.Join(fs.dir, p)] = data
+	return nil
+}
+
+// staged return the set of staged files
+func (fs *stagingFileSystem) staged() map[string]struct{} {
+	files := make(map[string]struct{}, len(fs.files))
+	for p := range fs.files {
+		files[p] = struct{}{}
+	}
+	return files
+}
+
+// keepsMetadata return true if the metadata used by the next conversion to
+// clean or upgrade the package is written, archives and bundles are generated
+// from scratch so they only contain the package
+func keepsMetadata(fs FileSystem) bool {
+	_, ok := fs.(*dirFileSystem)
+	return ok
+}
+
+// readFilesManifest return the files generated by the previous conversion,
+// nil if there is no manifest. The files are removed when they are not
+// generated anymore, so paths outside of the package or in the metadata
+// directory are rejected.
+func readFilesManifest(fs FileSystem) (map[string]struct{}, error) {
+	if !fs.Exists(FilesManifestFilename) {
+		return nil, nil
+	}
+
+	data, err := fs.ReadFile(FilesManifestFilename)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]struct{})
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		p := path.Clean(line)
+		if path.IsAbs(p) || p == ".." || strings.HasPrefix(p, "../") ||
+			p == MetadataDirectory || strings.HasPrefix(p, MetadataDirectory+"/") {
+			return nil, fmt.Errorf("invalid %s, %s is not a file of the package", FilesManifestFilename, line)
+		}
+		files[p] = struct{}{}
+	}
+	return files, nil
+}
+
+// writeFilesManifest write the list of generated files
+func writeFilesManifest(fs FileSystem, files map[string]struct{}) error {
+	return writeFile(fs, FilesManifestFilename, []byte(strings.Join(sortedPaths(files), "\n")+"\n"), 0644)
+}
+
+func sortedPaths(files map[string]struct{}) []string {
+	paths := make([]string, 0, len(files))
+	for p := range files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
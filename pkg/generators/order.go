@@ -0,0 +1,137 @@
+package generators
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/ContainerSolutions/helm-convert/pkg/types"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// topLevelFields is the conventional order of the top level fields of a
+// Kubernetes manifest
+var topLevelFields = []string{
+	"apiVersion",
+	"kind",
+	"metadata",
+	"spec",
+	"type",
+	"data",
+	"stringData",
+	"binaryData",
+}
+
+// lastTopLevelFields are always written at the end of a manifest
+var lastTopLevelFields = []string{"status"}
+
+// nestedFields is the conventional order of the fields of a nested map,
+// indexed by the key of the map. Fields not listed are sorted alphabetically.
+var nestedFields = map[string][]string{
+	"metadata":       {"name", "generateName", "namespace", "labels", "annotations"},
+	"containers":     {"name", "image", "imagePullPolicy", "command", "args"},
+	"initContainers": {"name", "image", "imagePullPolicy", "command", "args"},
+	"mutators":       {"image", "configPath", "configMap"},
+	"":               {"name"},
+}
+
+// marshalOrderedYaml marshal data into YAML. Top level fields follow the
+// Kubernetes conventional order, nested fields follow the order in which they
+// were declared in the rendered template then the conventional order.
+func marshalOrderedYaml(data interface{}, order *types.FieldOrder) ([]byte, error) {
+	j, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(j, &doc); err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(sortTopLevel(doc, order))
+}
+
+// sortTopLevel sort the top level fields of a manifest
+func sortTopLevel(m yaml.MapSlice, order *types.FieldOrder) yaml.MapSlice {
+	rank := func(key string) (int, int) {
+		if i := indexOf(topLevelFields, key); i >= 0 {
+			return 0, i
+		}
+		if i := indexOf(lastTopLevelFields, key); i >= 0 {
+			return 3, i
+		}
+		if i := order.Index(key); i >= 0 {
+			return 1, i
+		}
+		return 2, 0
+	}
+
+	return sortMapSlice(m, order, rank)
+}
+
+// sortNested sort the fields of a nested map
+func sortNested(key string, m yaml.MapSlice, order *types.FieldOrder) yaml.MapSlice {
+	fields, ok := nestedFields[key]
+	if !ok {
+		fields = nestedFields[""]
+	}
+
+	rank := func(key string) (int, int) {
+		if i := order.Index(key); i >= 0 {
+			return 0, i
+		}
+		if i := indexOf(fields, key); i >= 0 {
+			return 1, i
+		}
+		return 2, 0
+	}
+
+	return sortMapSlice(m, order, rank)
+}
+
+// sortMapSlice sort a map using a rank function returning a group and a
+// position in this group, keys with the same rank are sorted alphabetically.
+// Nested maps and lists of maps are sorted recursively.
+func sortMapSlice(m yaml.MapSlice, order *types.FieldOrder, rank func(string) (int, int)) yaml.MapSlice {
+	for i := range m {
+		key := fmt.Sprint(m[i].Key)
+		child := order.Child(key)
+
+		switch typedV := m[i].Value.(type) {
+		case yaml.MapSlice:
+			m[i].Value = sortNested(key, typedV, child)
+		case []interface{}:
+			for j := range typedV {
+				if item, ok := typedV[j].(yaml.MapSlice); ok {
+					typedV[j] = sortNested(key, item, child)
+				}
+			}
+		}
+	}
+
+	sort.SliceStable(m, func(i, j int) bool {
+		ki, kj := fmt.Sprint(m[i].Key), fmt.Sprint(m[j].Key)
+		gi, pi := rank(ki)
+		gj, pj := rank(kj)
+		if gi != gj {
+			return gi < gj
+		}
+		if pi != pj {
+			return pi < pj
+		}
+		return ki < kj
+	})
+
+	return m
+}
+
+// indexOf return the position of a string in a list, -1 if not found
+func indexOf(list []string, s string) int {
+	for i, item := range list {
+		if item == s {
+			return i
+		}
+	}
+	return -1
+}
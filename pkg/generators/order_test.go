@@ -0,0 +1,99 @@
+package generators
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ContainerSolutions/helm-convert/pkg/types"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestMarshalOrderedYaml(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		input    map[string]interface{}
+		template string
+		expected string
+	}{
+		{
+			name: "it should use the conventional order when the template order is unknown",
+			input: map[string]interface{}{
+				"data":       map[string]interface{}{"b": "1", "a": "2"},
+				"kind":       "ConfigMap",
+				"metadata":   map[string]interface{}{"labels": map[string]interface{}{"app": "demo"}, "name": "cm"},
+				"apiVersion": "v1",
+			},
+			expected: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: cm
+  labels:
+    app: demo
+data:
+  a: "2"
+  b: "1"
+`,
+		},
+		{
+			name: "it should preserve the template order of nested fields",
+			input: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Service",
+				"metadata":   map[string]interface{}{"name": "svc"},
+				"spec": map[string]interface{}{
+					"type": "NodePort",
+					"ports": []interface{}{
+						map[string]interface{}{"targetPort": 8080, "port": 80},
+					},
+				},
+				"status": map[string]interface{}{"loadBalancer": map[string]interface{}{}},
+			},
+			template: `kind: Service
+status: {}
+spec:
+  ports:
+  - targetPort: 8080
+    port: 80
+  type: NodePort
+apiVersion: v1
+metadata:
+  name: svc
+`,
+			expected: `apiVersion: v1
+kind: Service
+metadata:
+  name: svc
+spec:
+  ports:
+  - targetPort: 8080
+    port: 80
+  type: NodePort
+status:
+  loadBalancer: {}
+`,
+		},
+	} {
+		t.Run(fmt.Sprintf("%s", test.name), func(t *testing.T) {
+			var order *types.FieldOrder
+			if test.template != "" {
+				var doc yaml.MapSlice
+				if err := yaml.Unmarshal([]byte(test.template), &doc); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				order = types.NewFieldOrder(doc)
+			}
+
+			output, err := marshalOrderedYaml(test.input, order)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(output) != test.expected {
+				t.Fatalf(
+					"expected: \n%v\ngot:\n%v",
+					test.expected,
+					string(output),
+				)
+			}
+		})
+	}
+}
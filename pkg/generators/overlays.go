@@ -0,0 +1,52 @@
+package generators
+
+import (
+	"fmt"
+	"path"
+
+	"github.com/ContainerSolutions/helm-convert/pkg/overlays"
+	"github.com/ContainerSolutions/helm-convert/pkg/types"
+)
+
+// SetOverlays write the package in the base directory and an overlay per
+// Kubernetes version on top of it
+func (g *Generator) SetOverlays(list []*types.Overlay) {
+	g.overlays = list
+}
+
+// checkOverlays return an error if the overlays cannot be generated with the
+// other options of the generator
+func (g *Generator) checkOverlays() error {
+	if len(g.overlays) == 0 {
+		return nil
+	}
+	if g.mode != ModeKustomize {
+		return fmt.Errorf("overlays cannot be generated with the %s mode", g.mode)
+	}
+	if g.gitops != nil {
+		return fmt.Errorf("overlays cannot be generated with GitOps objects")
+	}
+	return nil
+}
+
+// writeOverlays write the resources and kustomization of each overlay, the
+// base is referenced relatively to the overlay directory
+func (g *Generator) writeOverlays(fs FileSystem, addConfigComments bool) error {
+	for _, overlay := range g.overlays {
+		dir := path.Join(overlays.Directory, overlay.Name)
+
+		err := g.writeResources(fs, dir, overlay.Resources)
+		if err != nil {
+			return err
+		}
+
+		config := *overlay.Config
+		config.Bases = []string{path.Join("..", "..", overlays.BaseDirectory)}
+		err = writeKustomizationFile(fs, path.Join(dir, DefaultKustomizationFilename),
+			convertKustomization(&config, overlay.Resources, g.version), addConfigComments)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
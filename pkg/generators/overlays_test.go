@@ -0,0 +1,125 @@
+package generators
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/ContainerSolutions/helm-convert/pkg/gitops"
+	"github.com/ContainerSolutions/helm-convert/pkg/types"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"sigs.k8s.io/kustomize/k8sdeps/kunstruct"
+	"sigs.k8s.io/kustomize/pkg/gvk"
+	"sigs.k8s.io/kustomize/pkg/resid"
+	"sigs.k8s.io/kustomize/pkg/resource"
+	ktypes "sigs.k8s.io/kustomize/pkg/types"
+)
+
+func TestRenderOverlays(t *testing.T) {
+	var rf = resource.NewFactory(kunstruct.NewKunstructuredFactoryImpl())
+	var service = gvk.Gvk{Version: "v1", Kind: "Service"}
+	var ingress = gvk.Gvk{Group: "networking.k8s.io", Version: "v1", Kind: "Ingress"}
+
+	for _, test := range []struct {
+		name          string
+		version       KustomizeVersion
+		gitops        *gitops.Options
+		expected      map[string]string
+		expectedError string
+	}{
+		{
+			name:    "it should write the package in the base and the overlays on top of it",
+			version: KustomizeV2,
+			expected: map[string]string{
+				"base/kustomization.yaml":   "resources:\n  - web-svc.yaml\n",
+				"base/web-svc.yaml":         "apiVersion: v1\nkind: Service\nmetadata:\n  name: web\n",
+				"base/Kube-descriptor.yaml": "name: web\n",
+				"overlays/k8s-1.28/kustomization.yaml": `namePrefix: release-
+
+resources:
+  - web-ing.yaml
+
+bases:
+  - ../../base
+`,
+				"overlays/k8s-1.28/web-ing.yaml": "apiVersion: networking.k8s.io/v1\nkind: Ingress\nmetadata:\n  name: web\n",
+			},
+		},
+		{
+			name:    "it should reference the base as a resource with kustomize v3",
+			version: KustomizeV3,
+			expected: map[string]string{
+				"overlays/k8s-1.28/kustomization.yaml": `apiVersion: kustomize.config.k8s.io/v1beta1
+kind: Kustomization
+
+namePrefix: release-
+
+resources:
+  - web-ing.yaml
+  - ../../base
+`,
+			},
+		},
+		{
+			name:          "it should not generate overlays with the GitOps objects",
+			version:       KustomizeV2,
+			gitops:        &gitops.Options{Tool: gitops.Flux, Name: "web"},
+			expectedError: "overlays cannot be generated with GitOps objects",
+		},
+	} {
+		t.Run(fmt.Sprintf("%s", test.name), func(t *testing.T) {
+			fs := NewArchiveFileSystem(OutputFormatTar, "test", func() (io.WriteCloser, error) {
+				return nil, nil
+			}).(*memoryFileSystem)
+
+			g, err := NewGenerator(fs, OnExistsOverwrite, nil, test.version, ModeKustomize)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			g.SetGitOps(test.gitops)
+
+			overlay := types.NewOverlay("k8s-1.28")
+			overlay.Config.NamePrefix = "release-"
+			overlay.Config.Resources = []string{"web-ing.yaml"}
+			overlay.Resources.ResMap[resid.NewResId(ingress, "web")] = rf.FromMap(map[string]interface{}{
+				"apiVersion": "networking.k8s.io/v1",
+				"kind":       "Ingress",
+				"metadata":   map[string]interface{}{"name": "web"},
+			})
+			g.SetOverlays([]*types.Overlay{overlay})
+
+			resources := types.NewResources()
+			resources.ResMap[resid.NewResId(service, "web")] = rf.FromMap(map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Service",
+				"metadata":   map[string]interface{}{"name": "web"},
+			})
+
+			config := &ktypes.Kustomization{Resources: []string{"web-svc.yaml"}}
+			err = g.render(config, &chart.Metadata{Name: "web"}, resources, nil, false)
+			if test.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedError) {
+					t.Fatalf("%s: expected error %q, got %v", test.name, test.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if fs.Exists(DefaultKustomizationFilename) {
+				t.Errorf("%s: expected no kustomization at the root of the package", test.name)
+			}
+			for p, expected := range test.expected {
+				data, err := fs.ReadFile(p)
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if string(data) != expected {
+					t.Errorf("%s, %s: got\n%s\nwant\n%s", test.name, p, data, expected)
+				}
+			}
+		})
+	}
+}
@@ -1,78 +1,65 @@
 package generators
 
 import (
-	"bufio"
-	"io/ioutil"
 	"os"
-	"regexp"
-	"strings"
 
+	"github.com/ContainerSolutions/helm-convert/pkg/types"
 	"github.com/ghodss/yaml"
 	"github.com/golang/glog"
 )
 
-// Pattern used to detect if a line contains a YAML key
-var yamlKeyPattern = regexp.MustCompile("^[^ :]*:")
-
 // writeYamlFile write a given interface into yaml
-func writeYamlFile(filePath string, data interface{}) error {
+func writeYamlFile(fs FileSystem, filePath string, data interface{}) error {
 	output, err := yaml.Marshal(data)
 	if err != nil {
 		return err
 	}
 
-	return writeFile(filePath, output, 0644)
+	return writeFile(fs, filePath, output, 0644)
 }
 
-// writeAndFormatKustomizationConfig adds line break and comments
-func writeAndFormatKustomizationConfig(filePath string, comments bool) error {
-	glog.V(4).Infof("Formatting %s", filePath)
-
-	file, err := os.Open(filePath)
-	if err != nil {
-		return err
-	}
-
-	defer file.Close()
-
-	var output []string
-
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		line := scanner.Text()
-
-		// add line break before comment except for if this is the first line
-		if yamlKeyPattern.MatchString(line) && len(output) > 0 {
-			output = append(output, "")
+// writeYamlDocuments write a list of interfaces as a multi-document yaml file,
+// fields of each document are ordered using the corresponding field order
+func writeYamlDocuments(fs FileSystem, filePath string, documents []interface{}, orders []*types.FieldOrder) error {
+	var output []byte
+	for i, data := range documents {
+		document, err := marshalOrderedYaml(data, orders[i])
+		if err != nil {
+			return err
 		}
-
-		// add comments
-		if comments {
-			for key, value := range commentsMapping {
-				if strings.HasPrefix(line, key+":") {
-					output = append(output, value)
-				}
-			}
+		if i > 0 {
+			output = append(output, []byte("---\n")...)
 		}
-
-		output = append(output, line)
-	}
-
-	if err := scanner.Err(); err != nil {
-		return err
+		output = append(output, document...)
 	}
 
-	return writeFile(filePath, []byte(strings.Join(output, "\n")), 0644)
+	return writeFile(fs, filePath, output, 0644)
 }
 
-// writeFile writes data to a file named by filename.
-func writeFile(filePath string, data []byte, perm os.FileMode) error {
-	glog.V(4).Infof("Writing %s", filePath)
+// writeKustomizationFile write a kustomization config, comments of the file
+// being replaced are preserved
+func writeKustomizationFile(fs FileSystem, filePath string, config interface{}, comments bool) error {
+	var existing []byte
+	if fs.Exists(filePath) {
+		glog.V(4).Infof("Loading comments from %s", filePath)
+
+		var err error
+		existing, err = fs.ReadFile(filePath)
+		if err != nil {
+			return err
+		}
+	}
 
-	err := ioutil.WriteFile(filePath, data, perm)
+	output, err := marshalKustomization(config, existing, comments)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return writeFile(fs, filePath, output, 0644)
+}
+
+// writeFile writes data to a file named by filename, parent directories are
+// created if they don't exist.
+func writeFile(fs FileSystem, filePath string, data []byte, perm os.FileMode) error {
+	return fs.WriteFile(filePath, data, perm)
 }
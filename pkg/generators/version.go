@@ -0,0 +1,325 @@
+package generators
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ContainerSolutions/helm-convert/pkg/types"
+	"github.com/ContainerSolutions/helm-convert/pkg/utils"
+	"github.com/golang/glog"
+	"sigs.k8s.io/kustomize/pkg/resid"
+	ktypes "sigs.k8s.io/kustomize/pkg/types"
+)
+
+// KustomizeVersion is the major version of kustomize the kustomization.yaml
+// files are written for
+type KustomizeVersion int
+
+const (
+	// KustomizeV2 write the legacy schema, without apiVersion and kind
+	KustomizeV2 KustomizeVersion = 2
+
+	// KustomizeV3 add apiVersion and kind, list bases in resources, env
+	// files in envs and all patches in patches
+	KustomizeV3 KustomizeVersion = 3
+
+	// KustomizeV4 replace commonLabels by labels and vars by replacements
+	KustomizeV4 KustomizeVersion = 4
+
+	// KustomizeV5 add sortOptions to keep the Helm install order
+	KustomizeV5 KustomizeVersion = 5
+)
+
+// KustomizeVersions is the list of supported kustomize versions
+var KustomizeVersions = []KustomizeVersion{KustomizeV2, KustomizeV3, KustomizeV4, KustomizeV5}
+
+// installOrder is the order in which Helm installs resources, kinds which
+// are not listed are installed after
+var installOrder = []string{
+	"Namespace",
+	"ResourceQuota",
+	"LimitRange",
+	"PodSecurityPolicy",
+	"PodDisruptionBudget",
+	"Secret",
+	"ConfigMap",
+	"StorageClass",
+	"PersistentVolume",
+	"PersistentVolumeClaim",
+	"ServiceAccount",
+	"CustomResourceDefinition",
+	"ClusterRole",
+	"ClusterRoleBinding",
+	"Role",
+	"RoleBinding",
+	"Service",
+	"DaemonSet",
+	"Pod",
+	"ReplicationController",
+	"ReplicaSet",
+	"Deployment",
+	"StatefulSet",
+	"Job",
+	"CronJob",
+	"Ingress",
+	"APIService",
+}
+
+// webhookOrder are the kinds output last by the default legacy order of
+// kustomize, the sort options replace the defaults so they are listed too
+var webhookOrder = []string{
+	"MutatingWebhookConfiguration",
+	"ValidatingWebhookConfiguration",
+}
+
+var indexPattern = regexp.MustCompile(`\[(\d+)\]`)
+
+func (v KustomizeVersion) String() string {
+	return fmt.Sprintf("v%d", v)
+}
+
+// ParseKustomizeVersion parse a kustomize version, ie: v5, 4 or v3.5.4, only
+// the major version is kept
+func ParseKustomizeVersion(s string) (KustomizeVersion, error) {
+	major := strings.SplitN(strings.TrimPrefix(s, "v"), ".", 2)[0]
+	n, err := strconv.Atoi(major)
+	if err == nil {
+		for _, v := range KustomizeVersions {
+			if int(v) == n {
+				return v, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unsupported kustomize version '%s', expected one of %v", s, KustomizeVersions)
+}
+
+// convertKustomization return the kustomization config in the schema of the
+// given kustomize version, the resources are used to find the fields
+// referencing vars
+func convertKustomization(config *ktypes.Kustomization, resources *types.Resources,
+	version KustomizeVersion) interface{} {
+	if version < KustomizeV3 {
+		return config
+	}
+
+	k := &types.Kustomization{
+		APIVersion:        types.KustomizationAPIVersion,
+		Kind:              types.KustomizationKind,
+		Namespace:         config.Namespace,
+		NamePrefix:        config.NamePrefix,
+		NameSuffix:        config.NameSuffix,
+		CommonAnnotations: config.CommonAnnotations,
+		Resources:         append(append([]string{}, config.Resources...), config.Bases...),
+		Crds:              config.Crds,
+		Configurations:    config.Configurations,
+		GeneratorOptions:  config.GeneratorOptions,
+		Images:            config.Images,
+	}
+	if len(k.Resources) == 0 {
+		k.Resources = nil
+	}
+
+	for _, args := range config.ConfigMapGenerator {
+		k.ConfigMapGenerator = append(k.ConfigMapGenerator, convertGeneratorArgs(args.GeneratorArgs, ""))
+	}
+	for _, args := range config.SecretGenerator {
+		k.SecretGenerator = append(k.SecretGenerator, convertGeneratorArgs(args.GeneratorArgs, args.Type))
+	}
+
+	for _, p := range config.PatchesStrategicMerge {
+		k.Patches = append(k.Patches, types.Patch{Path: string(p)})
+	}
+	for _, p := range config.PatchesJson6902 {
+		patch := types.Patch{Path: p.Path}
+		if p.Target != nil {
+			patch.Target = &types.PatchTarget{
+				Group:     p.Target.Group,
+				Version:   p.Target.Version,
+				Kind:      p.Target.Kind,
+				Name:      p.Target.Name,
+				Namespace: p.Target.Namespace,
+			}
+		}
+		k.Patches = append(k.Patches, patch)
+	}
+
+	if version < KustomizeV4 {
+		k.CommonLabels = config.CommonLabels
+		k.Vars = config.Vars
+	} else {
+		k.Labels = convertLabels(config.CommonLabels)
+		for _, v := range config.Vars {
+			replacement, err := convertVar(v, resources)
+			if err != nil {
+				glog.Warningf("Var %s is kept as a var: %v", v.Name, err)
+				k.Vars = append(k.Vars, v)
+				continue
+			}
+			k.Replacements = append(k.Replacements, *replacement)
+		}
+	}
+
+	if version >= KustomizeV5 {
+		k.SortOptions = &types.SortOptions{
+			Order:             "legacy",
+			LegacySortOptions: &types.LegacySortOptions{OrderFirst: installOrder, OrderLast: webhookOrder},
+		}
+	}
+
+	return k
+}
+
+// convertComponentConfig return the kustomization config of a component in
+// the schema of the given kustomize version
+func convertComponentConfig(config *types.ComponentConfig, version KustomizeVersion) *types.ComponentConfig {
+	if version < KustomizeV4 {
+		return config
+	}
+
+	c := *config
+	c.Labels = convertLabels(config.CommonLabels)
+	c.CommonLabels = nil
+	return &c
+}
+
+func convertGeneratorArgs(args ktypes.GeneratorArgs, secretType string) types.GeneratorArgs {
+	converted := types.GeneratorArgs{
+		Name:      args.Name,
+		Namespace: args.Namespace,
+		Behavior:  args.Behavior,
+		Type:      secretType,
+		Literals:  args.LiteralSources,
+		Files:     args.FileSources,
+	}
+	if args.EnvSource != "" {
+		converted.Envs = []string{args.EnvSource}
+	}
+	return converted
+}
+
+// convertLabels return the labels equivalent to commonLabels, which are also
+// added to selectors and templates
+func convertLabels(labels map[string]string) []types.Label {
+	if len(labels) == 0 {
+		return nil
+	}
+	return []types.Label{{Pairs: labels, IncludeSelectors: true}}
+}
+
+// convertVar return the replacement equivalent to a var. Only fields which
+// value is exactly $(NAME) can be replaced, a var embedded in a longer string
+// cannot be expressed as a replacement.
+func convertVar(v ktypes.Var, resources *types.Resources) (*types.Replacement, error) {
+	group, version := v.ObjRef.Group, v.ObjRef.Version
+	if v.ObjRef.APIVersion != "" {
+		if i := strings.Index(v.ObjRef.APIVersion, "/"); i >= 0 {
+			group, version = v.ObjRef.APIVersion[:i], v.ObjRef.APIVersion[i+1:]
+		} else {
+			version = v.ObjRef.APIVersion
+		}
+	}
+
+	fieldPath := v.FieldRef.FieldPath
+	if fieldPath == "" {
+		fieldPath = "metadata.name"
+	}
+
+	replacement := &types.Replacement{
+		Source: &types.ReplacementSource{
+			Group:     group,
+			Version:   version,
+			Kind:      v.ObjRef.Kind,
+			Name:      v.ObjRef.Name,
+			FieldPath: indexPattern.ReplaceAllString(fieldPath, ".$1"),
+		},
+	}
+
+	reference := "$(" + v.Name + ")"
+	for _, id := range utils.SortedResIds(resources.ResMap) {
+		var fieldPaths []string
+		err := findReferences(resources.ResMap[id].Map(), nil, reference, &fieldPaths)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", id, err)
+		}
+		if len(fieldPaths) == 0 {
+			continue
+		}
+
+		replacement.Targets = append(replacement.Targets, types.ReplacementTarget{
+			Select:     selectTarget(id),
+			FieldPaths: fieldPaths,
+		})
+	}
+
+	if len(replacement.Targets) == 0 {
+		return nil, fmt.Errorf("no field references %s", reference)
+	}
+
+	return replacement, nil
+}
+
+// findReferences append the path of the fields which value is the given
+// reference, list items are selected by name when they have one
+func findReferences(value interface{}, path []string, reference string, fieldPaths *[]string) error {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(typed))
+		for key := range typed {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			if strings.Contains(key, ".") {
+				if containsReference(typed[key], reference) {
+					return fmt.Errorf("field %s cannot be selected by a field path", key)
+				}
+				continue
+			}
+			if err := findReferences(typed[key], append(path, key), reference, fieldPaths); err != nil {
+				return err
+			}
+		}
+
+	case []interface{}:
+		for i, item := range typed {
+			segment := strconv.Itoa(i)
+			if m, ok := item.(map[string]interface{}); ok {
+				if name, ok := m["name"].(string); ok && name != "" {
+					segment = "[name=" + name + "]"
+				}
+			}
+			if err := findReferences(item, append(path, segment), reference, fieldPaths); err != nil {
+				return err
+			}
+		}
+
+	case string:
+		if typed == reference {
+			*fieldPaths = append(*fieldPaths, strings.Join(path, "."))
+		} else if strings.Contains(typed, reference) {
+			return fmt.Errorf("%s is embedded in the value of %s", reference, strings.Join(path, "."))
+		}
+	}
+
+	return nil
+}
+
+func containsReference(value interface{}, reference string) bool {
+	var fieldPaths []string
+	err := findReferences(value, nil, reference, &fieldPaths)
+	return err != nil || len(fieldPaths) > 0
+}
+
+func selectTarget(id resid.ResId) *types.PatchTarget {
+	return &types.PatchTarget{
+		Group:     id.Gvk().Group,
+		Version:   id.Gvk().Version,
+		Kind:      id.Gvk().Kind,
+		Name:      id.Name(),
+		Namespace: id.Namespace(),
+	}
+}
@@ -0,0 +1,152 @@
+package generators
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ContainerSolutions/helm-convert/pkg/types"
+	"github.com/kylelemons/godebug/pretty"
+	"sigs.k8s.io/kustomize/k8sdeps/kunstruct"
+	"sigs.k8s.io/kustomize/pkg/gvk"
+	"sigs.k8s.io/kustomize/pkg/patch"
+	"sigs.k8s.io/kustomize/pkg/resid"
+	"sigs.k8s.io/kustomize/pkg/resmap"
+	"sigs.k8s.io/kustomize/pkg/resource"
+	ktypes "sigs.k8s.io/kustomize/pkg/types"
+)
+
+func TestConvertKustomization(t *testing.T) {
+	var rf = resource.NewFactory(kunstruct.NewKunstructuredFactoryImpl())
+	var deploy = gvk.Gvk{Group: "apps", Version: "v1", Kind: "Deployment"}
+
+	resources := &types.Resources{
+		ResMap: resmap.ResMap{
+			resid.NewResId(deploy, "web"): rf.FromMap(map[string]interface{}{
+				"apiVersion": "apps/v1",
+				"kind":       "Deployment",
+				"metadata":   map[string]interface{}{"name": "web"},
+				"spec": map[string]interface{}{
+					"template": map[string]interface{}{
+						"spec": map[string]interface{}{
+							"containers": []interface{}{
+								map[string]interface{}{
+									"name": "web",
+									"args": []interface{}{"--service", "$(SERVICE)"},
+								},
+							},
+						},
+					},
+				},
+			}),
+		},
+	}
+
+	config := &ktypes.Kustomization{
+		CommonLabels: map[string]string{"app": "web"},
+		Resources:    []string{"web-deploy.yaml"},
+		Bases:        []string{"../base"},
+		ConfigMapGenerator: []ktypes.ConfigMapArgs{
+			{GeneratorArgs: ktypes.GeneratorArgs{Name: "web", DataSources: ktypes.DataSources{
+				EnvSource: "files/web/env",
+			}}},
+		},
+		PatchesStrategicMerge: []patch.StrategicMerge{"patch.yaml"},
+		PatchesJson6902: []patch.Json6902{
+			{Path: "json-patch.yaml", Target: &patch.Target{Gvk: deploy, Name: "web"}},
+		},
+		Vars: []ktypes.Var{
+			{Name: "SERVICE", ObjRef: ktypes.Target{APIVersion: "v1", Gvk: gvk.Gvk{Kind: "Service"}, Name: "web"}},
+		},
+	}
+
+	patches := []types.Patch{
+		{Path: "patch.yaml"},
+		{Path: "json-patch.yaml", Target: &types.PatchTarget{Group: "apps", Version: "v1", Kind: "Deployment", Name: "web"}},
+	}
+	generators := []types.GeneratorArgs{{Name: "web", Envs: []string{"files/web/env"}}}
+
+	for _, test := range []struct {
+		name     string
+		version  KustomizeVersion
+		expected interface{}
+	}{
+		{
+			name:     "it should keep the legacy schema for v2",
+			version:  KustomizeV2,
+			expected: config,
+		},
+		{
+			name:    "it should replace bases, env and legacy patches for v3",
+			version: KustomizeV3,
+			expected: &types.Kustomization{
+				APIVersion:         types.KustomizationAPIVersion,
+				Kind:               types.KustomizationKind,
+				CommonLabels:       map[string]string{"app": "web"},
+				Resources:          []string{"web-deploy.yaml", "../base"},
+				ConfigMapGenerator: generators,
+				Patches:            patches,
+				Vars:               config.Vars,
+			},
+		},
+		{
+			name:    "it should replace commonLabels and vars for v4",
+			version: KustomizeV4,
+			expected: &types.Kustomization{
+				APIVersion:         types.KustomizationAPIVersion,
+				Kind:               types.KustomizationKind,
+				Labels:             []types.Label{{Pairs: map[string]string{"app": "web"}, IncludeSelectors: true}},
+				Resources:          []string{"web-deploy.yaml", "../base"},
+				ConfigMapGenerator: generators,
+				Patches:            patches,
+				Replacements: []types.Replacement{
+					{
+						Source: &types.ReplacementSource{Version: "v1", Kind: "Service", Name: "web", FieldPath: "metadata.name"},
+						Targets: []types.ReplacementTarget{
+							{
+								Select:     &types.PatchTarget{Group: "apps", Version: "v1", Kind: "Deployment", Name: "web"},
+								FieldPaths: []string{"spec.template.spec.containers.[name=web].args.1"},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name:    "it should keep the legacy order with webhooks last for v5",
+			version: KustomizeV5,
+			expected: &types.Kustomization{
+				APIVersion:         types.KustomizationAPIVersion,
+				Kind:               types.KustomizationKind,
+				Labels:             []types.Label{{Pairs: map[string]string{"app": "web"}, IncludeSelectors: true}},
+				Resources:          []string{"web-deploy.yaml", "../base"},
+				ConfigMapGenerator: generators,
+				Patches:            patches,
+				Replacements: []types.Replacement{
+					{
+						Source: &types.ReplacementSource{Version: "v1", Kind: "Service", Name: "web", FieldPath: "metadata.name"},
+						Targets: []types.ReplacementTarget{
+							{
+								Select:     &types.PatchTarget{Group: "apps", Version: "v1", Kind: "Deployment", Name: "web"},
+								FieldPaths: []string{"spec.template.spec.containers.[name=web].args.1"},
+							},
+						},
+					},
+				},
+				SortOptions: &types.SortOptions{
+					Order: "legacy",
+					LegacySortOptions: &types.LegacySortOptions{
+						OrderFirst: installOrder,
+						OrderLast:  []string{"MutatingWebhookConfiguration", "ValidatingWebhookConfiguration"},
+					},
+				},
+			},
+		},
+	} {
+		t.Run(fmt.Sprintf("%s", test.name), func(t *testing.T) {
+			output := convertKustomization(config, resources, test.version)
+			if diff := pretty.Compare(output, test.expected); diff != "" {
+				t.Errorf("%s, diff: (-got +want)\n%s", test.name, diff)
+			}
+		})
+	}
+}
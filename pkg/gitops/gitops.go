@@ -0,0 +1,309 @@
+// Package gitops build the Flux Kustomizations and Argo CD Applications
+// deploying a converted package. CRDs and Helm hooks are split in stages
+// applied before or after the resources of the chart.
+package gitops
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/ContainerSolutions/helm-convert/pkg/types"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"sigs.k8s.io/kustomize/pkg/resid"
+	"sigs.k8s.io/kustomize/pkg/resource"
+)
+
+// Tool is a GitOps tool
+type Tool string
+
+const (
+	// Flux generate Flux Kustomizations
+	Flux Tool = "flux"
+
+	// ArgoCD generate Argo CD Applications
+	ArgoCD Tool = "argocd"
+)
+
+// Tools is the list of supported GitOps tools
+var Tools = []Tool{Flux, ArgoCD}
+
+const (
+	// Directory is the directory of the package containing the GitOps
+	// objects
+	Directory = "gitops"
+
+	// DefaultFluxSource is the name of the GitRepository created by flux
+	// bootstrap
+	DefaultFluxSource = "flux-system"
+
+	fluxNamespace   = "flux-system"
+	argoCDNamespace = "argocd"
+	syncWave        = "argocd.argoproj.io/sync-wave"
+)
+
+// Stage is a part of the package applied separately, in order
+type Stage struct {
+	// Name is appended to the name of the package, empty for the main stage
+	Name string
+
+	// Directory of the stage in the package, empty for the main stage
+	Directory string
+
+	// Wave is the Argo CD sync wave of the stage
+	Wave int
+
+	// Resources of the stage
+	Resources *types.Resources
+
+	// ClusterScoped stages have no target namespace and are not pruned
+	ClusterScoped bool
+}
+
+// stage definitions, in the order they are applied
+var (
+	crdsStage      = Stage{Name: "crds", Directory: "crds", Wave: -2, ClusterScoped: true}
+	preHooksStage  = Stage{Name: "pre-hooks", Directory: "hooks/pre", Wave: -1}
+	mainStage      = Stage{Wave: 0}
+	postHooksStage = Stage{Name: "post-hooks", Directory: "hooks/post", Wave: 1}
+)
+
+// Options of the generated objects
+type Options struct {
+	Tool Tool
+
+	// Name of the package
+	Name string
+
+	// Namespace the resources are deployed to
+	Namespace string
+
+	// Repo is the URL of the git repository for Argo CD, the name of the
+	// GitRepository for Flux
+	Repo string
+
+	// Path of the package in the git repository
+	Path string
+}
+
+// ParseTool return the GitOps tool with the given name
+func ParseTool(s string) (Tool, error) {
+	for _, tool := range Tools {
+		if string(tool) == s {
+			return tool, nil
+		}
+	}
+	return "", fmt.Errorf("unknown gitops tool '%s', expected one of %v", s, Tools)
+}
+
+// Split the resources in stages: the CRDs first, then the pre-install and
+// pre-upgrade hooks, the other resources and finally the post-install and
+// post-upgrade hooks. The main stage is always returned, other stages only if
+// they have resources.
+func Split(resources *types.Resources) []Stage {
+	stages := []Stage{crdsStage, preHooksStage, mainStage, postHooksStage}
+	for i := range stages {
+		stages[i].Resources = types.NewResources()
+	}
+
+	for id, res := range resources.ResMap {
+		i := 2
+		origin := resources.Origins[id]
+		switch {
+		case id.Gvk().Kind == "CustomResourceDefinition":
+			i = 0
+		case origin != nil && hasHook(origin.Hook, "pre-install", "pre-upgrade"):
+			i = 1
+		case origin != nil && hasHook(origin.Hook, "post-install", "post-upgrade"):
+			i = 3
+		}
+		addResource(stages[i].Resources, resources, id, res)
+	}
+	stages[2].Resources.SourceFiles = resources.SourceFiles
+
+	var result []Stage
+	for _, stage := range stages {
+		if stage.Directory == "" || len(stage.Resources.ResMap) > 0 {
+			result = append(result, stage)
+		}
+	}
+	return result
+}
+
+// Objects return the Flux Kustomizations or Argo CD Applications deploying
+// the stages of a package, labelled and annotated with the chart metadata
+func Objects(options *Options, metadata *chart.Metadata, stages []Stage) []interface{} {
+	var objects []interface{}
+	var previous []string
+	for _, stage := range stages {
+		name := options.Name
+		if stage.Name != "" {
+			name += "-" + stage.Name
+		}
+		p := options.Path
+		if stage.Directory != "" {
+			p = path.Join(p, stage.Directory)
+		}
+		if !strings.HasPrefix(p, "./") && !path.IsAbs(p) {
+			p = "./" + p
+		}
+
+		namespace := options.Namespace
+		if stage.ClusterScoped {
+			namespace = ""
+		}
+
+		switch options.Tool {
+		case Flux:
+			objects = append(objects, fluxKustomization(options, metadata, stage, name, p, namespace, previous))
+		case ArgoCD:
+			objects = append(objects, argoCDApplication(options, metadata, stage, name, p, namespace))
+		}
+
+		// each stage depends on the stages applied before
+		previous = append(previous, name)
+	}
+	return objects
+}
+
+func fluxKustomization(options *Options, metadata *chart.Metadata, stage Stage, name, p, namespace string,
+	dependsOn []string) map[string]interface{} {
+	spec := map[string]interface{}{
+		"interval": "10m",
+		"path":     p,
+		"prune":    !stage.ClusterScoped,
+		"sourceRef": map[string]interface{}{
+			"kind": "GitRepository",
+			"name": options.Repo,
+		},
+	}
+	if namespace != "" {
+		spec["targetNamespace"] = namespace
+	}
+	if stage.ClusterScoped {
+		spec["wait"] = true
+	}
+	if len(dependsOn) > 0 {
+		var deps []interface{}
+		for _, dep := range dependsOn {
+			deps = append(deps, map[string]interface{}{"name": dep})
+		}
+		spec["dependsOn"] = deps
+	}
+
+	return map[string]interface{}{
+		"apiVersion": "kustomize.toolkit.fluxcd.io/v1",
+		"kind":       "Kustomization",
+		"metadata":   objectMetadata(name, fluxNamespace, metadata, nil),
+		"spec":       spec,
+	}
+}
+
+func argoCDApplication(options *Options, metadata *chart.Metadata, stage Stage, name, p,
+	namespace string) map[string]interface{} {
+	destination := map[string]interface{}{
+		"server": "https://kubernetes.default.svc",
+	}
+	syncOptions := []interface{}{}
+	if namespace != "" {
+		destination["namespace"] = namespace
+		syncOptions = append(syncOptions, "CreateNamespace=true")
+	}
+	if stage.ClusterScoped {
+		syncOptions = append(syncOptions, "ServerSideApply=true")
+	}
+
+	automated := map[string]interface{}{"selfHeal": true, "prune": !stage.ClusterScoped}
+	syncPolicy := map[string]interface{}{"automated": automated}
+	if len(syncOptions) > 0 {
+		syncPolicy["syncOptions"] = syncOptions
+	}
+
+	return map[string]interface{}{
+		"apiVersion": "argoproj.io/v1alpha1",
+		"kind":       "Application",
+		"metadata": objectMetadata(name, argoCDNamespace, metadata, map[string]string{
+			syncWave: fmt.Sprint(stage.Wave),
+		}),
+		"spec": map[string]interface{}{
+			"project": "default",
+			"source": map[string]interface{}{
+				"repoURL":        options.Repo,
+				"targetRevision": "HEAD",
+				"path":           strings.TrimPrefix(p, "./"),
+			},
+			"destination": destination,
+			"syncPolicy":  syncPolicy,
+		},
+	}
+}
+
+// objectMetadata return the metadata of a GitOps object: the chart name and
+// versions as labels, the chart description and home as annotations
+func objectMetadata(name, namespace string, metadata *chart.Metadata, annotations map[string]string) map[string]interface{} {
+	labels := map[string]interface{}{
+		"app.kubernetes.io/name":       metadata.Name,
+		"app.kubernetes.io/managed-by": "helm-convert",
+	}
+	if metadata.Version != "" {
+		labels["helm.sh/chart"] = strings.Replace(metadata.Name+"-"+metadata.Version, "+", "_", -1)
+	}
+	if metadata.AppVersion != "" {
+		labels["app.kubernetes.io/version"] = labelValue(metadata.AppVersion)
+	}
+
+	objAnnotations := map[string]interface{}{}
+	for key, value := range annotations {
+		objAnnotations[key] = value
+	}
+	if metadata.Description != "" {
+		objAnnotations["helm-convert/description"] = metadata.Description
+	}
+	if metadata.Home != "" {
+		objAnnotations["helm-convert/home"] = metadata.Home
+	}
+	if len(metadata.Sources) > 0 {
+		objAnnotations["helm-convert/sources"] = strings.Join(metadata.Sources, ",")
+	}
+
+	m := map[string]interface{}{
+		"name":      name,
+		"namespace": namespace,
+		"labels":    labels,
+	}
+	if len(objAnnotations) > 0 {
+		m["annotations"] = objAnnotations
+	}
+	return m
+}
+
+// labelValue replace the characters which are not allowed in label values
+func labelValue(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if r == '-' || r == '_' || r == '.' || (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') {
+			return r
+		}
+		return '_'
+	}, s)
+	if len(s) > 63 {
+		s = s[:63]
+	}
+	return strings.Trim(s, "-_.")
+}
+
+func hasHook(hook string, names ...string) bool {
+	for _, h := range strings.Split(hook, ",") {
+		for _, name := range names {
+			if strings.TrimSpace(h) == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func addResource(dst, src *types.Resources, id resid.ResId, res *resource.Resource) {
+	dst.ResMap[id] = res
+	dst.Origins[id] = src.Origins[id]
+	dst.FieldOrders[id] = src.FieldOrders[id]
+}
@@ -0,0 +1,132 @@
+package gitops
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ContainerSolutions/helm-convert/pkg/types"
+	"github.com/kylelemons/godebug/pretty"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"sigs.k8s.io/kustomize/k8sdeps/kunstruct"
+	"sigs.k8s.io/kustomize/pkg/gvk"
+	"sigs.k8s.io/kustomize/pkg/resid"
+	"sigs.k8s.io/kustomize/pkg/resource"
+)
+
+func TestObjects(t *testing.T) {
+	var rf = resource.NewFactory(kunstruct.NewKunstructuredFactoryImpl())
+	var crd = gvk.Gvk{Group: "apiextensions.k8s.io", Version: "v1", Kind: "CustomResourceDefinition"}
+	var job = gvk.Gvk{Group: "batch", Version: "v1", Kind: "Job"}
+	var service = gvk.Gvk{Version: "v1", Kind: "Service"}
+
+	resources := types.NewResources()
+	for _, r := range []struct {
+		gvk  gvk.Gvk
+		name string
+		hook string
+	}{
+		{crd, "widgets.example.com", ""},
+		{job, "migrate", "pre-install,pre-upgrade"},
+		{service, "web", ""},
+	} {
+		id := resid.NewResId(r.gvk, r.name)
+		resources.ResMap[id] = rf.FromMap(map[string]interface{}{
+			"kind":     r.gvk.Kind,
+			"metadata": map[string]interface{}{"name": r.name},
+		})
+		resources.Origins[id] = &types.Origin{Hook: r.hook}
+	}
+
+	metadata := &chart.Metadata{Name: "web", Version: "1.0.0+1", AppVersion: "2.1"}
+	labels := map[string]interface{}{
+		"app.kubernetes.io/name":       "web",
+		"app.kubernetes.io/managed-by": "helm-convert",
+		"app.kubernetes.io/version":    "2.1",
+		"helm.sh/chart":                "web-1.0.0_1",
+	}
+
+	fluxKustomization := func(name, path string, prune bool, extra map[string]interface{}) map[string]interface{} {
+		spec := map[string]interface{}{
+			"interval":  "10m",
+			"path":      path,
+			"prune":     prune,
+			"sourceRef": map[string]interface{}{"kind": "GitRepository", "name": "flux-system"},
+		}
+		for key, value := range extra {
+			spec[key] = value
+		}
+		return map[string]interface{}{
+			"apiVersion": "kustomize.toolkit.fluxcd.io/v1",
+			"kind":       "Kustomization",
+			"metadata":   map[string]interface{}{"name": name, "namespace": "flux-system", "labels": labels},
+			"spec":       spec,
+		}
+	}
+
+	for _, test := range []struct {
+		name      string
+		options   *Options
+		resources *types.Resources
+		expected  []interface{}
+	}{
+		{
+			name:      "it should deploy the CRDs and hooks before the chart with Flux",
+			options:   &Options{Tool: Flux, Name: "web", Namespace: "web", Repo: "flux-system", Path: "apps/web"},
+			resources: resources,
+			expected: []interface{}{
+				fluxKustomization("web-crds", "./apps/web/crds", false, map[string]interface{}{"wait": true}),
+				fluxKustomization("web-pre-hooks", "./apps/web/hooks/pre", true, map[string]interface{}{
+					"targetNamespace": "web",
+					"dependsOn":       []interface{}{map[string]interface{}{"name": "web-crds"}},
+				}),
+				fluxKustomization("web", "./apps/web", true, map[string]interface{}{
+					"targetNamespace": "web",
+					"dependsOn": []interface{}{
+						map[string]interface{}{"name": "web-crds"},
+						map[string]interface{}{"name": "web-pre-hooks"},
+					},
+				}),
+			},
+		},
+		{
+			name:      "it should generate a single Argo CD application without CRDs and hooks",
+			options:   &Options{Tool: ArgoCD, Name: "web", Namespace: "web", Repo: "https://git.example.com/apps", Path: "apps/web"},
+			resources: types.NewResources(),
+			expected: []interface{}{
+				map[string]interface{}{
+					"apiVersion": "argoproj.io/v1alpha1",
+					"kind":       "Application",
+					"metadata": map[string]interface{}{
+						"name":        "web",
+						"namespace":   "argocd",
+						"labels":      labels,
+						"annotations": map[string]interface{}{syncWave: "0"},
+					},
+					"spec": map[string]interface{}{
+						"project": "default",
+						"source": map[string]interface{}{
+							"repoURL":        "https://git.example.com/apps",
+							"targetRevision": "HEAD",
+							"path":           "apps/web",
+						},
+						"destination": map[string]interface{}{
+							"server":    "https://kubernetes.default.svc",
+							"namespace": "web",
+						},
+						"syncPolicy": map[string]interface{}{
+							"automated":   map[string]interface{}{"selfHeal": true, "prune": true},
+							"syncOptions": []interface{}{"CreateNamespace=true"},
+						},
+					},
+				},
+			},
+		},
+	} {
+		t.Run(fmt.Sprintf("%s", test.name), func(t *testing.T) {
+			output := Objects(test.options, metadata, Split(test.resources))
+			if diff := pretty.Compare(output, test.expected); diff != "" {
+				t.Errorf("%s, diff: (-got +want)\n%s", test.name, diff)
+			}
+		})
+	}
+}
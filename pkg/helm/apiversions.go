@@ -0,0 +1,82 @@
+package helm
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/ghodss/yaml"
+)
+
+// discovery is a document of the Kubernetes discovery API, either an
+// APIGroupList, APIVersions or APIResourceList
+type discovery struct {
+	Kind         string   `json:"kind"`
+	Versions     []string `json:"versions"`
+	GroupVersion string   `json:"groupVersion"`
+	Groups       []struct {
+		Versions []struct {
+			GroupVersion string `json:"groupVersion"`
+		} `json:"versions"`
+	} `json:"groups"`
+	Resources []struct {
+		Kind string `json:"kind"`
+	} `json:"resources"`
+}
+
+// LoadAPIVersions load the API versions of a cluster from a file, either the
+// output of kubectl api-versions or a discovery document such as the output
+// of kubectl get --raw /apis
+func LoadAPIVersions(filename string) ([]string, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	trimmed := bytes.TrimSpace(data)
+	if bytes.HasPrefix(trimmed, []byte("{")) || bytes.Contains(trimmed, []byte("kind:")) {
+		return parseDiscovery(trimmed, filename)
+	}
+
+	var versions []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		versions = append(versions, line)
+	}
+	return versions, scanner.Err()
+}
+
+// parseDiscovery return the API versions of a discovery document, the kinds
+// of an APIResourceList are returned as group/version/kind
+func parseDiscovery(data []byte, filename string) ([]string, error) {
+	var d discovery
+	if err := yaml.Unmarshal(data, &d); err != nil {
+		return nil, fmt.Errorf("could not parse the API versions of %s: %v", filename, err)
+	}
+
+	var versions []string
+	switch d.Kind {
+	case "APIGroupList":
+		for _, group := range d.Groups {
+			for _, version := range group.Versions {
+				versions = append(versions, version.GroupVersion)
+			}
+		}
+	case "APIVersions":
+		versions = append(versions, d.Versions...)
+	case "APIResourceList":
+		versions = append(versions, d.GroupVersion)
+		for _, resource := range d.Resources {
+			versions = append(versions, d.GroupVersion+"/"+resource.Kind)
+		}
+	default:
+		return nil, fmt.Errorf("could not parse the API versions of %s: unsupported kind %q", filename, d.Kind)
+	}
+	return versions, nil
+}
@@ -0,0 +1,108 @@
+package helm
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kylelemons/godebug/pretty"
+)
+
+func TestLoadAPIVersions(t *testing.T) {
+	dir, err := ioutil.TempDir("", "helm-convert-api-versions")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, test := range []struct {
+		name             string
+		content          string
+		expectedVersions []string
+		expectedError    string
+	}{
+		{
+			name:    "it should load the output of kubectl api-versions",
+			content: "apps/v1\n\n# comment\nnetworking.k8s.io/v1\nv1\n",
+			expectedVersions: []string{
+				"apps/v1",
+				"networking.k8s.io/v1",
+				"v1",
+			},
+		},
+		{
+			name: "it should load an APIGroupList",
+			content: `{
+  "kind": "APIGroupList",
+  "apiVersion": "v1",
+  "groups": [
+    {
+      "name": "apps",
+      "versions": [{"groupVersion": "apps/v1", "version": "v1"}],
+      "preferredVersion": {"groupVersion": "apps/v1", "version": "v1"}
+    },
+    {
+      "name": "autoscaling",
+      "versions": [
+        {"groupVersion": "autoscaling/v2", "version": "v2"},
+        {"groupVersion": "autoscaling/v1", "version": "v1"}
+      ]
+    }
+  ]
+}`,
+			expectedVersions: []string{
+				"apps/v1",
+				"autoscaling/v2",
+				"autoscaling/v1",
+			},
+		},
+		{
+			name:             "it should load APIVersions",
+			content:          "kind: APIVersions\nversions:\n- v1\n",
+			expectedVersions: []string{"v1"},
+		},
+		{
+			name: "it should load the kinds of an APIResourceList",
+			content: `kind: APIResourceList
+groupVersion: monitoring.coreos.com/v1
+resources:
+- name: servicemonitors
+  kind: ServiceMonitor
+`,
+			expectedVersions: []string{
+				"monitoring.coreos.com/v1",
+				"monitoring.coreos.com/v1/ServiceMonitor",
+			},
+		},
+		{
+			name:          "it should fail with an unsupported kind",
+			content:       `{"kind": "Pod"}`,
+			expectedError: `unsupported kind "Pod"`,
+		},
+	} {
+		t.Run(fmt.Sprintf("%s", test.name), func(t *testing.T) {
+			filename := filepath.Join(dir, "api-versions")
+			if err := ioutil.WriteFile(filename, []byte(test.content), 0644); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			versions, err := LoadAPIVersions(filename)
+			if test.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedError) {
+					t.Fatalf("%s: expected error %q, got %v", test.name, test.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if diff := pretty.Compare(versions, test.expectedVersions); diff != "" {
+				t.Errorf("%s, diff: (-got +want)\n%s", test.name, diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,228 @@
+package helm
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/ignore"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/sympath"
+)
+
+const (
+	// APIVersionV1 is the API version of the Helm 2 charts
+	APIVersionV1 = "v1"
+
+	// APIVersionV2 is the API version of the Helm 3 charts
+	APIVersionV2 = "v2"
+)
+
+// ChartTypeLibrary is the type of the Helm 3 charts only providing template
+// helpers to other charts
+const ChartTypeLibrary = "library"
+
+// ChartLoader loads the files of a chart into the chart rendered by the
+// template engine
+type ChartLoader interface {
+	LoadFiles(files []*chartutil.BufferedFile) (*chart.Chart, error)
+}
+
+// ChartLoaderFunc is a function implementing ChartLoader
+type ChartLoaderFunc func(files []*chartutil.BufferedFile) (*chart.Chart, error)
+
+// LoadFiles call the function
+func (f ChartLoaderFunc) LoadFiles(files []*chartutil.BufferedFile) (*chart.Chart, error) {
+	return f(files)
+}
+
+// ChartLoaders are the loaders of each chart API version
+var ChartLoaders = map[string]ChartLoader{
+	APIVersionV1: ChartLoaderFunc(chartutil.LoadFiles),
+	APIVersionV2: ChartLoaderFunc(loadHelm3Files),
+}
+
+// chartfile is the part of Chart.yaml unknown to Helm 2
+type chartfile struct {
+	APIVersion   string                  `json:"apiVersion"`
+	Type         string                  `json:"type"`
+	Dependencies []*chartutil.Dependency `json:"dependencies"`
+}
+
+// version return the API version of the chart, charts without API version
+// are Helm 2 charts
+func (c *chartfile) version() string {
+	if c.APIVersion == "" {
+		return APIVersionV1
+	}
+	return c.APIVersion
+}
+
+// parseChartfile parse the Chart.yaml file at the root of the files, an
+// empty chartfile is returned if there is none
+func parseChartfile(files []*chartutil.BufferedFile) (*chartfile, error) {
+	cf := &chartfile{}
+	for _, f := range files {
+		if f.Name == "Chart.yaml" {
+			if err := yaml.Unmarshal(f.Data, cf); err != nil {
+				return nil, fmt.Errorf("invalid chart (Chart.yaml): %v", err)
+			}
+		}
+	}
+	return cf, nil
+}
+
+// LoadChartPath loads a chart directory or archive with the loader of its API
+// version
+func LoadChartPath(name string) (*chart.Chart, error) {
+	files, err := readChart(name)
+	if err != nil {
+		return nil, err
+	}
+
+	cf, err := parseChartfile(files)
+	if err != nil {
+		return nil, err
+	}
+
+	loader, ok := ChartLoaders[cf.version()]
+	if !ok {
+		return nil, fmt.Errorf("chart %s has an unsupported apiVersion '%s'", name, cf.APIVersion)
+	}
+	return loader.LoadFiles(files)
+}
+
+// readChart read the files of a chart directory or archive, the files of a
+// directory matching its .helmignore are skipped
+func readChart(name string) ([]*chartutil.BufferedFile, error) {
+	name = filepath.FromSlash(name)
+	fi, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !fi.IsDir() {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return readArchive(f)
+	}
+
+	if validChart, err := chartutil.IsChartDir(name); !validChart {
+		return nil, err
+	}
+	return readDir(name)
+}
+
+// readDir read the files of a chart directory
+func readDir(dir string) ([]*chartutil.BufferedFile, error) {
+	topdir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := ignore.Empty()
+	ifile := filepath.Join(topdir, ignore.HelmIgnore)
+	if _, err := os.Stat(ifile); err == nil {
+		rules, err = ignore.ParseFile(ifile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	rules.AddDefaults()
+
+	files := []*chartutil.BufferedFile{}
+	topdir += string(filepath.Separator)
+
+	walk := func(name string, fi os.FileInfo, err error) error {
+		n := strings.TrimPrefix(name, topdir)
+		if n == "" {
+			return nil
+		}
+		n = filepath.ToSlash(n)
+
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			if rules.Ignore(n, fi) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if rules.Ignore(n, fi) {
+			return nil
+		}
+
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			return fmt.Errorf("error reading %s: %s", n, err)
+		}
+
+		files = append(files, &chartutil.BufferedFile{Name: n, Data: data})
+		return nil
+	}
+	if err := sympath.Walk(topdir, walk); err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// readArchive read the files of a gzipped tar archive, the top directory is
+// removed from the file names
+func readArchive(in io.Reader) ([]*chartutil.BufferedFile, error) {
+	unzipped, err := gzip.NewReader(in)
+	if err != nil {
+		return nil, err
+	}
+	defer unzipped.Close()
+
+	files := []*chartutil.BufferedFile{}
+	tr := tar.NewReader(unzipped)
+	for {
+		hd, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hd.FileInfo().IsDir() || hd.Typeflag == tar.TypeXGlobalHeader || hd.Typeflag == tar.TypeXHeader {
+			continue
+		}
+
+		parts := strings.Split(strings.Replace(hd.Name, "\\", "/", -1), "/")
+		if parts[0] == "Chart.yaml" {
+			return nil, errors.New("chart yaml not in base directory")
+		}
+
+		n := path.Clean(strings.Join(parts[1:], "/"))
+		if path.IsAbs(n) || n == "." || strings.HasPrefix(n, "..") || strings.Contains(n, ":") {
+			return nil, fmt.Errorf("chart contains an illegal path: %s", hd.Name)
+		}
+
+		var b bytes.Buffer
+		if _, err := io.Copy(&b, tr); err != nil {
+			return nil, err
+		}
+		files = append(files, &chartutil.BufferedFile{Name: n, Data: b.Bytes()})
+	}
+
+	if len(files) == 0 {
+		return nil, errors.New("no files in chart archive")
+	}
+	return files, nil
+}
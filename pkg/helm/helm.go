@@ -8,6 +8,7 @@ import (
 	"io/ioutil"
 	"net/url"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 
@@ -28,8 +29,9 @@ var defaultKubeVersion = fmt.Sprintf("%s.%s", chartutil.DefaultKubeVersion.Major
 
 // Helm type
 type Helm struct {
-	settings helm_env.EnvSettings
-	out      io.Writer
+	settings  helm_env.EnvSettings
+	out       io.Writer
+	plainHTTP bool
 }
 
 // LoadChartConfig define the configuration to load a chart
@@ -56,20 +58,49 @@ type RenderChartConfig struct {
 	Values         []string
 	StringValues   []string
 	FileValues     []string
+
+	// KubeVersion is the Kubernetes version of .Capabilities.KubeVersion, ie:
+	// 1.25 or v1.25.3, empty for the default of the chart API version
+	KubeVersion string
+
+	// ClusterAPIVersions are the API versions served by the cluster, they
+	// replace the default .Capabilities.APIVersions if given
+	ClusterAPIVersions []string
+
+	// APIVersions are added to the default .Capabilities.APIVersions, ie:
+	// networking.k8s.io/v1 or networking.k8s.io/v1/Ingress
+	APIVersions []string
+
+	// SkipSchemaValidation disable the validation of the values against the
+	// values.schema.json of the chart and its subcharts
+	SkipSchemaValidation bool
+
+	// LookupFixtures are the objects the lookup template function resolves
+	// against, nil to return empty objects like helm template
+	LookupFixtures *LookupFixtures
 }
 
 // NewHelm constructs helm
 func NewHelm(settings helm_env.EnvSettings, out io.Writer) *Helm {
 	return &Helm{
-		settings,
-		out,
+		settings: settings,
+		out:      out,
 	}
 }
 
+// SetPlainHTTP use HTTP instead of HTTPS to pull charts from OCI registries
+func (h *Helm) SetPlainHTTP(plainHTTP bool) {
+	h.plainHTTP = plainHTTP
+}
+
 // LoadChart download a chart or load it from cache
 func (h *Helm) LoadChart(c *LoadChartConfig) (*chart.Chart, error) {
 	glog.V(8).Infof("Loading chart with settings %#v", c)
 
+	if err := h.useHelm3Repositories(); err != nil {
+		return nil, err
+	}
+
 	chartPath, err := h.LocateChartPath(
 		c.RepoURL,
 		c.Username,
@@ -88,7 +119,7 @@ func (h *Helm) LoadChart(c *LoadChartConfig) (*chart.Chart, error) {
 	glog.V(8).Infof("Using chart path %v", chartPath)
 
 	// Check chart requirements to make sure all dependencies are present in /charts
-	chartRequested, err := chartutil.Load(chartPath)
+	chartRequested, err := LoadChartPath(chartPath)
 	if err != nil {
 		return nil, err
 	}
@@ -104,12 +135,16 @@ func (h *Helm) LoadChart(c *LoadChartConfig) (*chart.Chart, error) {
 					SkipUpdate: false,
 					Getters:    getter.All(h.settings),
 				}
-				if err := man.Update(); err != nil {
+				update := func(p string) error {
+					man.ChartPath = p
+					return man.Update()
+				}
+				if err := updateHelm3Dependencies(chartPath, update); err != nil {
 					return nil, err
 				}
 
 				// Update all dependencies which are present in /charts.
-				chartRequested, err = chartutil.Load(chartPath)
+				chartRequested, err = LoadChartPath(chartPath)
 				if err != nil {
 					return nil, err
 				}
@@ -126,17 +161,28 @@ func (h *Helm) LoadChart(c *LoadChartConfig) (*chart.Chart, error) {
 
 // RenderChart manifest
 func (h *Helm) RenderChart(c *RenderChartConfig) ([]manifest.Manifest, error) {
-	renderOpts := renderutil.Options{
+	renderOpts := renderOptions{
 		ReleaseOptions: chartutil.ReleaseOptions{
 			Name:      c.Name,
 			Namespace: c.Namespace,
 		},
-		KubeVersion: defaultKubeVersion,
+		KubeVersion:          c.KubeVersion,
+		ClusterAPIVersions:   c.ClusterAPIVersions,
+		APIVersions:          c.APIVersions,
+		SkipSchemaValidation: c.SkipSchemaValidation,
+		LookupFixtures:       c.LookupFixtures,
 	}
 	glog.V(8).Infof("Rendering chart with options: %#v\n", renderOpts)
 
-	// get combined values and create config
-	rawVals, err := h.Vals(c.ValueFiles, c.Values, c.StringValues, c.FileValues, "", "", "")
+	// get combined values and create config, the source of each value is
+	// kept to report invalid values
+	vals, sources, err := h.vals(c.ValueFiles, c.Values, c.StringValues, c.FileValues, "", "", "")
+	if err != nil {
+		return nil, err
+	}
+	renderOpts.ValuesSources = sources
+
+	rawVals, err := yaml.Marshal(vals)
 	if err != nil {
 		return nil, err
 	}
@@ -145,7 +191,7 @@ func (h *Helm) RenderChart(c *RenderChartConfig) ([]manifest.Manifest, error) {
 	glog.V(10).Infof("Chart config: %#v", config)
 	glog.V(10).Info("Chart requested", c.ChartRequested)
 
-	renderedTemplates, err := renderutil.Render(c.ChartRequested, config, renderOpts)
+	renderedTemplates, err := render(c.ChartRequested, config, renderOpts)
 	if err != nil {
 		return nil, err
 	}
@@ -153,6 +199,26 @@ func (h *Helm) RenderChart(c *RenderChartConfig) ([]manifest.Manifest, error) {
 	return manifest.SplitManifests(renderedTemplates), nil
 }
 
+// useHelm3Repositories use the Helm 3 repositories when the Helm home has no
+// repositories file
+func (h *Helm) useHelm3Repositories() error {
+	if _, err := os.Stat(h.settings.Home.RepositoryFile()); err == nil {
+		return nil
+	}
+	config, cache := helm3RepositoryPaths()
+	if _, err := os.Stat(config); err != nil {
+		return nil
+	}
+
+	home, err := helm3Home(config, cache)
+	if err != nil {
+		return err
+	}
+	glog.V(4).Infof("Using the Helm 3 repositories of %s", config)
+	h.settings.Home = home
+	return nil
+}
+
 // LocateChartPath looks for a chart directory in known places, and returns either the full path or an error.
 //
 // This does not ensure that the chart is well-formed; only that the requested filename exists.
@@ -161,6 +227,7 @@ func (h *Helm) RenderChart(c *RenderChartConfig) ([]manifest.Manifest, error) {
 // - current working directory
 // - if path is absolute or begins with '.', error out here
 // - chart repos in $HELM_HOME
+// - OCI registry, oci://registry/repository
 // - URL
 //
 // If 'verify' is true, this will attempt to also verify the chart.
@@ -168,6 +235,22 @@ func (h *Helm) LocateChartPath(repoURL, username, password, name, version string
 	certFile, keyFile, caFile string) (string, error) {
 	name = strings.TrimSpace(name)
 	version = strings.TrimSpace(version)
+
+	if IsOCIReference(name) {
+		if verify {
+			return "", errors.New("charts stored in an OCI registry cannot be verified")
+		}
+		client, err := NewRegistryClient(username, password, certFile, keyFile, caFile)
+		if err != nil {
+			return "", err
+		}
+		client.PlainHTTP = h.plainHTTP
+		filename, err := client.Pull(name, version, h.settings.Home.Archive())
+		if err != nil {
+			return "", err
+		}
+		return filepath.Abs(filename)
+	}
 	if fi, err := os.Stat(name); err == nil {
 		abs, err := filepath.Abs(name)
 		if err != nil {
@@ -236,10 +319,71 @@ func (h *Helm) LocateChartPath(repoURL, username, password, name, version string
 	return lname, nil
 }
 
+// ChartRepository return the URL of the repository a chart is fetched from:
+// the given repository URL, the URL of a repository alias (repo/chart), the
+// base URL of a chart archive or the OCI repository containing the chart. An
+// empty string is returned for local charts.
+func (h *Helm) ChartRepository(repoURL, name string) (string, error) {
+	if repoURL != "" {
+		return repoURL, nil
+	}
+
+	if _, err := os.Stat(name); err == nil {
+		return "", nil
+	}
+
+	// the chart name is the last element of the repository
+	if IsOCIReference(name) {
+		ref, err := parseOCIReference(name)
+		if err != nil {
+			return "", err
+		}
+		return OCIScheme + path.Join(ref.Registry, path.Dir(ref.Repository)), nil
+	}
+
+	if strings.Contains(name, "://") {
+		u, err := url.Parse(name)
+		if err != nil {
+			return "", err
+		}
+		u.Path = path.Dir(u.Path)
+		return u.String(), nil
+	}
+
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) != 2 {
+		return "", fmt.Errorf("cannot find the repository of chart %q, use --repo", name)
+	}
+
+	f, err := repo.LoadRepositoriesFile(h.settings.Home.RepositoryFile())
+	if err != nil {
+		return "", err
+	}
+	for _, entry := range f.Repositories {
+		if entry.Name == parts[0] {
+			return entry.URL, nil
+		}
+	}
+
+	return "", fmt.Errorf("repository %q not found in %s", parts[0], h.settings.Home.RepositoryFile())
+}
+
 // Vals merges values from files specified via -f/--values and
 // directly via --set or --set-string or --set-file, marshaling them to YAML
 func (h *Helm) Vals(valueFiles ValueFiles, values []string, stringValues []string, fileValues []string, CertFile, KeyFile, CAFile string) ([]byte, error) {
+	base, _, err := h.vals(valueFiles, values, stringValues, fileValues, CertFile, KeyFile, CAFile)
+	if err != nil {
+		return []byte{}, err
+	}
+
+	return yaml.Marshal(base)
+}
+
+// vals merges values like Vals, the values set by each file and flag are
+// returned as well in the order they are merged
+func (h *Helm) vals(valueFiles ValueFiles, values []string, stringValues []string, fileValues []string, CertFile, KeyFile, CAFile string) (map[string]interface{}, []valuesSource, error) {
 	base := map[string]interface{}{}
+	var sources []valuesSource
 
 	// User specified a values files via -f/--values
 	for _, filePath := range valueFiles {
@@ -254,12 +398,13 @@ func (h *Helm) Vals(valueFiles ValueFiles, values []string, stringValues []strin
 		}
 
 		if err != nil {
-			return []byte{}, err
+			return nil, nil, err
 		}
 
 		if err := yaml.Unmarshal(bytes, &currentMap); err != nil {
-			return []byte{}, fmt.Errorf("failed to parse %s: %s", filePath, err)
+			return nil, nil, fmt.Errorf("failed to parse %s: %s", filePath, err)
 		}
+		sources = append(sources, valuesSource{Name: filePath, Values: currentMap})
 		// Merge with the previous map
 		base = mergeValues(base, currentMap)
 	}
@@ -267,15 +412,17 @@ func (h *Helm) Vals(valueFiles ValueFiles, values []string, stringValues []strin
 	// User specified a value via --set
 	for _, value := range values {
 		if err := strvals.ParseInto(value, base); err != nil {
-			return []byte{}, fmt.Errorf("failed parsing --set data: %s", err)
+			return nil, nil, fmt.Errorf("failed parsing --set data: %s", err)
 		}
+		sources = append(sources, flagSource("--set", value, strvals.ParseInto))
 	}
 
 	// User specified a value via --set-string
 	for _, value := range stringValues {
 		if err := strvals.ParseIntoString(value, base); err != nil {
-			return []byte{}, fmt.Errorf("failed parsing --set-string data: %s", err)
+			return nil, nil, fmt.Errorf("failed parsing --set-string data: %s", err)
 		}
+		sources = append(sources, flagSource("--set-string", value, strvals.ParseIntoString))
 	}
 
 	// User specified a value via --set-file
@@ -285,11 +432,23 @@ func (h *Helm) Vals(valueFiles ValueFiles, values []string, stringValues []strin
 			return string(bytes), err
 		}
 		if err := strvals.ParseIntoFile(value, base, reader); err != nil {
-			return []byte{}, fmt.Errorf("failed parsing --set-file data: %s", err)
+			return nil, nil, fmt.Errorf("failed parsing --set-file data: %s", err)
 		}
+		sources = append(sources, flagSource("--set-file", value, func(s string, dest map[string]interface{}) error {
+			return strvals.ParseIntoFile(s, dest, func(rs []rune) (interface{}, error) { return string(rs), nil })
+		}))
 	}
 
-	return yaml.Marshal(base)
+	return base, sources, nil
+}
+
+// flagSource return the values set by a --set flag alone
+func flagSource(flag, value string, parse func(string, map[string]interface{}) error) valuesSource {
+	values := map[string]interface{}{}
+	if err := parse(value, values); err != nil {
+		glog.V(4).Infof("Couldn't parse %s %s: %v", flag, value, err)
+	}
+	return valuesSource{Name: flag + " " + value, Values: values}
 }
 
 //readFile load a file from the local directory or a remote file with a url.
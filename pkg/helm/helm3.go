@@ -0,0 +1,461 @@
+package helm
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/ghodss/yaml"
+	"github.com/golang/glog"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/helm/helmpath"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/repo"
+)
+
+// defaultKubeVersionV3 is the Kubernetes version Helm 3 charts are rendered
+// for, the default of Helm 3.14
+const defaultKubeVersionV3 = "1.29"
+
+// defaultAPIVersionsV3 are the API versions served by Kubernetes 1.29, with
+// an entry per group version and per kind like the default ones of Helm 3
+var defaultAPIVersionsV3 = kubeAPIVersions(29)
+
+// capabilitiesV3 are the capabilities given to the templates of Helm 3
+// charts
+type capabilitiesV3 struct {
+	KubeVersion kubeVersionV3
+	APIVersions chartutil.VersionSet
+}
+
+// kubeVersionV3 is the Kubernetes version given to the templates of Helm 3
+// charts
+type kubeVersionV3 struct {
+	Version string
+	Major   string
+	Minor   string
+}
+
+// String return the version, ie: v1.29.0
+func (kv kubeVersionV3) String() string {
+	return kv.Version
+}
+
+// GitVersion return the version, deprecated by Helm 3 but still used by
+// charts supporting both Helm 2 and 3
+func (kv kubeVersionV3) GitVersion() string {
+	return kv.Version
+}
+
+// newCapabilitiesV3 constructs the Helm 3 capabilities from the Helm 2 ones
+func newCapabilitiesV3(caps *chartutil.Capabilities) *capabilitiesV3 {
+	return &capabilitiesV3{
+		KubeVersion: kubeVersionV3{
+			Version: caps.KubeVersion.GitVersion,
+			Major:   caps.KubeVersion.Major,
+			Minor:   caps.KubeVersion.Minor,
+		},
+		APIVersions: caps.APIVersions,
+	}
+}
+
+// funcMapV3 are the template functions added by Helm 3 for Helm 3 charts,
+// lookup is added to all the charts by render
+func funcMapV3() template.FuncMap {
+	return template.FuncMap{
+		"mustToYaml": func(v interface{}) (string, error) {
+			data, err := yaml.Marshal(v)
+			return strings.TrimSuffix(string(data), "\n"), err
+		},
+		"mustToJson": func(v interface{}) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+		"fromYamlArray": func(str string) []interface{} {
+			a := []interface{}{}
+			if err := yaml.Unmarshal([]byte(str), &a); err != nil {
+				a = []interface{}{err.Error()}
+			}
+			return a
+		},
+		"fromJsonArray": func(str string) []interface{} {
+			a := []interface{}{}
+			if err := json.Unmarshal([]byte(str), &a); err != nil {
+				a = []interface{}{err.Error()}
+			}
+			return a
+		},
+	}
+}
+
+// loadHelm3Files loads a Helm 3 chart, library charts cannot be rendered
+func loadHelm3Files(files []*chartutil.BufferedFile) (*chart.Chart, error) {
+	cf, err := parseChartfile(files)
+	if err != nil {
+		return nil, err
+	}
+	if cf.Type == ChartTypeLibrary {
+		return nil, fmt.Errorf("library charts are not installable")
+	}
+
+	files, err = helm3Files(files)
+	if err != nil {
+		return nil, err
+	}
+	return chartutil.LoadFiles(files)
+}
+
+// helm3Files translate the files of a chart and its subcharts for the Helm 2
+// loader: the dependencies declared in Chart.yaml are written in
+// requirements.yaml, the templates of library charts are reduced to their
+// helpers and the archived subcharts are extracted
+func helm3Files(files []*chartutil.BufferedFile) ([]*chartutil.BufferedFile, error) {
+	cf, err := parseChartfile(files)
+	if err != nil {
+		return nil, err
+	}
+	v2 := cf.version() == APIVersionV2
+	library := v2 && cf.Type == ChartTypeLibrary
+
+	var result []*chartutil.BufferedFile
+	subcharts := map[string][]*chartutil.BufferedFile{}
+	hasRequirements := false
+	for _, f := range files {
+		if f.Name == "requirements.yaml" {
+			hasRequirements = true
+		}
+
+		if library && strings.HasPrefix(f.Name, "templates/") && !strings.HasPrefix(path.Base(f.Name), "_") {
+			continue
+		}
+
+		// subcharts starting with . or _ are ignored by the loader
+		name := strings.TrimPrefix(f.Name, "charts/")
+		if name == f.Name || strings.IndexAny(name, "._") == 0 || path.Ext(name) == ".prov" {
+			result = append(result, f)
+			continue
+		}
+
+		parts := strings.SplitN(name, "/", 2)
+		switch {
+		case len(parts) == 2:
+			subcharts[parts[0]] = append(subcharts[parts[0]], &chartutil.BufferedFile{Name: parts[1], Data: f.Data})
+		case path.Ext(name) == ".tgz":
+			archived, err := readArchive(bytes.NewReader(f.Data))
+			if err != nil {
+				return nil, fmt.Errorf("error unpacking %s: %v", name, err)
+			}
+			dir := strings.TrimSuffix(name, ".tgz")
+			subcharts[dir] = append(subcharts[dir], archived...)
+		default:
+			result = append(result, f)
+		}
+	}
+
+	if v2 && !hasRequirements && len(cf.Dependencies) > 0 {
+		data, err := yaml.Marshal(&chartutil.Requirements{Dependencies: cf.Dependencies})
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, &chartutil.BufferedFile{Name: "requirements.yaml", Data: data})
+	}
+
+	names := make([]string, 0, len(subcharts))
+	for name := range subcharts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		subfiles, err := helm3Files(subcharts[name])
+		if err != nil {
+			return nil, fmt.Errorf("error loading %s: %v", name, err)
+		}
+		for _, f := range subfiles {
+			result = append(result, &chartutil.BufferedFile{Name: path.Join("charts", name, f.Name), Data: f.Data})
+		}
+	}
+
+	return result, nil
+}
+
+// crdsV3 return the CRDs of the crds directory of a Helm 3 chart and its
+// enabled subcharts, keyed by their path like the rendered templates
+func crdsV3(c *chart.Chart, parent string) map[string]string {
+	crds := map[string]string{}
+	dir := path.Join(parent, c.Metadata.Name)
+	for _, f := range c.Files {
+		switch path.Ext(f.TypeUrl) {
+		case ".yaml", ".yml", ".json":
+		default:
+			continue
+		}
+		if strings.HasPrefix(f.TypeUrl, "crds/") {
+			crds[path.Join(dir, f.TypeUrl)] = string(f.Value)
+		}
+	}
+	for _, dep := range c.Dependencies {
+		for name, content := range crdsV3(dep, path.Join(dir, "charts")) {
+			crds[name] = content
+		}
+	}
+	return crds
+}
+
+// HasCRDs return true if the chart is a Helm 3 chart with CRDs in the crds
+// directory of the chart or of its dependencies, they are only rendered by
+// helm template with --include-crds
+func HasCRDs(c *chart.Chart) bool {
+	return c.Metadata.ApiVersion == APIVersionV2 && len(crdsV3(c, "")) > 0
+}
+
+// helm3RepositoryPaths return the Helm 3 repositories file and cache
+// directory, following $HELM_REPOSITORY_CONFIG, $HELM_REPOSITORY_CACHE and the
+// XDG base directories
+func helm3RepositoryPaths() (string, string) {
+	xdg := func(env, def string) string {
+		if dir := os.Getenv(env); dir != "" {
+			return dir
+		}
+		home, _ := os.UserHomeDir()
+		return filepath.Join(home, def)
+	}
+
+	config := os.Getenv("HELM_REPOSITORY_CONFIG")
+	if config == "" {
+		config = filepath.Join(xdg("XDG_CONFIG_HOME", ".config"), "helm", "repositories.yaml")
+	}
+	cache := os.Getenv("HELM_REPOSITORY_CACHE")
+	if cache == "" {
+		cache = filepath.Join(xdg("XDG_CACHE_HOME", ".cache"), "helm", "repository")
+	}
+	return config, cache
+}
+
+// helm3Repository is an entry of the Helm 3 repositories file
+type helm3Repository struct {
+	Name     string `json:"name"`
+	URL      string `json:"url"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+	CertFile string `json:"certFile"`
+	KeyFile  string `json:"keyFile"`
+	CAFile   string `json:"caFile"`
+}
+
+// helm3Home return a Helm 2 home using the Helm 3 repositories, it is
+// written in the Helm 3 cache directory. The chart archives are downloaded
+// in it. The repositories file holds the repository credentials, it is only
+// readable by the user like the Helm 3 one.
+func helm3Home(config, cache string) (helmpath.Home, error) {
+	data, err := ioutil.ReadFile(config)
+	if err != nil {
+		return "", err
+	}
+	f := struct {
+		Repositories []*helm3Repository `json:"repositories"`
+	}{}
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return "", fmt.Errorf("cannot parse %s: %v", config, err)
+	}
+
+	home := helmpath.Home(filepath.Join(filepath.Dir(cache), "helm-convert"))
+	for _, dir := range []string{home.Cache(), home.Archive()} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return "", err
+		}
+	}
+
+	rf := repo.NewRepoFile()
+	for _, r := range f.Repositories {
+		// the index is copied as Helm 3 stores it under another name
+		index, err := ioutil.ReadFile(filepath.Join(cache, r.Name+"-index.yaml"))
+		if err != nil {
+			glog.Warningf("Repository %s has no index, run helm repo update: %v", r.Name, err)
+		} else if err := ioutil.WriteFile(home.CacheIndex(r.Name), index, 0644); err != nil {
+			return "", err
+		}
+
+		rf.Add(&repo.Entry{
+			Name:     r.Name,
+			Cache:    home.CacheIndex(r.Name),
+			URL:      r.URL,
+			Username: r.Username,
+			Password: r.Password,
+			CertFile: r.CertFile,
+			KeyFile:  r.KeyFile,
+			CAFile:   r.CAFile,
+		})
+	}
+	if err := rf.WriteFile(home.RepositoryFile(), 0600); err != nil {
+		return "", err
+	}
+	// the mode isn't changed when the file already exists
+	if err := os.Chmod(home.RepositoryFile(), 0600); err != nil {
+		return "", err
+	}
+
+	return home, nil
+}
+
+// updateHelm3Dependencies run update with the dependencies of a Helm 3 chart
+// directory. The dependencies are resolved in a temporary copy of the chart
+// with a requirements.yaml, the archives are then moved to the charts
+// directory and a Helm 3 Chart.lock is written.
+func updateHelm3Dependencies(chartPath string, update func(chartPath string) error) error {
+	files, err := readDir(chartPath)
+	if err != nil {
+		return err
+	}
+	cf, err := parseChartfile(files)
+	if err != nil {
+		return err
+	}
+	if cf.version() != APIVersionV2 || len(cf.Dependencies) == 0 {
+		return update(chartPath)
+	}
+	if _, err := os.Stat(filepath.Join(chartPath, "requirements.yaml")); err == nil {
+		return update(chartPath)
+	}
+
+	abs, err := filepath.Abs(chartPath)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempDir("", "helm-convert-deps")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	chartfile, err := ioutil.ReadFile(filepath.Join(chartPath, "Chart.yaml"))
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(tmp, "Chart.yaml"), chartfile, 0644); err != nil {
+		return err
+	}
+
+	// local dependencies are relative to the chart, not to the copy
+	req := make([]*chartutil.Dependency, len(cf.Dependencies))
+	for i, d := range cf.Dependencies {
+		dep := *d
+		if p := strings.TrimPrefix(dep.Repository, "file://"); p != dep.Repository && !filepath.IsAbs(p) {
+			dep.Repository = "file://" + filepath.Join(abs, p)
+		}
+		req[i] = &dep
+	}
+	data, err := yaml.Marshal(&chartutil.Requirements{Dependencies: req})
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(tmp, "requirements.yaml"), data, 0644); err != nil {
+		return err
+	}
+
+	if err := update(tmp); err != nil {
+		return err
+	}
+
+	data, err = ioutil.ReadFile(filepath.Join(tmp, "requirements.lock"))
+	if err != nil {
+		return err
+	}
+	lock := &chartutil.RequirementsLock{}
+	if err := yaml.Unmarshal(data, lock); err != nil {
+		return err
+	}
+	if len(lock.Dependencies) != len(cf.Dependencies) {
+		return fmt.Errorf("resolved %d dependencies of %d", len(lock.Dependencies), len(cf.Dependencies))
+	}
+
+	if err := moveDependencies(filepath.Join(tmp, "charts"), filepath.Join(chartPath, "charts"), cf.Dependencies); err != nil {
+		return err
+	}
+
+	data, err = helm3Lock(cf.Dependencies, lock)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(chartPath, "Chart.lock"), data, 0644)
+}
+
+// moveDependencies replace the archives of the dependencies in the charts
+// directory by the downloaded ones, like helm dependency update does
+func moveDependencies(src, dest string, deps []*chartutil.Dependency) error {
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return err
+	}
+
+	for _, d := range deps {
+		archives, err := filepath.Glob(filepath.Join(dest, d.Name+"-*.tgz"))
+		if err != nil {
+			return err
+		}
+		for _, archive := range archives {
+			c, err := chartutil.LoadFile(archive)
+			if err != nil || c.Metadata.Name != d.Name {
+				continue
+			}
+			if err := os.Remove(archive); err != nil {
+				return err
+			}
+		}
+	}
+
+	downloaded, err := ioutil.ReadDir(src)
+	if err != nil {
+		return err
+	}
+	for _, fi := range downloaded {
+		data, err := ioutil.ReadFile(filepath.Join(src, fi.Name()))
+		if err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(dest, fi.Name()), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// helm3Lock return the Chart.lock of the dependencies resolved by Helm 2,
+// the repositories are the ones of Chart.yaml resolved like Helm 3 does and
+// the digest is the one of Helm 3 which hashes the lock too
+func helm3Lock(deps []*chartutil.Dependency, resolved *chartutil.RequirementsLock) ([]byte, error) {
+	req := make([]*chartutil.Dependency, len(deps))
+	locked := make([]*chartutil.Dependency, len(deps))
+	for i, d := range deps {
+		dep := *d
+		// repository aliases are replaced by their URL
+		if strings.HasPrefix(d.Repository, "@") || strings.HasPrefix(d.Repository, "alias:") {
+			dep.Repository = resolved.Dependencies[i].Repository
+		}
+		req[i] = &dep
+		locked[i] = &chartutil.Dependency{
+			Name:       d.Name,
+			Version:    resolved.Dependencies[i].Version,
+			Repository: dep.Repository,
+		}
+	}
+
+	data, err := json.Marshal([2][]*chartutil.Dependency{req, locked})
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(data)
+
+	return yaml.Marshal(&chartutil.RequirementsLock{
+		Generated:    resolved.Generated,
+		Digest:       "sha256:" + hex.EncodeToString(sum[:]),
+		Dependencies: locked,
+	})
+}
@@ -0,0 +1,309 @@
+package helm
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/kylelemons/godebug/pretty"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/downloader"
+	"k8s.io/helm/pkg/helm/helmpath"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/repo"
+)
+
+func TestRenderHelm3Chart(t *testing.T) {
+	for _, test := range []struct {
+		name             string
+		values           string
+		kubeVersion      string
+		apiVersions      []string
+		chartKubeVersion string
+		expectedNames    []string
+		expectedTemplate string
+		expectedError    string
+	}{
+		{
+			name:   "it should render the templates and CRDs of the enabled charts",
+			values: "{}",
+			expectedNames: []string{
+				"app/crds/widget.yaml",
+				"app/templates/deploy.yaml",
+			},
+			expectedTemplate: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web-app
+  labels:
+    managed-by: Helm
+    kube-version: v1.29.0
+    policy-v1: "true"
+spec:
+  replicas: 2
+`,
+		},
+		{
+			name:   "it should render the CRDs of the subcharts enabled by a condition",
+			values: "opt:\n  enabled: true\n",
+			expectedNames: []string{
+				"app/charts/opt/crds/gadget.yaml",
+				"app/charts/opt/templates/svc.yaml",
+				"app/crds/widget.yaml",
+				"app/templates/deploy.yaml",
+			},
+		},
+		{
+			name:        "it should render for the given Kubernetes version and API versions",
+			values:      "{}",
+			kubeVersion: "v1.20.4",
+			apiVersions: []string{"policy/v1"},
+			expectedNames: []string{
+				"app/crds/widget.yaml",
+				"app/templates/deploy.yaml",
+			},
+			expectedTemplate: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web-app
+  labels:
+    managed-by: Helm
+    kube-version: v1.20.4
+    policy-v1: "true"
+spec:
+  replicas: 2
+`,
+		},
+		{
+			name:             "it should fail if the Kubernetes version doesn't match the chart kubeVersion",
+			values:           "{}",
+			kubeVersion:      "1.20",
+			chartKubeVersion: ">= 1.25.0-0",
+			expectedError:    "chart app requires kubeVersion: >= 1.25.0-0 which is incompatible with Kubernetes v1.20.0",
+		},
+		{
+			name:          "it should fail with an invalid Kubernetes version",
+			values:        "{}",
+			kubeVersion:   "latest",
+			expectedError: "could not parse a kubernetes version: latest",
+		},
+	} {
+		t.Run(fmt.Sprintf("%s", test.name), func(t *testing.T) {
+			c, err := LoadChartPath("testdata/helm3")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			c.Metadata.KubeVersion = test.chartKubeVersion
+
+			rendered, err := render(c, &chart.Config{Raw: test.values}, renderOptions{
+				ReleaseOptions: chartutil.ReleaseOptions{Name: "web", Namespace: "default"},
+				KubeVersion:    test.kubeVersion,
+				APIVersions:    test.apiVersions,
+			})
+			if test.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedError) {
+					t.Fatalf("%s: expected error %q, got %v", test.name, test.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var names []string
+			for name := range rendered {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			if diff := pretty.Compare(names, test.expectedNames); diff != "" {
+				t.Errorf("%s, diff: (-got +want)\n%s", test.name, diff)
+			}
+
+			if test.expectedTemplate != "" && rendered["app/templates/deploy.yaml"] != test.expectedTemplate {
+				t.Errorf("%s: got\n%s\nwant\n%s", test.name, rendered["app/templates/deploy.yaml"], test.expectedTemplate)
+			}
+		})
+	}
+}
+
+func TestLoadChartPath(t *testing.T) {
+	for _, test := range []struct {
+		name          string
+		path          string
+		expectedError string
+	}{
+		{
+			name:          "it should not load a library chart",
+			path:          "testdata/helm3/charts/common",
+			expectedError: "library charts are not installable",
+		},
+		{
+			name: "it should load a Helm 3 chart",
+			path: "testdata/helm3",
+		},
+	} {
+		t.Run(fmt.Sprintf("%s", test.name), func(t *testing.T) {
+			c, err := LoadChartPath(test.path)
+			if test.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedError) {
+					t.Fatalf("%s: expected error %q, got %v", test.name, test.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !HasCRDs(c) {
+				t.Errorf("%s: expected the CRDs of the crds directories", test.name)
+			}
+
+			req, err := chartutil.LoadRequirements(c)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(req.Dependencies) != 2 || len(c.Dependencies) != 2 {
+				t.Errorf("%s: expected the 2 dependencies of Chart.yaml, got %d requirements and %d charts",
+					test.name, len(req.Dependencies), len(c.Dependencies))
+			}
+		})
+	}
+}
+
+func TestUpdateHelm3Dependencies(t *testing.T) {
+	dir, err := ioutil.TempDir("", "helm-convert-deps")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for name, data := range map[string]string{
+		"app/Chart.yaml": `apiVersion: v2
+name: app
+version: 1.0.0
+dependencies:
+- name: db
+  version: ~0.1.0
+  repository: file://../db
+  condition: db.enabled
+`,
+		"db/Chart.yaml":                     "apiVersion: v1\nname: db\nversion: 0.1.2\n",
+		"home/repository/repositories.yaml": "",
+	} {
+		p := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if err := ioutil.WriteFile(p, []byte(data), 0644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	home := helmpath.Home(filepath.Join(dir, "home"))
+	if err := repo.NewRepoFile().WriteFile(home.RepositoryFile(), 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	man := &downloader.Manager{Out: ioutil.Discard, HelmHome: home}
+	update := func(p string) error {
+		man.ChartPath = p
+		return man.Update()
+	}
+	if err := updateHelm3Dependencies(filepath.Join(dir, "app"), update); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	infos, err := ioutil.ReadDir(filepath.Join(dir, "app"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var files []string
+	for _, fi := range infos {
+		files = append(files, fi.Name())
+	}
+	if diff := pretty.Compare(files, []string{"Chart.lock", "Chart.yaml", "charts"}); diff != "" {
+		t.Errorf("chart files, diff: (-got +want)\n%s", diff)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "app/charts/db-0.1.2.tgz")); err != nil {
+		t.Errorf("expected the dependency archive: %v", err)
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "app/Chart.lock"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	lock := &chartutil.RequirementsLock{}
+	if err := yaml.Unmarshal(data, lock); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expectedLock := &chartutil.RequirementsLock{
+		Generated: lock.Generated,
+		// digest of helm dependency update with Helm 3
+		Digest: "sha256:8c4d79b04b3f3aecf57f42abc64f9e39ab4ea5a811767e3d68ec1747d7c45519",
+		Dependencies: []*chartutil.Dependency{
+			{Name: "db", Version: "0.1.2", Repository: "file://../db"},
+		},
+	}
+	if diff := pretty.Compare(lock, expectedLock); diff != "" {
+		t.Errorf("Chart.lock, diff: (-got +want)\n%s", diff)
+	}
+}
+
+func TestHelm3Home(t *testing.T) {
+	dir, err := ioutil.TempDir("", "helm-convert-home")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	config := filepath.Join(dir, "repositories.yaml")
+	err = ioutil.WriteFile(config, []byte(`repositories:
+- name: private
+  url: https://charts.example.com
+  username: user
+  password: secret
+`), 0600)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	cache := filepath.Join(dir, "cache", "repository")
+
+	// a repositories file written by a previous version
+	home := helmpath.Home(filepath.Join(dir, "cache", "helm-convert"))
+	if err := os.MkdirAll(home.Repository(), 0755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := ioutil.WriteFile(home.RepositoryFile(), []byte{}, 0644); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := helm3Home(config, cache)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != home {
+		t.Errorf("got home %s, want %s", got, home)
+	}
+
+	fi, err := os.Stat(home.RepositoryFile())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fi.Mode().Perm() != 0600 {
+		t.Errorf("got mode %v of the repositories file, want %v", fi.Mode().Perm(), os.FileMode(0600))
+	}
+
+	rf, err := repo.LoadRepositoriesFile(home.RepositoryFile())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rf.Repositories) != 1 || rf.Repositories[0].Username != "user" {
+		t.Errorf("expected the private repository, got %v", rf.Repositories)
+	}
+}
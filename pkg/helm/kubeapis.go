@@ -0,0 +1,143 @@
+package helm
+
+import (
+	"sort"
+)
+
+// kubeAPI is a group version of the built-in Kubernetes API and the kinds it
+// serves between two minor versions of Kubernetes 1.x
+type kubeAPI struct {
+	GroupVersion string
+	Kinds        []string
+
+	// Since is the first minor version serving the kinds
+	Since int
+
+	// Until is the first minor version not serving the kinds anymore, zero if
+	// they are still served
+	Until int
+}
+
+// kubeAPIs are the built-in group versions served by default, alpha versions
+// and the ones disabled by default are not listed
+var kubeAPIs = []kubeAPI{
+	{GroupVersion: "v1", Kinds: []string{
+		"Binding", "ComponentStatus", "ConfigMap", "Endpoints", "Event", "LimitRange", "Namespace", "Node",
+		"PersistentVolume", "PersistentVolumeClaim", "Pod", "PodTemplate", "ReplicationController",
+		"ResourceQuota", "Secret", "Service", "ServiceAccount",
+	}},
+	{GroupVersion: "admissionregistration.k8s.io/v1beta1", Kinds: []string{
+		"MutatingWebhookConfiguration", "ValidatingWebhookConfiguration",
+	}, Since: 9, Until: 22},
+	{GroupVersion: "admissionregistration.k8s.io/v1", Kinds: []string{
+		"MutatingWebhookConfiguration", "ValidatingWebhookConfiguration",
+	}, Since: 16},
+	{GroupVersion: "admissionregistration.k8s.io/v1", Kinds: []string{
+		"ValidatingAdmissionPolicy", "ValidatingAdmissionPolicyBinding",
+	}, Since: 30},
+	{GroupVersion: "apiextensions.k8s.io/v1beta1", Kinds: []string{"CustomResourceDefinition"}, Until: 22},
+	{GroupVersion: "apiextensions.k8s.io/v1", Kinds: []string{"CustomResourceDefinition"}, Since: 16},
+	{GroupVersion: "apiregistration.k8s.io/v1beta1", Kinds: []string{"APIService"}, Until: 22},
+	{GroupVersion: "apiregistration.k8s.io/v1", Kinds: []string{"APIService"}, Since: 10},
+	{GroupVersion: "apps/v1beta1", Kinds: []string{
+		"ControllerRevision", "Deployment", "StatefulSet",
+	}, Until: 16},
+	{GroupVersion: "apps/v1beta2", Kinds: []string{
+		"ControllerRevision", "DaemonSet", "Deployment", "ReplicaSet", "StatefulSet",
+	}, Since: 8, Until: 16},
+	{GroupVersion: "apps/v1", Kinds: []string{
+		"ControllerRevision", "DaemonSet", "Deployment", "ReplicaSet", "StatefulSet",
+	}, Since: 9},
+	{GroupVersion: "authentication.k8s.io/v1beta1", Kinds: []string{"TokenReview"}, Until: 22},
+	{GroupVersion: "authentication.k8s.io/v1", Kinds: []string{"TokenReview"}},
+	{GroupVersion: "authentication.k8s.io/v1", Kinds: []string{"SelfSubjectReview"}, Since: 28},
+	{GroupVersion: "authorization.k8s.io/v1beta1", Kinds: []string{
+		"LocalSubjectAccessReview", "SelfSubjectAccessReview", "SelfSubjectRulesReview", "SubjectAccessReview",
+	}, Until: 22},
+	{GroupVersion: "authorization.k8s.io/v1", Kinds: []string{
+		"LocalSubjectAccessReview", "SelfSubjectAccessReview", "SelfSubjectRulesReview", "SubjectAccessReview",
+	}},
+	{GroupVersion: "autoscaling/v1", Kinds: []string{"HorizontalPodAutoscaler"}},
+	{GroupVersion: "autoscaling/v2beta1", Kinds: []string{"HorizontalPodAutoscaler"}, Since: 8, Until: 25},
+	{GroupVersion: "autoscaling/v2beta2", Kinds: []string{"HorizontalPodAutoscaler"}, Since: 12, Until: 26},
+	{GroupVersion: "autoscaling/v2", Kinds: []string{"HorizontalPodAutoscaler"}, Since: 23},
+	{GroupVersion: "batch/v1", Kinds: []string{"Job"}},
+	{GroupVersion: "batch/v1", Kinds: []string{"CronJob"}, Since: 21},
+	{GroupVersion: "batch/v1beta1", Kinds: []string{"CronJob"}, Since: 8, Until: 25},
+	{GroupVersion: "certificates.k8s.io/v1beta1", Kinds: []string{"CertificateSigningRequest"}, Until: 22},
+	{GroupVersion: "certificates.k8s.io/v1", Kinds: []string{"CertificateSigningRequest"}, Since: 19},
+	{GroupVersion: "coordination.k8s.io/v1beta1", Kinds: []string{"Lease"}, Since: 12, Until: 22},
+	{GroupVersion: "coordination.k8s.io/v1", Kinds: []string{"Lease"}, Since: 14},
+	{GroupVersion: "discovery.k8s.io/v1beta1", Kinds: []string{"EndpointSlice"}, Since: 17, Until: 25},
+	{GroupVersion: "discovery.k8s.io/v1", Kinds: []string{"EndpointSlice"}, Since: 21},
+	{GroupVersion: "events.k8s.io/v1beta1", Kinds: []string{"Event"}, Since: 8, Until: 25},
+	{GroupVersion: "events.k8s.io/v1", Kinds: []string{"Event"}, Since: 19},
+	{GroupVersion: "extensions/v1beta1", Kinds: []string{
+		"DaemonSet", "Deployment", "NetworkPolicy", "PodSecurityPolicy", "ReplicaSet",
+	}, Until: 16},
+	{GroupVersion: "extensions/v1beta1", Kinds: []string{"Ingress"}, Until: 22},
+	{GroupVersion: "flowcontrol.apiserver.k8s.io/v1beta1", Kinds: []string{
+		"FlowSchema", "PriorityLevelConfiguration",
+	}, Since: 20, Until: 26},
+	{GroupVersion: "flowcontrol.apiserver.k8s.io/v1beta2", Kinds: []string{
+		"FlowSchema", "PriorityLevelConfiguration",
+	}, Since: 23, Until: 29},
+	{GroupVersion: "flowcontrol.apiserver.k8s.io/v1beta3", Kinds: []string{
+		"FlowSchema", "PriorityLevelConfiguration",
+	}, Since: 26, Until: 32},
+	{GroupVersion: "flowcontrol.apiserver.k8s.io/v1", Kinds: []string{
+		"FlowSchema", "PriorityLevelConfiguration",
+	}, Since: 29},
+	{GroupVersion: "networking.k8s.io/v1", Kinds: []string{"NetworkPolicy"}, Since: 7},
+	{GroupVersion: "networking.k8s.io/v1", Kinds: []string{"Ingress", "IngressClass"}, Since: 19},
+	{GroupVersion: "networking.k8s.io/v1beta1", Kinds: []string{"Ingress"}, Since: 14, Until: 22},
+	{GroupVersion: "networking.k8s.io/v1beta1", Kinds: []string{"IngressClass"}, Since: 18, Until: 22},
+	{GroupVersion: "node.k8s.io/v1beta1", Kinds: []string{"RuntimeClass"}, Since: 14, Until: 25},
+	{GroupVersion: "node.k8s.io/v1", Kinds: []string{"RuntimeClass"}, Since: 20},
+	{GroupVersion: "policy/v1beta1", Kinds: []string{"PodDisruptionBudget"}, Until: 25},
+	{GroupVersion: "policy/v1beta1", Kinds: []string{"PodSecurityPolicy"}, Since: 10, Until: 25},
+	{GroupVersion: "policy/v1", Kinds: []string{"PodDisruptionBudget"}, Since: 21},
+	{GroupVersion: "rbac.authorization.k8s.io/v1beta1", Kinds: []string{
+		"ClusterRole", "ClusterRoleBinding", "Role", "RoleBinding",
+	}, Until: 22},
+	{GroupVersion: "rbac.authorization.k8s.io/v1", Kinds: []string{
+		"ClusterRole", "ClusterRoleBinding", "Role", "RoleBinding",
+	}, Since: 8},
+	{GroupVersion: "scheduling.k8s.io/v1beta1", Kinds: []string{"PriorityClass"}, Since: 11, Until: 22},
+	{GroupVersion: "scheduling.k8s.io/v1", Kinds: []string{"PriorityClass"}, Since: 14},
+	{GroupVersion: "storage.k8s.io/v1beta1", Kinds: []string{
+		"CSIDriver", "CSINode", "StorageClass", "VolumeAttachment",
+	}, Until: 22},
+	{GroupVersion: "storage.k8s.io/v1beta1", Kinds: []string{"CSIStorageCapacity"}, Since: 21, Until: 27},
+	{GroupVersion: "storage.k8s.io/v1", Kinds: []string{"StorageClass"}},
+	{GroupVersion: "storage.k8s.io/v1", Kinds: []string{"VolumeAttachment"}, Since: 13},
+	{GroupVersion: "storage.k8s.io/v1", Kinds: []string{"CSINode"}, Since: 17},
+	{GroupVersion: "storage.k8s.io/v1", Kinds: []string{"CSIDriver"}, Since: 18},
+	{GroupVersion: "storage.k8s.io/v1", Kinds: []string{"CSIStorageCapacity"}, Since: 24},
+}
+
+// kubeAPIVersions return the group versions and the group/version/kind
+// served by a minor version of Kubernetes 1.x, like the default API versions
+// of Helm 3
+func kubeAPIVersions(minor int) []string {
+	seen := make(map[string]struct{})
+	var versions []string
+	add := func(v string) {
+		if _, ok := seen[v]; !ok {
+			seen[v] = struct{}{}
+			versions = append(versions, v)
+		}
+	}
+
+	for _, api := range kubeAPIs {
+		if minor < api.Since || (api.Until != 0 && minor >= api.Until) {
+			continue
+		}
+		add(api.GroupVersion)
+		for _, kind := range api.Kinds {
+			add(api.GroupVersion + "/" + kind)
+		}
+	}
+	sort.Strings(versions)
+	return versions
+}
@@ -0,0 +1,53 @@
+package helm
+
+import (
+	"fmt"
+	"testing"
+
+	"k8s.io/helm/pkg/chartutil"
+)
+
+func TestKubeAPIVersions(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		minor    int
+		expected map[string]bool
+	}{
+		{
+			name:  "it should serve the deprecated APIs of Kubernetes 1.16",
+			minor: 16,
+			expected: map[string]bool{
+				"v1/Secret":                          true,
+				"extensions/v1beta1/Ingress":         true,
+				"extensions/v1beta1/Deployment":      false,
+				"networking.k8s.io/v1":               true,
+				"networking.k8s.io/v1/NetworkPolicy": true,
+				"networking.k8s.io/v1/Ingress":       false,
+				"policy/v1beta1/PodSecurityPolicy":   true,
+				"policy/v1":                          false,
+			},
+		},
+		{
+			name:  "it should serve the APIs of Kubernetes 1.29 per group version and kind",
+			minor: 29,
+			expected: map[string]bool{
+				"networking.k8s.io/v1":                 true,
+				"networking.k8s.io/v1/Ingress":         true,
+				"extensions/v1beta1":                   false,
+				"policy/v1/PodDisruptionBudget":        true,
+				"policy/v1beta1/PodSecurityPolicy":     false,
+				"batch/v1/CronJob":                     true,
+				"flowcontrol.apiserver.k8s.io/v1beta2": false,
+			},
+		},
+	} {
+		t.Run(fmt.Sprintf("%s", test.name), func(t *testing.T) {
+			versions := chartutil.NewVersionSet(kubeAPIVersions(test.minor)...)
+			for version, expected := range test.expected {
+				if versions.Has(version) != expected {
+					t.Errorf("%s: expected %s to be %v", test.name, version, expected)
+				}
+			}
+		})
+	}
+}
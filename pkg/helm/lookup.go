@@ -0,0 +1,167 @@
+package helm
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang/glog"
+	"k8s.io/apimachinery/pkg/runtime"
+	k8syaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// LookupFixtures are the objects the lookup template function resolves
+// against, they reproduce the state of a cluster when rendering offline
+type LookupFixtures struct {
+	objects []map[string]interface{}
+}
+
+// lookupFunc return the lookup template function, it resolves against the
+// fixtures if any and returns an empty object otherwise, like helm template
+func lookupFunc(fixtures *LookupFixtures) func(apiVersion, kind, namespace, name string) (map[string]interface{}, error) {
+	return func(apiVersion, kind, namespace, name string) (map[string]interface{}, error) {
+		if fixtures != nil {
+			return fixtures.Lookup(apiVersion, kind, namespace, name)
+		}
+		return map[string]interface{}{}, nil
+	}
+}
+
+// LoadLookupFixtures load the YAML or JSON objects of a file or of the files of
+// a directory. Lists, ie: the output of kubectl get -o yaml, are expanded and
+// the stringData of secrets is encoded in their data like the API server does.
+func LoadLookupFixtures(root string) (*LookupFixtures, error) {
+	fixtures := &LookupFixtures{}
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(p)) {
+		case ".yaml", ".yml", ".json":
+		default:
+			return nil
+		}
+		return fixtures.loadFile(p)
+	})
+	if err != nil {
+		return nil, err
+	}
+	glog.V(4).Infof("Loaded %d lookup fixtures from %s", len(fixtures.objects), root)
+	return fixtures, nil
+}
+
+// loadFile load the documents of a fixture file
+func (f *LookupFixtures) loadFile(filename string) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	decoder := k8syaml.NewYAMLOrJSONDecoder(file, 4096)
+	for i := 0; ; i++ {
+		var obj map[string]interface{}
+		err := decoder.Decode(&obj)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to parse the lookup fixtures of %s: %v", filename, err)
+		}
+		if len(obj) == 0 {
+			continue
+		}
+
+		objects := []map[string]interface{}{obj}
+		if items, ok := obj["items"].([]interface{}); ok && strings.HasSuffix(stringField(obj, "kind"), "List") {
+			objects = nil
+			for _, item := range items {
+				if m, ok := item.(map[string]interface{}); ok {
+					objects = append(objects, m)
+				}
+			}
+		}
+
+		for _, o := range objects {
+			if stringField(o, "apiVersion") == "" || stringField(o, "kind") == "" || stringField(o, "metadata", "name") == "" {
+				return fmt.Errorf("lookup fixture #%d of %s has no apiVersion, kind or metadata.name", i, filename)
+			}
+			encodeStringData(o)
+			f.objects = append(f.objects, o)
+		}
+	}
+}
+
+// Lookup resolve a call to the lookup template function. An object is
+// returned if the name is given, an empty object if it doesn't exist.
+// Otherwise a list of the objects of the namespace is returned, of all the
+// namespaces if the namespace is empty. Objects without namespace match all
+// the namespaces.
+func (f *LookupFixtures) Lookup(apiVersion, kind, namespace, name string) (map[string]interface{}, error) {
+	items := []interface{}{}
+	for _, obj := range f.objects {
+		if stringField(obj, "apiVersion") != apiVersion || stringField(obj, "kind") != kind {
+			continue
+		}
+		ns := stringField(obj, "metadata", "namespace")
+		if namespace != "" && ns != "" && ns != namespace {
+			continue
+		}
+
+		if name == "" {
+			items = append(items, runtime.DeepCopyJSON(obj))
+			continue
+		}
+		if stringField(obj, "metadata", "name") == name {
+			return runtime.DeepCopyJSON(obj), nil
+		}
+	}
+
+	if name != "" {
+		return map[string]interface{}{}, nil
+	}
+	return map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       kind + "List",
+		"metadata":   map[string]interface{}{},
+		"items":      items,
+	}, nil
+}
+
+// encodeStringData move the stringData of a secret to its data
+func encodeStringData(obj map[string]interface{}) {
+	stringData, ok := obj["stringData"].(map[string]interface{})
+	if !ok || stringField(obj, "apiVersion") != "v1" || stringField(obj, "kind") != "Secret" {
+		return
+	}
+
+	data, ok := obj["data"].(map[string]interface{})
+	if !ok {
+		data = map[string]interface{}{}
+	}
+	for key, value := range stringData {
+		data[key] = base64.StdEncoding.EncodeToString([]byte(fmt.Sprint(value)))
+	}
+	obj["data"] = data
+	delete(obj, "stringData")
+}
+
+// stringField return a string field of an object, empty if it doesn't exist
+func stringField(obj map[string]interface{}, fields ...string) string {
+	var current interface{} = obj
+	for _, field := range fields {
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return ""
+		}
+		current = m[field]
+	}
+	s, _ := current.(string)
+	return s
+}
@@ -0,0 +1,229 @@
+package helm
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kylelemons/godebug/pretty"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+func TestLookup(t *testing.T) {
+	fixtures, err := LoadLookupFixtures("testdata/fixtures")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, test := range []struct {
+		name       string
+		apiVersion string
+		kind       string
+		namespace  string
+		objName    string
+		expected   map[string]interface{}
+	}{
+		{
+			name:       "it should return the object of the namespace",
+			apiVersion: "v1",
+			kind:       "Secret",
+			namespace:  "staging",
+			objName:    "web-db",
+			expected: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Secret",
+				"metadata":   map[string]interface{}{"name": "web-db", "namespace": "staging"},
+				"data":       map[string]interface{}{"password": "c3RhZ2luZw=="},
+			},
+		},
+		{
+			name:       "it should encode the stringData of a secret",
+			apiVersion: "v1",
+			kind:       "Secret",
+			namespace:  "default",
+			objName:    "web-api",
+			expected: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Secret",
+				"metadata":   map[string]interface{}{"name": "web-api", "namespace": "default"},
+				"data":       map[string]interface{}{"token": "YWJj"},
+			},
+		},
+		{
+			name:       "it should return an empty object if the object doesn't exist",
+			apiVersion: "v1",
+			kind:       "Secret",
+			namespace:  "production",
+			objName:    "web-db",
+			expected:   map[string]interface{}{},
+		},
+		{
+			name:       "it should match cluster scoped objects in any namespace",
+			apiVersion: "v1",
+			kind:       "Namespace",
+			namespace:  "staging",
+			objName:    "default",
+			expected: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "Namespace",
+				"metadata":   map[string]interface{}{"name": "default"},
+			},
+		},
+		{
+			name:       "it should list the objects of all the namespaces without name",
+			apiVersion: "v1",
+			kind:       "Secret",
+			expected: map[string]interface{}{
+				"apiVersion": "v1",
+				"kind":       "SecretList",
+				"metadata":   map[string]interface{}{},
+				"items": []interface{}{
+					map[string]interface{}{
+						"apiVersion": "v1",
+						"kind":       "Secret",
+						"metadata":   map[string]interface{}{"name": "web-api", "namespace": "default"},
+						"data":       map[string]interface{}{"token": "YWJj"},
+					},
+					map[string]interface{}{
+						"apiVersion": "v1",
+						"kind":       "Secret",
+						"metadata":   map[string]interface{}{"name": "web-db", "namespace": "default"},
+						"data":       map[string]interface{}{"password": "c2VjcmV0"},
+					},
+					map[string]interface{}{
+						"apiVersion": "v1",
+						"kind":       "Secret",
+						"metadata":   map[string]interface{}{"name": "web-db", "namespace": "staging"},
+						"data":       map[string]interface{}{"password": "c3RhZ2luZw=="},
+					},
+				},
+			},
+		},
+	} {
+		t.Run(fmt.Sprintf("%s", test.name), func(t *testing.T) {
+			obj, err := fixtures.Lookup(test.apiVersion, test.kind, test.namespace, test.objName)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if diff := pretty.Compare(obj, test.expected); diff != "" {
+				t.Errorf("%s, diff: (-got +want)\n%s", test.name, diff)
+			}
+		})
+	}
+}
+
+func TestLoadLookupFixtures(t *testing.T) {
+	for _, test := range []struct {
+		name          string
+		content       string
+		expectedError string
+	}{
+		{
+			name:    "it should skip the empty documents",
+			content: "---\n# comment\n---\napiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: web\n",
+		},
+		{
+			name:          "it should fail with an object without name",
+			content:       "apiVersion: v1\nkind: ConfigMap\nmetadata: {}\n",
+			expectedError: "lookup fixture #0 of",
+		},
+		{
+			name:          "it should fail with invalid YAML",
+			content:       "apiVersion: [v1\n",
+			expectedError: "failed to parse the lookup fixtures of",
+		},
+	} {
+		t.Run(fmt.Sprintf("%s", test.name), func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "lookup")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			defer os.RemoveAll(dir)
+
+			filename := filepath.Join(dir, "fixtures.yaml")
+			if err := ioutil.WriteFile(filename, []byte(test.content), 0644); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			_, err = LoadLookupFixtures(filename)
+			if test.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedError) {
+					t.Fatalf("%s: expected error %q, got %v", test.name, test.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestRenderLookup(t *testing.T) {
+	fixtures, err := LoadLookupFixtures("testdata/fixtures")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for _, test := range []struct {
+		name            string
+		chartAPIVersion string
+		namespace       string
+		fixtures        *LookupFixtures
+		expected        string
+	}{
+		{
+			name:      "it should keep the existing password",
+			namespace: "staging",
+			fixtures:  fixtures,
+			expected:  "password: c3RhZ2luZw==",
+		},
+		{
+			name:            "it should keep the existing password of a Helm 2 chart",
+			chartAPIVersion: APIVersionV1,
+			namespace:       "staging",
+			fixtures:        fixtures,
+			expected:        "password: c3RhZ2luZw==",
+		},
+		{
+			name:      "it should generate a password without existing secret",
+			namespace: "production",
+			fixtures:  fixtures,
+		},
+		{
+			name:      "it should generate a password without fixtures",
+			namespace: "staging",
+		},
+	} {
+		t.Run(fmt.Sprintf("%s", test.name), func(t *testing.T) {
+			c, err := LoadChartPath("testdata/lookup")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if test.chartAPIVersion != "" {
+				c.Metadata.ApiVersion = test.chartAPIVersion
+			}
+
+			rendered, err := render(c, &chart.Config{Raw: "{}"}, renderOptions{
+				ReleaseOptions: chartutil.ReleaseOptions{Name: "web", Namespace: test.namespace},
+				LookupFixtures: test.fixtures,
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			secret := rendered["lookup/templates/secret.yaml"]
+			if test.expected != "" && !strings.Contains(secret, test.expected) {
+				t.Errorf("%s: got\n%s\nwant %s", test.name, secret, test.expected)
+			}
+			if test.expected == "" && strings.Contains(secret, "c3RhZ2luZw==") {
+				t.Errorf("%s: got the existing password\n%s", test.name, secret)
+			}
+		})
+	}
+}
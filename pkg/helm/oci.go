@@ -0,0 +1,509 @@
+package helm
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"github.com/golang/glog"
+	"k8s.io/helm/pkg/tlsutil"
+)
+
+// OCIScheme is the scheme of the charts stored in an OCI registry, ie:
+// oci://registry.example.com/charts/app
+const OCIScheme = "oci://"
+
+const (
+	ociManifestMediaType = "application/vnd.oci.image.manifest.v1+json"
+	ociChartMediaType    = "application/vnd.cncf.helm.chart.content.v1.tar+gzip"
+)
+
+// IsOCIReference return true if the chart is stored in an OCI registry
+func IsOCIReference(name string) bool {
+	return strings.HasPrefix(name, OCIScheme)
+}
+
+// ociReference is a chart stored in an OCI registry
+type ociReference struct {
+	// Registry is the host of the registry, ie: registry.example.com:5000
+	Registry string
+
+	// Repository is the path of the chart in the registry, ie: charts/app
+	Repository string
+
+	// Tag or Digest of the chart, both are empty if the version is resolved
+	// from the tags of the repository
+	Tag    string
+	Digest string
+}
+
+// parseOCIReference parse oci://registry/repository[:tag|@digest]
+func parseOCIReference(name string) (*ociReference, error) {
+	ref := &ociReference{}
+	rest := strings.TrimPrefix(name, OCIScheme)
+
+	if i := strings.Index(rest, "@"); i >= 0 {
+		rest, ref.Digest = rest[:i], rest[i+1:]
+	}
+
+	parts := strings.SplitN(rest, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return nil, fmt.Errorf("invalid OCI reference %q, expected oci://registry/repository", name)
+	}
+	ref.Registry, ref.Repository = parts[0], parts[1]
+
+	if i := strings.LastIndex(ref.Repository, ":"); i >= 0 {
+		ref.Repository, ref.Tag = ref.Repository[:i], ref.Repository[i+1:]
+	}
+	return ref, nil
+}
+
+// Name return the name of the chart, the last element of the repository
+func (r *ociReference) Name() string {
+	return path.Base(r.Repository)
+}
+
+// RegistryClient pulls charts from an OCI registry with the distribution API
+type RegistryClient struct {
+	// Username and Password authenticate to the registry, the credentials of
+	// the docker config files are used if empty
+	Username string
+	Password string
+
+	// PlainHTTP use HTTP instead of HTTPS
+	PlainHTTP bool
+
+	// DockerConfigs are the docker config files the credentials are looked
+	// up in, the first one with credentials for the registry is used
+	DockerConfigs []string
+
+	client *http.Client
+	tokens map[string]string
+}
+
+// NewRegistryClient constructs a RegistryClient, the certificates are
+// optional
+func NewRegistryClient(username, password, certFile, keyFile, caFile string) (*RegistryClient, error) {
+	client := &http.Client{}
+	if certFile != "" || keyFile != "" || caFile != "" {
+		tlsConfig, err := tlsutil.NewClientTLS(certFile, keyFile, caFile)
+		if err != nil {
+			return nil, err
+		}
+		client.Transport = &http.Transport{Proxy: http.ProxyFromEnvironment, TLSClientConfig: tlsConfig}
+	}
+
+	return &RegistryClient{
+		Username:      username,
+		Password:      password,
+		DockerConfigs: dockerConfigPaths(),
+		client:        client,
+		tokens:        map[string]string{},
+	}, nil
+}
+
+// dockerConfigPaths return the Helm 3 registry config and the docker config,
+// following $HELM_REGISTRY_CONFIG and $DOCKER_CONFIG
+func dockerConfigPaths() []string {
+	home, _ := os.UserHomeDir()
+
+	helmConfig := os.Getenv("HELM_REGISTRY_CONFIG")
+	if helmConfig == "" {
+		config := os.Getenv("XDG_CONFIG_HOME")
+		if config == "" {
+			config = filepath.Join(home, ".config")
+		}
+		helmConfig = filepath.Join(config, "helm", "registry", "config.json")
+	}
+
+	dockerConfig := os.Getenv("DOCKER_CONFIG")
+	if dockerConfig == "" {
+		dockerConfig = filepath.Join(home, ".docker")
+	}
+
+	return []string{helmConfig, filepath.Join(dockerConfig, "config.json")}
+}
+
+// Pull download the chart archive in the directory and return its path. The
+// version is an exact version, a semver constraint or empty for the latest
+// stable version.
+func (c *RegistryClient) Pull(name, version, dir string) (string, error) {
+	ref, err := parseOCIReference(name)
+	if err != nil {
+		return "", err
+	}
+
+	if ref.Tag != "" && version != "" && ref.Tag != version {
+		return "", fmt.Errorf("chart %s has tag %s but version %s is requested", name, ref.Tag, version)
+	}
+	if ref.Tag == "" && ref.Digest == "" {
+		ref.Tag, err = c.resolveVersion(ref, version)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	manifestRef := ref.Digest
+	if manifestRef == "" {
+		// + is not allowed in tags, Helm replaces it with _
+		manifestRef = strings.Replace(ref.Tag, "+", "_", -1)
+	}
+
+	var manifest struct {
+		Layers []struct {
+			MediaType string `json:"mediaType"`
+			Digest    string `json:"digest"`
+		} `json:"layers"`
+	}
+	data, err := c.get(ref, "manifests/"+manifestRef, ociManifestMediaType)
+	if err != nil {
+		return "", err
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return "", fmt.Errorf("invalid manifest of %s: %v", name, err)
+	}
+
+	digest := ""
+	for _, layer := range manifest.Layers {
+		if layer.MediaType == ociChartMediaType {
+			digest = layer.Digest
+		}
+	}
+	if digest == "" {
+		return "", fmt.Errorf("%s is not a Helm chart, no layer has the media type %s", name, ociChartMediaType)
+	}
+
+	archive, err := c.get(ref, "blobs/"+digest, "")
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(archive)
+	if "sha256:"+hex.EncodeToString(sum[:]) != digest {
+		return "", fmt.Errorf("digest of %s doesn't match %s", name, digest)
+	}
+
+	filename := ref.Name()
+	if ref.Tag != "" {
+		filename += "-" + ref.Tag
+	} else {
+		filename += "-" + strings.TrimPrefix(digest, "sha256:")[:12]
+	}
+	filename = filepath.Join(dir, filename+".tgz")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+	if err := ioutil.WriteFile(filename, archive, 0644); err != nil {
+		return "", err
+	}
+	glog.V(4).Infof("Pulled %s to %s", name, filename)
+
+	return filename, nil
+}
+
+// resolveVersion return the highest version of the repository tags matching
+// the version, the version is returned as is if it is an exact version
+func (c *RegistryClient) resolveVersion(ref *ociReference, version string) (string, error) {
+	if _, err := semver.NewVersion(version); err == nil && version != "" {
+		return version, nil
+	}
+
+	constraint := version
+	if constraint == "" {
+		constraint = "*"
+	}
+	constraints, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return "", fmt.Errorf("invalid version %q: %v", version, err)
+	}
+
+	var tags struct {
+		Tags []string `json:"tags"`
+	}
+	data, err := c.get(ref, "tags/list", "")
+	if err != nil {
+		return "", err
+	}
+	if err := json.Unmarshal(data, &tags); err != nil {
+		return "", fmt.Errorf("invalid tags of %s/%s: %v", ref.Registry, ref.Repository, err)
+	}
+
+	var latest *semver.Version
+	for _, tag := range tags.Tags {
+		v, err := semver.NewVersion(strings.Replace(tag, "_", "+", -1))
+		if err != nil || !constraints.Check(v) {
+			continue
+		}
+		if latest == nil || v.GreaterThan(latest) {
+			latest = v
+		}
+	}
+	if latest == nil {
+		return "", fmt.Errorf("no version of %s/%s matches %q", ref.Registry, ref.Repository, constraint)
+	}
+	return latest.Original(), nil
+}
+
+// get a path of the repository, the registry authentication challenge is
+// answered with the credentials
+func (c *RegistryClient) get(ref *ociReference, p, accept string) ([]byte, error) {
+	scheme := "https"
+	if c.PlainHTTP {
+		scheme = "http"
+	}
+	u := fmt.Sprintf("%s://%s/v2/%s/%s", scheme, ref.Registry, ref.Repository, p)
+
+	resp, err := c.do(ref.Registry, u, accept)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+		if err := c.authenticate(ref, resp.Header.Get("WWW-Authenticate")); err != nil {
+			return nil, err
+		}
+		resp, err = c.do(ref.Registry, u, accept)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %s: %s", u, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// do send a GET request authenticated with the token or credentials of the
+// registry
+func (c *RegistryClient) do(registry, u, accept string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if token, ok := c.tokens[registry]; ok {
+		req.Header.Set("Authorization", token)
+	}
+	return c.client.Do(req)
+}
+
+// authenticate answer a basic or bearer challenge, the authorization is used
+// by the following requests to the registry
+func (c *RegistryClient) authenticate(ref *ociReference, challenge string) error {
+	username, password, err := c.credentials(ref.Registry)
+	if err != nil {
+		return err
+	}
+	scheme, params := parseChallenge(challenge)
+
+	switch scheme {
+	case "basic":
+		if username == "" {
+			return fmt.Errorf("registry %s requires credentials, use --username and --password or docker login", ref.Registry)
+		}
+		c.tokens[ref.Registry] = "Basic " + basicAuth(username, password)
+		return nil
+
+	case "bearer":
+		realm, err := url.Parse(params["realm"])
+		if err != nil || params["realm"] == "" {
+			return fmt.Errorf("invalid authentication challenge of registry %s: %s", ref.Registry, challenge)
+		}
+		q := realm.Query()
+		if params["service"] != "" {
+			q.Set("service", params["service"])
+		}
+		scope := params["scope"]
+		if scope == "" {
+			scope = fmt.Sprintf("repository:%s:pull", ref.Repository)
+		}
+		q.Set("scope", scope)
+		realm.RawQuery = q.Encode()
+
+		req, err := http.NewRequest(http.MethodGet, realm.String(), nil)
+		if err != nil {
+			return err
+		}
+		if username != "" {
+			req.SetBasicAuth(username, password)
+		}
+		resp, err := c.client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("failed to authenticate to registry %s: %s", ref.Registry, resp.Status)
+		}
+
+		var token struct {
+			Token       string `json:"token"`
+			AccessToken string `json:"access_token"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+			return fmt.Errorf("invalid token of registry %s: %v", ref.Registry, err)
+		}
+		if token.Token == "" {
+			token.Token = token.AccessToken
+		}
+		c.tokens[ref.Registry] = "Bearer " + token.Token
+		return nil
+	}
+
+	return fmt.Errorf("unsupported authentication challenge of registry %s: %s", ref.Registry, challenge)
+}
+
+// credentials return the username and password of the flags or of the
+// first docker config with credentials for the registry, either in its auths
+// or in its credential helpers
+func (c *RegistryClient) credentials(registry string) (string, string, error) {
+	if c.Username != "" {
+		return c.Username, c.Password, nil
+	}
+
+	for _, filename := range c.DockerConfigs {
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			continue
+		}
+		var config struct {
+			Auths map[string]struct {
+				Auth     string `json:"auth"`
+				Username string `json:"username"`
+				Password string `json:"password"`
+			} `json:"auths"`
+			CredsStore  string            `json:"credsStore"`
+			CredHelpers map[string]string `json:"credHelpers"`
+		}
+		if err := json.Unmarshal(data, &config); err != nil {
+			glog.Warningf("Couldn't parse docker config %s: %v", filename, err)
+			continue
+		}
+
+		// like docker, the helper of the registry takes precedence over the
+		// credential store
+		helper := config.CredsStore
+		if h, ok := config.CredHelpers[registry]; ok {
+			helper = h
+		}
+		if helper != "" {
+			username, password, err := credentialHelper(helper, registry)
+			if err != nil {
+				return "", "", fmt.Errorf("couldn't get the credentials of registry %s from the credential helper of %s: %v, use --username and --password",
+					registry, filename, err)
+			}
+			if username != "" {
+				return username, password, nil
+			}
+		}
+
+		for key, auth := range config.Auths {
+			host := strings.TrimSuffix(strings.TrimPrefix(strings.TrimPrefix(key, "https://"), "http://"), "/")
+			if strings.SplitN(host, "/", 2)[0] != registry {
+				continue
+			}
+			if auth.Username != "" {
+				return auth.Username, auth.Password, nil
+			}
+			decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+			if err != nil {
+				continue
+			}
+			parts := strings.SplitN(string(decoded), ":", 2)
+			if len(parts) == 2 {
+				return parts[0], parts[1], nil
+			}
+		}
+	}
+	return "", "", nil
+}
+
+// credentialHelper run docker-credential-<helper> get for the registry, empty
+// credentials are returned if the helper has none
+func credentialHelper(helper, registry string) (string, string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("docker-credential-"+helper, "get")
+	cmd.Stdin = strings.NewReader(registry)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		// the message of the helpers of docker/docker-credential-helpers
+		if strings.Contains(stdout.String(), "credentials not found") {
+			return "", "", nil
+		}
+		if msg := strings.TrimSpace(stderr.String() + stdout.String()); msg != "" {
+			return "", "", fmt.Errorf("docker-credential-%s: %v: %s", helper, err, msg)
+		}
+		return "", "", fmt.Errorf("docker-credential-%s: %v", helper, err)
+	}
+
+	var credentials struct {
+		Username string `json:"Username"`
+		Secret   string `json:"Secret"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &credentials); err != nil {
+		return "", "", fmt.Errorf("invalid output of docker-credential-%s: %v", helper, err)
+	}
+	return credentials.Username, credentials.Secret, nil
+}
+
+// parseChallenge parse a WWW-Authenticate header, ie:
+// Bearer realm="https://auth.example.com/token",service="registry"
+func parseChallenge(challenge string) (string, map[string]string) {
+	params := map[string]string{}
+	parts := strings.SplitN(strings.TrimSpace(challenge), " ", 2)
+	scheme := strings.ToLower(parts[0])
+	if len(parts) == 1 {
+		return scheme, params
+	}
+
+	rest := parts[1]
+	for rest != "" {
+		i := strings.Index(rest, "=")
+		if i < 0 {
+			break
+		}
+		key := strings.TrimSpace(rest[:i])
+		rest = rest[i+1:]
+
+		var value string
+		if strings.HasPrefix(rest, `"`) {
+			end := strings.Index(rest[1:], `"`)
+			if end < 0 {
+				value, rest = rest[1:], ""
+			} else {
+				value, rest = rest[1:end+1], rest[end+2:]
+			}
+		} else {
+			end := strings.Index(rest, ",")
+			if end < 0 {
+				end = len(rest)
+			}
+			value, rest = rest[:end], rest[end:]
+		}
+		params[strings.ToLower(key)] = value
+		rest = strings.TrimLeft(rest, ", ")
+	}
+	return scheme, params
+}
+
+func basicAuth(username, password string) string {
+	return base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+}
@@ -0,0 +1,245 @@
+package helm
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// chartArchive return a gzipped tar archive of a chart
+func chartArchive(t *testing.T, name, version string) []byte {
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	tw := tar.NewWriter(gz)
+	for filename, content := range map[string]string{
+		"Chart.yaml":        fmt.Sprintf("apiVersion: v2\nname: %s\nversion: %s\n", name, version),
+		"templates/cm.yaml": "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: app\n",
+	} {
+		err := tw.WriteHeader(&tar.Header{Name: name + "/" + filename, Mode: 0644, Size: int64(len(content))})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+	return b.Bytes()
+}
+
+// newRegistry start an OCI registry serving the charts/app repository with
+// the given tags, it requires a bearer token obtained with user:secret
+func newRegistry(t *testing.T, tags []string) *httptest.Server {
+	manifests := map[string][]byte{}
+	blobs := map[string][]byte{}
+	for _, tag := range tags {
+		archive := chartArchive(t, "app", strings.Replace(tag, "_", "+", -1))
+		sum := sha256.Sum256(archive)
+		digest := "sha256:" + hex.EncodeToString(sum[:])
+		blobs[digest] = archive
+		manifests[tag], _ = json.Marshal(map[string]interface{}{
+			"schemaVersion": 2,
+			"mediaType":     ociManifestMediaType,
+			"layers": []interface{}{
+				map[string]interface{}{"mediaType": ociChartMediaType, "digest": digest, "size": len(archive)},
+			},
+		})
+	}
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if username, password, ok := r.BasicAuth(); !ok || username != "user" || password != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.URL.Query().Get("scope") != "repository:charts/app:pull" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			fmt.Fprint(w, `{"token": "t0k3n"}`)
+			return
+		}
+
+		if r.Header.Get("Authorization") != "Bearer t0k3n" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(
+				`Bearer realm="%s/token",service="registry",scope="repository:charts/app:pull"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		p := strings.TrimPrefix(r.URL.Path, "/v2/charts/app/")
+		switch {
+		case p == "tags/list":
+			json.NewEncoder(w).Encode(map[string]interface{}{"name": "charts/app", "tags": tags})
+		case strings.HasPrefix(p, "manifests/") && manifests[strings.TrimPrefix(p, "manifests/")] != nil:
+			w.Write(manifests[strings.TrimPrefix(p, "manifests/")])
+		case strings.HasPrefix(p, "blobs/") && blobs[strings.TrimPrefix(p, "blobs/")] != nil:
+			w.Write(blobs[strings.TrimPrefix(p, "blobs/")])
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return server
+}
+
+func TestRegistryClientPull(t *testing.T) {
+	server := newRegistry(t, []string{"1.2.3", "1.2.4_build.1", "1.10.0", "2.0.0-rc.1"})
+	defer server.Close()
+	registry := strings.TrimPrefix(server.URL, "http://")
+
+	dir, err := ioutil.TempDir("", "helm-convert-oci")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	dockerConfig := filepath.Join(dir, "config.json")
+	err = ioutil.WriteFile(dockerConfig, []byte(fmt.Sprintf(`{"auths": {"https://%s": {"auth": "%s"}}}`,
+		registry, base64.StdEncoding.EncodeToString([]byte("user:secret")))), 0644)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// a credential helper knowing the credentials of the registry only
+	helper := fmt.Sprintf(`#!/bin/sh
+read server
+if [ "$server" = "%s" ]; then
+  echo '{"ServerURL": "%s", "Username": "user", "Secret": "secret"}'
+else
+  echo "credentials not found in native keychain"
+  exit 1
+fi
+`, registry, registry)
+	if err := ioutil.WriteFile(filepath.Join(dir, "docker-credential-test"), []byte(helper), 0755); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer os.Setenv("PATH", os.Getenv("PATH"))
+	os.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	configs := map[string]string{
+		"creds-store.json":   `{"auths": {"` + registry + `": {}}, "credsStore": "test"}`,
+		"cred-helpers.json":  `{"credsStore": "missing", "credHelpers": {"` + registry + `": "test"}}`,
+		"missing-store.json": `{"credsStore": "missing"}`,
+	}
+	for name, config := range configs {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(config), 0644); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	for _, test := range []struct {
+		name            string
+		reference       string
+		version         string
+		username        string
+		password        string
+		dockerConfigs   []string
+		expectedVersion string
+		expectedError   string
+	}{
+		{
+			name:            "it should pull the requested version",
+			reference:       "oci://" + registry + "/charts/app",
+			version:         "1.2.3",
+			username:        "user",
+			password:        "secret",
+			expectedVersion: "1.2.3",
+		},
+		{
+			name:            "it should pull the latest stable version",
+			reference:       "oci://" + registry + "/charts/app",
+			username:        "user",
+			password:        "secret",
+			expectedVersion: "1.10.0",
+		},
+		{
+			name:            "it should pull the highest version matching a constraint",
+			reference:       "oci://" + registry + "/charts/app",
+			version:         "~1.2",
+			username:        "user",
+			password:        "secret",
+			expectedVersion: "1.2.4+build.1",
+		},
+		{
+			name:            "it should pull the tag of the reference with the credentials of the docker config",
+			reference:       "oci://" + registry + "/charts/app:1.2.3",
+			dockerConfigs:   []string{filepath.Join(dir, "missing.json"), dockerConfig},
+			expectedVersion: "1.2.3",
+		},
+		{
+			name:            "it should pull with the credentials of the credential store",
+			reference:       "oci://" + registry + "/charts/app:1.2.3",
+			dockerConfigs:   []string{filepath.Join(dir, "creds-store.json")},
+			expectedVersion: "1.2.3",
+		},
+		{
+			name:            "it should pull with the credentials of the credential helper of the registry",
+			reference:       "oci://" + registry + "/charts/app:1.2.3",
+			dockerConfigs:   []string{filepath.Join(dir, "cred-helpers.json")},
+			expectedVersion: "1.2.3",
+		},
+		{
+			name:          "it should fail if the credential helper can't be run",
+			reference:     "oci://" + registry + "/charts/app:1.2.3",
+			dockerConfigs: []string{filepath.Join(dir, "missing-store.json")},
+			expectedError: "docker-credential-missing",
+		},
+		{
+			name:          "it should fail with invalid credentials",
+			reference:     "oci://" + registry + "/charts/app",
+			version:       "1.2.3",
+			username:      "user",
+			password:      "wrong",
+			expectedError: "failed to authenticate to registry",
+		},
+		{
+			name:          "it should fail if the version doesn't exist",
+			reference:     "oci://" + registry + "/charts/app",
+			version:       "3.0.0",
+			username:      "user",
+			password:      "secret",
+			expectedError: "404 Not Found",
+		},
+	} {
+		t.Run(fmt.Sprintf("%s", test.name), func(t *testing.T) {
+			client, err := NewRegistryClient(test.username, test.password, "", "", "")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			client.PlainHTTP = true
+			client.DockerConfigs = test.dockerConfigs
+
+			filename, err := client.Pull(test.reference, test.version, dir)
+			if test.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedError) {
+					t.Fatalf("%s: expected error %q, got %v", test.name, test.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			c, err := LoadChartPath(filename)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if c.Metadata.Name != "app" || c.Metadata.Version != test.expectedVersion {
+				t.Errorf("%s: got chart %s %s, want app %s", test.name, c.Metadata.Name, c.Metadata.Version,
+					test.expectedVersion)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package helm
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Masterminds/semver"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/engine"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/renderutil"
+	tversion "k8s.io/helm/pkg/version"
+)
+
+// renderOptions are the options of render
+type renderOptions struct {
+	ReleaseOptions chartutil.ReleaseOptions
+
+	// KubeVersion is the Kubernetes version, empty for the default of the
+	// chart API version
+	KubeVersion string
+
+	// ClusterAPIVersions are the API versions served by the cluster, they
+	// replace the default API versions if given
+	ClusterAPIVersions []string
+
+	// APIVersions are added to the default API versions of the chart API
+	// version
+	APIVersions []string
+
+	// ValuesSources are the values files and flags given by the user, used to
+	// report which one introduced an invalid value
+	ValuesSources []valuesSource
+
+	// SkipSchemaValidation disable the validation of the values against the
+	// values.schema.json files
+	SkipSchemaValidation bool
+
+	// LookupFixtures are the objects the lookup function of the charts
+	// resolves against, nil to return empty objects
+	LookupFixtures *LookupFixtures
+}
+
+// render the chart templates like renderutil.Render. Helm 3 charts are
+// rendered with the Helm 3 capabilities, release service and template
+// functions, the CRDs of their crds directories are added to the templates.
+func render(c *chart.Chart, config *chart.Config, opts renderOptions) (map[string]string, error) {
+	if req, err := chartutil.LoadRequirements(c); err == nil {
+		if err := renderutil.CheckDependencies(c, req); err != nil {
+			return nil, err
+		}
+	} else if err != chartutil.ErrRequirementsNotFound {
+		return nil, fmt.Errorf("cannot load requirements: %v", err)
+	}
+
+	err := chartutil.ProcessRequirementsEnabled(c, config)
+	if err != nil {
+		return nil, err
+	}
+	err = chartutil.ProcessRequirementsImportValues(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if !opts.SkipSchemaValidation {
+		if err := validateValues(c, config, opts.ValuesSources); err != nil {
+			return nil, err
+		}
+	}
+
+	v3 := c.Metadata.ApiVersion == APIVersionV2
+
+	caps, err := capabilities(opts.KubeVersion, opts.ClusterAPIVersions, opts.APIVersions, v3)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkKubeVersion(c, caps); err != nil {
+		return nil, err
+	}
+
+	vals, err := chartutil.ToRenderValuesCaps(c, config, opts.ReleaseOptions, caps)
+	if err != nil {
+		return nil, err
+	}
+
+	// Helm 3 renders the charts of both API versions with lookup
+	renderer := engine.New()
+	renderer.FuncMap["lookup"] = lookupFunc(opts.LookupFixtures)
+	if v3 {
+		vals["Capabilities"] = newCapabilitiesV3(caps)
+		if release, ok := vals["Release"].(map[string]interface{}); ok {
+			release["Service"] = "Helm"
+		}
+		for name, f := range funcMapV3() {
+			renderer.FuncMap[name] = f
+		}
+	}
+
+	rendered, err := renderer.Render(c, vals)
+	if err != nil {
+		return nil, err
+	}
+
+	if v3 {
+		for name, content := range crdsV3(c, "") {
+			rendered[name] = content
+		}
+	}
+	return rendered, nil
+}
+
+// capabilities return the capabilities of the Kubernetes version, the API
+// versions are added to the ones served by the cluster if they are given, by
+// the Kubernetes version if it is given, to the default ones of the Helm
+// version otherwise
+func capabilities(kubeVersion string, clusterAPIVersions, apiVersions []string, v3 bool) (*chartutil.Capabilities, error) {
+	defaultAPIVersions := []string{"v1"}
+	given := kubeVersion != ""
+	if v3 {
+		defaultAPIVersions = defaultAPIVersionsV3
+	}
+	switch {
+	case given:
+	case v3:
+		kubeVersion = defaultKubeVersionV3
+	default:
+		kubeVersion = defaultKubeVersion
+	}
+
+	// the default kube version is copied, renderutil.Render modifies it
+	kv := *chartutil.DefaultKubeVersion
+	v, err := semver.NewVersion(kubeVersion)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse a kubernetes version: %s", kubeVersion)
+	}
+	kv.Major = fmt.Sprint(v.Major())
+	kv.Minor = fmt.Sprint(v.Minor())
+	kv.GitVersion = fmt.Sprintf("v%d.%d.%d", v.Major(), v.Minor(), v.Patch())
+
+	// charts gating API changes on .Capabilities.APIVersions render
+	// differently for each Kubernetes version
+	switch {
+	case len(clusterAPIVersions) > 0:
+		// the core group is missing from the discovery of /apis
+		defaultAPIVersions = append([]string{"v1"}, clusterAPIVersions...)
+	case given && v.Major() == 1:
+		defaultAPIVersions = kubeAPIVersions(int(v.Minor()))
+	}
+
+	return &chartutil.Capabilities{
+		APIVersions:   chartutil.NewVersionSet(append(append([]string{}, defaultAPIVersions...), apiVersions...)...),
+		KubeVersion:   &kv,
+		TillerVersion: tversion.GetVersionProto(),
+	}, nil
+}
+
+// checkKubeVersion return an error if the Kubernetes version doesn't match
+// the kubeVersion constraint of the chart
+func checkKubeVersion(c *chart.Chart, caps *chartutil.Capabilities) error {
+	constraint := strings.TrimSpace(c.Metadata.KubeVersion)
+	if constraint == "" {
+		return nil
+	}
+	constraints, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return fmt.Errorf("invalid kubeVersion %q in chart %s: %v", constraint, c.Metadata.Name, err)
+	}
+
+	v, err := semver.NewVersion(caps.KubeVersion.GitVersion)
+	if err != nil {
+		return err
+	}
+	if !constraints.Check(v) {
+		return fmt.Errorf("chart %s requires kubeVersion: %s which is incompatible with Kubernetes %s",
+			c.Metadata.Name, constraint, caps.KubeVersion.GitVersion)
+	}
+	return nil
+}
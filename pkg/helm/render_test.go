@@ -0,0 +1,111 @@
+package helm
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCapabilities(t *testing.T) {
+	for _, test := range []struct {
+		name                string
+		kubeVersion         string
+		clusterAPIVersions  []string
+		apiVersions         []string
+		v3                  bool
+		expectedGitVersion  string
+		expectedAPIVersions map[string]bool
+	}{
+		{
+			name:               "it should default to the Helm 2 Kubernetes version",
+			expectedGitVersion: "v" + defaultKubeVersion + ".0",
+			expectedAPIVersions: map[string]bool{
+				"v1":                   true,
+				"networking.k8s.io/v1": false,
+			},
+		},
+		{
+			name:               "it should default to the Helm 3 Kubernetes version of Helm 3 charts",
+			v3:                 true,
+			expectedGitVersion: "v" + defaultKubeVersionV3 + ".0",
+			expectedAPIVersions: map[string]bool{
+				"networking.k8s.io/v1":         true,
+				"networking.k8s.io/v1/Ingress": true,
+			},
+		},
+		{
+			name:               "it should serve the API versions of the Kubernetes version",
+			kubeVersion:        "1.16",
+			expectedGitVersion: "v1.16.0",
+			expectedAPIVersions: map[string]bool{
+				"extensions/v1beta1/Ingress":   true,
+				"networking.k8s.io/v1/Ingress": false,
+				"policy/v1beta1":               true,
+			},
+		},
+		{
+			name:               "it should serve the API versions of the Kubernetes version of Helm 3 charts",
+			kubeVersion:        "1.28",
+			v3:                 true,
+			expectedGitVersion: "v1.28.0",
+			expectedAPIVersions: map[string]bool{
+				"extensions/v1beta1/Ingress":   false,
+				"networking.k8s.io/v1/Ingress": true,
+				"policy/v1beta1":               false,
+			},
+		},
+		{
+			name:               "it should replace the defaults by the API versions of the cluster",
+			kubeVersion:        "1.25",
+			clusterAPIVersions: []string{"apps/v1", "autoscaling/v2beta2"},
+			apiVersions:        []string{"monitoring.coreos.com/v1"},
+			v3:                 true,
+			expectedGitVersion: "v1.25.0",
+			expectedAPIVersions: map[string]bool{
+				"v1":                       true,
+				"apps/v1":                  true,
+				"autoscaling/v2beta2":      true,
+				"monitoring.coreos.com/v1": true,
+				"autoscaling/v2":           false,
+				"networking.k8s.io/v1":     false,
+			},
+		},
+		{
+			name:               "it should keep the given Kubernetes version of Helm 3 charts",
+			kubeVersion:        defaultKubeVersion,
+			v3:                 true,
+			expectedGitVersion: "v" + defaultKubeVersion + ".0",
+			expectedAPIVersions: map[string]bool{
+				"extensions/v1beta1/Ingress":   true,
+				"networking.k8s.io/v1/Ingress": false,
+			},
+		},
+		{
+			name:               "it should add the API versions to the defaults",
+			kubeVersion:        "v1.25.3",
+			apiVersions:        []string{"networking.k8s.io/v1", "monitoring.coreos.com/v1/ServiceMonitor"},
+			expectedGitVersion: "v1.25.3",
+			expectedAPIVersions: map[string]bool{
+				"v1":                   true,
+				"networking.k8s.io/v1": true,
+				"monitoring.coreos.com/v1/ServiceMonitor": true,
+				"extensions/v1beta1":                      false,
+			},
+		},
+	} {
+		t.Run(fmt.Sprintf("%s", test.name), func(t *testing.T) {
+			caps, err := capabilities(test.kubeVersion, test.clusterAPIVersions, test.apiVersions, test.v3)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if caps.KubeVersion.GitVersion != test.expectedGitVersion {
+				t.Errorf("%s: got version %s, want %s", test.name, caps.KubeVersion.GitVersion, test.expectedGitVersion)
+			}
+			for version, expected := range test.expectedAPIVersions {
+				if caps.APIVersions.Has(version) != expected {
+					t.Errorf("%s: expected %s to be %v", test.name, version, expected)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package helm
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/xeipuuv/gojsonschema"
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+)
+
+// SchemaFilename is the JSON Schema of the values of a chart
+const SchemaFilename = "values.schema.json"
+
+// valuesSource is a values file or a --set flag given by the user, used to
+// report which one introduced an invalid value
+type valuesSource struct {
+	// Name of the source, ie: values.yaml or --set image.tag=1.0
+	Name string
+
+	// Values set by the source alone
+	Values map[string]interface{}
+}
+
+// schemaError is a value which doesn't match the schema of a chart
+type schemaError struct {
+	chart       string
+	path        string
+	description string
+	source      string
+}
+
+// validateValues validate the coalesced values of a chart and its enabled
+// subcharts against their values.schema.json. Each error gives the JSON path
+// of the value and the source which set it, the last one setting the value
+// or its closest parent.
+func validateValues(c *chart.Chart, config *chart.Config, sources []valuesSource) error {
+	values, err := chartutil.CoalesceValues(c, config)
+	if err != nil {
+		return err
+	}
+
+	// the default values of the charts have a lower priority than the ones
+	// given by the user
+	sources = append(defaultSources(c, c.Metadata.Name, nil), sources...)
+
+	var errs []schemaError
+	err = validateChart(c, c.Metadata.Name, nil, values, sources, &errs)
+	if err != nil {
+		return err
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+
+	var b bytes.Buffer
+	b.WriteString("values don't meet the specifications of the schema(s) in the following chart(s):")
+	var chartName string
+	for _, e := range errs {
+		if e.chart != chartName {
+			chartName = e.chart
+			fmt.Fprintf(&b, "\n%s:", chartName)
+		}
+		fmt.Fprintf(&b, "\n- %s: %s", e.path, e.description)
+		if e.source != "" {
+			fmt.Fprintf(&b, " (set by %s)", e.source)
+		}
+	}
+	return fmt.Errorf("%s", b.String())
+}
+
+// validateChart validate the values of a chart then the values of its
+// subcharts, prefix is the path of the values of the chart from the root
+func validateChart(c *chart.Chart, chartPath string, prefix []string, values map[string]interface{},
+	sources []valuesSource, errs *[]schemaError) error {
+	for _, f := range c.Files {
+		if f.TypeUrl != SchemaFilename {
+			continue
+		}
+
+		glog.V(4).Infof("Validating the values of chart %s against %s", chartPath, SchemaFilename)
+		result, err := gojsonschema.Validate(gojsonschema.NewBytesLoader(f.Value), gojsonschema.NewGoLoader(values))
+		if err != nil {
+			return fmt.Errorf("invalid %s in chart %s: %v", SchemaFilename, chartPath, err)
+		}
+
+		for _, re := range result.Errors() {
+			fields := contextFields(re.Context().String("."))
+			switch re.Type() {
+			case "required", "additional_property_not_allowed":
+				if property, ok := re.Details()["property"].(string); ok {
+					fields = append(fields, property)
+				}
+			}
+			fields = append(append([]string{}, prefix...), fields...)
+
+			*errs = append(*errs, schemaError{
+				chart:       chartPath,
+				path:        jsonPath(values, prefix, fields),
+				description: re.Description(),
+				source:      sourceOf(sources, fields),
+			})
+		}
+	}
+
+	for _, dep := range c.Dependencies {
+		name := dep.Metadata.Name
+		subvalues, ok := values[name].(map[string]interface{})
+		if !ok {
+			subvalues = map[string]interface{}{}
+		}
+		err := validateChart(dep, path.Join(chartPath, "charts", name), append(append([]string{}, prefix...), name),
+			subvalues, sources, errs)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// defaultSources return the default values of a chart and its subcharts,
+// the subcharts first as their values are overridden by the parent chart
+func defaultSources(c *chart.Chart, chartPath string, prefix []string) []valuesSource {
+	var sources []valuesSource
+	for _, dep := range c.Dependencies {
+		sources = append(sources, defaultSources(dep, path.Join(chartPath, "charts", dep.Metadata.Name),
+			append(append([]string{}, prefix...), dep.Metadata.Name))...)
+	}
+
+	if c.Values == nil {
+		return sources
+	}
+	values, err := chartutil.ReadValues([]byte(c.Values.Raw))
+	if err != nil {
+		return sources
+	}
+	for i := len(prefix) - 1; i >= 0; i-- {
+		values = map[string]interface{}{prefix[i]: map[string]interface{}(values)}
+	}
+	return append(sources, valuesSource{Name: path.Join(chartPath, "values.yaml"), Values: values})
+}
+
+// contextFields split the context of a JSON Schema error, ie: (root).image.tag
+func contextFields(context string) []string {
+	context = strings.TrimPrefix(strings.TrimPrefix(context, "(root)"), ".")
+	if context == "" {
+		return nil
+	}
+	return strings.Split(context, ".")
+}
+
+// jsonPath return the JSON path of a value, ie: $.hosts[0].name, fields
+// indexing a list are written as indexes
+func jsonPath(values map[string]interface{}, prefix []string, fields []string) string {
+	p := "$"
+	var current interface{} = values
+	for i, field := range fields {
+		if i < len(prefix) {
+			p += "." + field
+			continue
+		}
+
+		switch typed := current.(type) {
+		case []interface{}:
+			p += "[" + field + "]"
+			if n, err := strconv.Atoi(field); err == nil && n >= 0 && n < len(typed) {
+				current = typed[n]
+			} else {
+				current = nil
+			}
+		case map[string]interface{}:
+			p += "." + field
+			current = typed[field]
+		default:
+			p += "." + field
+			current = nil
+		}
+	}
+	return p
+}
+
+// sourceOf return the name of the last source setting a value, or its closest
+// parent if no source sets the value itself
+func sourceOf(sources []valuesSource, fields []string) string {
+	for n := len(fields); n > 0; n-- {
+		for i := len(sources) - 1; i >= 0; i-- {
+			if hasValue(sources[i].Values, fields[:n]) {
+				return sources[i].Name
+			}
+		}
+	}
+	return ""
+}
+
+// hasValue return true if the values contain the given path
+func hasValue(values map[string]interface{}, fields []string) bool {
+	var current interface{} = values
+	for _, field := range fields {
+		switch typed := current.(type) {
+		case map[string]interface{}:
+			value, ok := typed[field]
+			if !ok {
+				return false
+			}
+			current = value
+		case chartutil.Values:
+			value, ok := typed[field]
+			if !ok {
+				return false
+			}
+			current = value
+		case []interface{}:
+			n, err := strconv.Atoi(field)
+			if err != nil || n < 0 || n >= len(typed) || typed[n] == nil {
+				return false
+			}
+			current = typed[n]
+		default:
+			return false
+		}
+	}
+	return true
+}
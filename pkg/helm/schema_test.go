@@ -0,0 +1,104 @@
+package helm
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"k8s.io/helm/pkg/chartutil"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"k8s.io/helm/pkg/strvals"
+)
+
+func TestValidateValues(t *testing.T) {
+	for _, test := range []struct {
+		name          string
+		valueFiles    map[string]string
+		values        []string
+		skip          bool
+		expectedError string
+	}{
+		{
+			name: "it should accept valid values",
+			valueFiles: map[string]string{
+				"prod.yaml": "replicas: 3\n",
+			},
+			values: []string{"opt.enabled=true"},
+		},
+		{
+			name: "it should report the file which set an invalid value",
+			valueFiles: map[string]string{
+				"prod.yaml": "replicas: two\n",
+			},
+			expectedError: `values don't meet the specifications of the schema(s) in the following chart(s):
+app:
+- $.replicas: Invalid type. Expected: integer, given: string (set by prod.yaml)`,
+		},
+		{
+			name:   "it should report the flag which set an unknown value",
+			values: []string{"replica=3"},
+			expectedError: `app:
+- $.replica: Additional property replica is not allowed (set by --set replica=3)`,
+		},
+		{
+			name:   "it should validate the values of the enabled subcharts",
+			values: []string{"opt.enabled=true", "opt.port=0"},
+			expectedError: `app/charts/opt:
+- $.opt.port: Must be greater than or equal to 1 (set by --set opt.port=0)`,
+		},
+		{
+			name:   "it should not validate the values of the disabled subcharts",
+			values: []string{"opt.port=0"},
+		},
+		{
+			name: "it should skip the validation",
+			valueFiles: map[string]string{
+				"prod.yaml": "replicas: two\n",
+			},
+			skip: true,
+		},
+	} {
+		t.Run(fmt.Sprintf("%s", test.name), func(t *testing.T) {
+			c, err := LoadChartPath("testdata/helm3")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			base := map[string]interface{}{}
+			var sources []valuesSource
+			for name, raw := range test.valueFiles {
+				values, err := chartutil.ReadValues([]byte(raw))
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				base = mergeValues(base, values)
+				sources = append(sources, valuesSource{Name: name, Values: values})
+			}
+			for _, value := range test.values {
+				if err := strvals.ParseInto(value, base); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				sources = append(sources, flagSource("--set", value, strvals.ParseInto))
+			}
+			raw, err := chartutil.Values(base).YAML()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			_, err = render(c, &chart.Config{Raw: raw}, renderOptions{
+				ReleaseOptions:       chartutil.ReleaseOptions{Name: "web", Namespace: "default"},
+				ValuesSources:        sources,
+				SkipSchemaValidation: test.skip,
+			})
+			if test.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedError) {
+					t.Fatalf("%s: expected error %q, got %v", test.name, test.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,380 @@
+// Package kpt build kpt packages: a Kptfile describing the chart, setters for
+// the images and replicas applied with apply-replacements and a function
+// pipeline reproducing the kustomization config
+package kpt
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ContainerSolutions/helm-convert/pkg/types"
+	"github.com/ContainerSolutions/helm-convert/pkg/utils"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"sigs.k8s.io/kustomize/pkg/resid"
+	ktypes "sigs.k8s.io/kustomize/pkg/types"
+)
+
+const (
+	// KptfileName is the name of the file describing a kpt package
+	KptfileName = "Kptfile"
+
+	// SettersFilename is the name of the file containing the setters
+	SettersFilename = "setters.yaml"
+
+	// ReplacementsFilename is the name of the apply-replacements config
+	ReplacementsFilename = "apply-replacements.yaml"
+
+	// KrmIgnoreFilename list the paths kpt doesn't read resources from
+	KrmIgnoreFilename = ".krmignore"
+
+	// SettersName is the name of the setters ConfigMap
+	SettersName = "setters"
+
+	// LocalConfigAnnotation mark resources which are not applied to the
+	// cluster
+	LocalConfigAnnotation = "config.kubernetes.io/local-config"
+)
+
+// functions used by the pipeline
+const (
+	applyReplacementsFunction   = "gcr.io/kpt-fn/apply-replacements:v0.1.1"
+	setNamespaceFunction        = "gcr.io/kpt-fn/set-namespace:v0.4.1"
+	setLabelsFunction           = "gcr.io/kpt-fn/set-labels:v0.2.0"
+	setAnnotationsFunction      = "gcr.io/kpt-fn/set-annotations:v0.1.4"
+	ensureNameSubstringFunction = "gcr.io/kpt-fn/ensure-name-substring:v0.2.0"
+)
+
+var invalidKeyChars = regexp.MustCompile(`[^-_a-zA-Z0-9]+`)
+
+// Kptfile is the kpt package descriptor
+type Kptfile struct {
+	APIVersion string       `json:"apiVersion"`
+	Kind       string       `json:"kind"`
+	Metadata   Metadata     `json:"metadata"`
+	Upstream   *Upstream    `json:"upstream,omitempty"`
+	Info       *PackageInfo `json:"info,omitempty"`
+	Pipeline   *Pipeline    `json:"pipeline,omitempty"`
+}
+
+// Metadata of a kpt resource
+type Metadata struct {
+	Name        string            `json:"name"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Upstream is the git repository the package is fetched from
+type Upstream struct {
+	Type           string `json:"type"`
+	Git            *Git   `json:"git"`
+	UpdateStrategy string `json:"updateStrategy,omitempty"`
+}
+
+// Git is a directory of a git repository at a given ref
+type Git struct {
+	Repo      string `json:"repo"`
+	Directory string `json:"directory"`
+	Ref       string `json:"ref"`
+}
+
+// PackageInfo describe the package
+type PackageInfo struct {
+	Site        string   `json:"site,omitempty"`
+	Emails      []string `json:"emails,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Keywords    []string `json:"keywords,omitempty"`
+}
+
+// Pipeline is the list of functions run by kpt fn render
+type Pipeline struct {
+	Mutators []Function `json:"mutators,omitempty"`
+}
+
+// Function is a KRM function with its config
+type Function struct {
+	Image      string            `json:"image"`
+	ConfigPath string            `json:"configPath,omitempty"`
+	ConfigMap  map[string]string `json:"configMap,omitempty"`
+}
+
+// ApplyReplacements is the config of the apply-replacements function
+type ApplyReplacements struct {
+	APIVersion   string              `json:"apiVersion"`
+	Kind         string              `json:"kind"`
+	Metadata     Metadata            `json:"metadata"`
+	Replacements []types.Replacement `json:"replacements"`
+}
+
+// Package is a kpt package, the resources are written as is
+type Package struct {
+	Kptfile *Kptfile
+
+	// Setters is the local ConfigMap holding the values of the setters, nil
+	// if there is none
+	Setters map[string]interface{}
+
+	// Replacements copy the setters to the resources, nil if there is none
+	Replacements *ApplyReplacements
+}
+
+// NewPackage build a kpt package from the chart metadata and the
+// kustomization config gathered by the transformers. Images and replicas are
+// exposed as setters, the other fields of the kustomization config are
+// applied by the functions of the pipeline.
+func NewPackage(config *ktypes.Kustomization, metadata *chart.Metadata, resources *types.Resources) (*Package, error) {
+	if len(config.ConfigMapGenerator) > 0 || len(config.SecretGenerator) > 0 {
+		return nil, fmt.Errorf("kpt packages cannot contain configmap or secret generators, skip the configmap and secret transformers")
+	}
+
+	pkg := &Package{
+		Kptfile: newKptfile(metadata),
+	}
+
+	setters, replacements := newSetters(resources)
+	if len(replacements) > 0 {
+		pkg.Setters = map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata":   localConfigMetadata(SettersName),
+			"data":       setters,
+		}
+		pkg.Replacements = &ApplyReplacements{
+			APIVersion:   "fn.kpt.dev/v1alpha1",
+			Kind:         "ApplyReplacements",
+			Metadata:     localConfigMetadata("apply-setters"),
+			Replacements: replacements,
+		}
+	}
+
+	pkg.Kptfile.Pipeline = newPipeline(config, pkg.Replacements != nil)
+
+	return pkg, nil
+}
+
+func newKptfile(metadata *chart.Metadata) *Kptfile {
+	kptfile := &Kptfile{
+		APIVersion: "kpt.dev/v1",
+		Kind:       "Kptfile",
+		Metadata: Metadata{
+			Name: metadata.Name,
+			Annotations: map[string]string{
+				LocalConfigAnnotation: "true",
+				"helm-convert/chart":  metadata.Name,
+			},
+		},
+	}
+	if metadata.Version != "" {
+		kptfile.Metadata.Annotations["helm-convert/chart-version"] = metadata.Version
+	}
+	if metadata.AppVersion != "" {
+		kptfile.Metadata.Annotations["helm-convert/app-version"] = metadata.AppVersion
+	}
+
+	// the sources of a chart are usually the git repository of the chart,
+	// the chart version is used as ref
+	for _, source := range metadata.Sources {
+		if strings.HasSuffix(source, ".git") || strings.HasPrefix(source, "https://github.com/") {
+			kptfile.Upstream = &Upstream{
+				Type:           "git",
+				Git:            &Git{Repo: source, Directory: "/", Ref: metadata.Version},
+				UpdateStrategy: "resource-merge",
+			}
+			break
+		}
+	}
+
+	info := &PackageInfo{
+		Site:        metadata.Home,
+		Description: metadata.Description,
+		Keywords:    metadata.Keywords,
+	}
+	for _, maintainer := range metadata.Maintainers {
+		if maintainer.Email != "" {
+			info.Emails = append(info.Emails, maintainer.Email)
+		}
+	}
+	if info.Site != "" || info.Description != "" || len(info.Keywords) > 0 || len(info.Emails) > 0 {
+		kptfile.Info = info
+	}
+
+	return kptfile
+}
+
+// newPipeline return the functions applying the namespace, common labels,
+// common annotations and name prefix and suffix of the kustomization config
+func newPipeline(config *ktypes.Kustomization, setters bool) *Pipeline {
+	pipeline := &Pipeline{}
+
+	if setters {
+		pipeline.Mutators = append(pipeline.Mutators, Function{
+			Image:      applyReplacementsFunction,
+			ConfigPath: ReplacementsFilename,
+		})
+	}
+	if config.Namespace != "" {
+		pipeline.Mutators = append(pipeline.Mutators, Function{
+			Image:     setNamespaceFunction,
+			ConfigMap: map[string]string{"namespace": config.Namespace},
+		})
+	}
+	if len(config.CommonLabels) > 0 {
+		pipeline.Mutators = append(pipeline.Mutators, Function{
+			Image:     setLabelsFunction,
+			ConfigMap: config.CommonLabels,
+		})
+	}
+	if len(config.CommonAnnotations) > 0 {
+		pipeline.Mutators = append(pipeline.Mutators, Function{
+			Image:     setAnnotationsFunction,
+			ConfigMap: config.CommonAnnotations,
+		})
+	}
+	if config.NamePrefix != "" || config.NameSuffix != "" {
+		substring := map[string]string{}
+		if config.NamePrefix != "" {
+			substring["prepend"] = config.NamePrefix
+		}
+		if config.NameSuffix != "" {
+			substring["append"] = config.NameSuffix
+		}
+		pipeline.Mutators = append(pipeline.Mutators, Function{
+			Image:     ensureNameSubstringFunction,
+			ConfigMap: substring,
+		})
+	}
+
+	if len(pipeline.Mutators) == 0 {
+		return nil
+	}
+	return pipeline
+}
+
+// newSetters return the setters of the container images and replicas of the
+// resources and the replacements copying them to the resources
+func newSetters(resources *types.Resources) (map[string]string, []types.Replacement) {
+	setters := make(map[string]string)
+	var replacements []types.Replacement
+
+	// setters are shared by all the containers using the same image
+	images := make(map[string]int)
+
+	for _, id := range utils.SortedResIds(resources.ResMap) {
+		obj := resources.ResMap[id].Map()
+
+		if spec, ok := obj["spec"].(map[string]interface{}); ok {
+			if replicas, ok := spec["replicas"]; ok {
+				key := uniqueKey(setters, id.Name()+"-replicas")
+				setters[key] = fmt.Sprint(replicas)
+				replacements = append(replacements, newReplacement(key, id, "spec.replicas"))
+			}
+		}
+
+		for _, container := range findContainers(obj, nil) {
+			if i, ok := images[container.image]; ok {
+				replacements[i].Targets = append(replacements[i].Targets, replacementTarget(id, container.path))
+				continue
+			}
+
+			name := container.image
+			if i := strings.LastIndex(name, "/"); i >= 0 {
+				name = name[i+1:]
+			}
+			name = strings.SplitN(strings.SplitN(name, "@", 2)[0], ":", 2)[0]
+
+			key := uniqueKey(setters, name+"-image")
+			setters[key] = container.image
+			images[container.image] = len(replacements)
+			replacements = append(replacements, newReplacement(key, id, container.path))
+		}
+	}
+
+	return setters, replacements
+}
+
+type container struct {
+	image string
+	path  string
+}
+
+// findContainers return the image and field path of the image of the
+// containers and init containers of a resource
+func findContainers(obj map[string]interface{}, path []string) []container {
+	var containers []container
+	for _, key := range sortedKeys(obj) {
+		switch typed := obj[key].(type) {
+		case map[string]interface{}:
+			containers = append(containers, findContainers(typed, append(append([]string{}, path...), key))...)
+		case []interface{}:
+			if key != "containers" && key != "initContainers" {
+				continue
+			}
+			for _, item := range typed {
+				c, ok := item.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				name, _ := c["name"].(string)
+				image, _ := c["image"].(string)
+				if name == "" || image == "" {
+					continue
+				}
+				p := append(append([]string{}, path...), key, "[name="+name+"]", "image")
+				containers = append(containers, container{image: image, path: strings.Join(p, ".")})
+			}
+		}
+	}
+	return containers
+}
+
+func newReplacement(key string, id resid.ResId, fieldPath string) types.Replacement {
+	return types.Replacement{
+		Source: &types.ReplacementSource{
+			Kind:      "ConfigMap",
+			Name:      SettersName,
+			FieldPath: "data." + key,
+		},
+		Targets: []types.ReplacementTarget{replacementTarget(id, fieldPath)},
+	}
+}
+
+func replacementTarget(id resid.ResId, fieldPath string) types.ReplacementTarget {
+	return types.ReplacementTarget{
+		Select: &types.PatchTarget{
+			Group:     id.Gvk().Group,
+			Version:   id.Gvk().Version,
+			Kind:      id.Gvk().Kind,
+			Name:      id.Name(),
+			Namespace: id.Namespace(),
+		},
+		FieldPaths: []string{fieldPath},
+	}
+}
+
+// uniqueKey return a valid ConfigMap key which is not already used
+func uniqueKey(setters map[string]string, key string) string {
+	key = strings.Trim(invalidKeyChars.ReplaceAllString(key, "-"), "-")
+	unique := key
+	for i := 2; ; i++ {
+		if _, ok := setters[unique]; !ok {
+			return unique
+		}
+		unique = fmt.Sprintf("%s-%d", key, i)
+	}
+}
+
+func localConfigMetadata(name string) Metadata {
+	return Metadata{
+		Name:        name,
+		Annotations: map[string]string{LocalConfigAnnotation: "true"},
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
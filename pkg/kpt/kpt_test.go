@@ -0,0 +1,145 @@
+package kpt
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ContainerSolutions/helm-convert/pkg/types"
+	"github.com/kylelemons/godebug/pretty"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	"sigs.k8s.io/kustomize/k8sdeps/kunstruct"
+	"sigs.k8s.io/kustomize/pkg/gvk"
+	"sigs.k8s.io/kustomize/pkg/resid"
+	"sigs.k8s.io/kustomize/pkg/resmap"
+	"sigs.k8s.io/kustomize/pkg/resource"
+	ktypes "sigs.k8s.io/kustomize/pkg/types"
+)
+
+func TestNewPackage(t *testing.T) {
+	var rf = resource.NewFactory(kunstruct.NewKunstructuredFactoryImpl())
+	var deploy = gvk.Gvk{Group: "apps", Version: "v1", Kind: "Deployment"}
+	var target = &types.PatchTarget{Group: "apps", Version: "v1", Kind: "Deployment", Name: "web"}
+
+	newDeployment := func(name string) *resource.Resource {
+		return rf.FromMap(map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata":   map[string]interface{}{"name": name},
+			"spec": map[string]interface{}{
+				"replicas": 2,
+				"template": map[string]interface{}{
+					"spec": map[string]interface{}{
+						"containers": []interface{}{
+							map[string]interface{}{"name": "web", "image": "registry.example.com/nginx:1.15"},
+						},
+					},
+				},
+			},
+		})
+	}
+
+	metadata := &chart.Metadata{
+		Name:        "web",
+		Version:     "1.2.0",
+		Description: "Web server",
+		Sources:     []string{"https://github.com/example/charts"},
+		Maintainers: []*chart.Maintainer{{Name: "ops", Email: "ops@example.com"}},
+	}
+
+	for _, test := range []struct {
+		name      string
+		config    *ktypes.Kustomization
+		resources *types.Resources
+		expected  *Package
+	}{
+		{
+			name:      "it should describe the chart in the Kptfile",
+			config:    &ktypes.Kustomization{},
+			resources: &types.Resources{ResMap: resmap.ResMap{}},
+			expected: &Package{
+				Kptfile: &Kptfile{
+					APIVersion: "kpt.dev/v1",
+					Kind:       "Kptfile",
+					Metadata: Metadata{
+						Name: "web",
+						Annotations: map[string]string{
+							LocalConfigAnnotation:        "true",
+							"helm-convert/chart":         "web",
+							"helm-convert/chart-version": "1.2.0",
+						},
+					},
+					Upstream: &Upstream{
+						Type:           "git",
+						Git:            &Git{Repo: "https://github.com/example/charts", Directory: "/", Ref: "1.2.0"},
+						UpdateStrategy: "resource-merge",
+					},
+					Info: &PackageInfo{Description: "Web server", Emails: []string{"ops@example.com"}},
+				},
+			},
+		},
+		{
+			name:   "it should expose images and replicas as setters and build the pipeline",
+			config: &ktypes.Kustomization{Namespace: "web", CommonLabels: map[string]string{"app": "web"}},
+			resources: &types.Resources{
+				ResMap: resmap.ResMap{
+					resid.NewResId(deploy, "web"): newDeployment("web"),
+				},
+			},
+			expected: &Package{
+				Setters: map[string]interface{}{
+					"apiVersion": "v1",
+					"kind":       "ConfigMap",
+					"metadata":   localConfigMetadata(SettersName),
+					"data": map[string]string{
+						"web-replicas": "2",
+						"nginx-image":  "registry.example.com/nginx:1.15",
+					},
+				},
+				Replacements: &ApplyReplacements{
+					APIVersion: "fn.kpt.dev/v1alpha1",
+					Kind:       "ApplyReplacements",
+					Metadata:   localConfigMetadata("apply-setters"),
+					Replacements: []types.Replacement{
+						{
+							Source: &types.ReplacementSource{Kind: "ConfigMap", Name: SettersName, FieldPath: "data.web-replicas"},
+							Targets: []types.ReplacementTarget{
+								{Select: target, FieldPaths: []string{"spec.replicas"}},
+							},
+						},
+						{
+							Source: &types.ReplacementSource{Kind: "ConfigMap", Name: SettersName, FieldPath: "data.nginx-image"},
+							Targets: []types.ReplacementTarget{
+								{Select: target, FieldPaths: []string{"spec.template.spec.containers.[name=web].image"}},
+							},
+						},
+					},
+				},
+				Kptfile: &Kptfile{
+					Pipeline: &Pipeline{
+						Mutators: []Function{
+							{Image: applyReplacementsFunction, ConfigPath: ReplacementsFilename},
+							{Image: setNamespaceFunction, ConfigMap: map[string]string{"namespace": "web"}},
+							{Image: setLabelsFunction, ConfigMap: map[string]string{"app": "web"}},
+						},
+					},
+				},
+			},
+		},
+	} {
+		t.Run(fmt.Sprintf("%s", test.name), func(t *testing.T) {
+			pkg, err := NewPackage(test.config, metadata, test.resources)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			// only compare the pipeline when the Kptfile is not expected
+			if test.expected.Kptfile.APIVersion == "" {
+				pkg.Kptfile = &Kptfile{Pipeline: pkg.Kptfile.Pipeline}
+			}
+
+			if diff := pretty.Compare(pkg, test.expected); diff != "" {
+				t.Errorf("%s, diff: (-got +want)\n%s", test.name, diff)
+			}
+		})
+	}
+}
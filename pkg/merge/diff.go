@@ -0,0 +1,105 @@
+package merge
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Operation is a JSON patch (RFC 6902) operation
+type Operation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value,omitempty"`
+}
+
+// MarshalJSON always write the value of the operations which require one,
+// even if it is null, false, 0 or empty
+func (o Operation) MarshalJSON() ([]byte, error) {
+	switch o.Op {
+	case "add", "replace", "test":
+		return json.Marshal(struct {
+			Op    string      `json:"op"`
+			Path  string      `json:"path"`
+			Value interface{} `json:"value"`
+		}{o.Op, o.Path, o.Value})
+	}
+
+	type operation Operation
+	return json.Marshal(operation(o))
+}
+
+// Diff return the JSON patch operations transforming from into to. Maps are
+// compared key by key, lists item by item when they have the same length or
+// when items are only appended, otherwise they are replaced as a whole.
+func Diff(from, to interface{}) []Operation {
+	return diff("", from, to, nil)
+}
+
+func diff(path string, from, to interface{}, ops []Operation) []Operation {
+	if reflect.DeepEqual(from, to) {
+		return ops
+	}
+
+	switch typedFrom := from.(type) {
+	case map[string]interface{}:
+		typedTo, ok := to.(map[string]interface{})
+		if !ok {
+			break
+		}
+
+		for _, key := range sortedKeys(typedFrom) {
+			if _, ok := typedTo[key]; !ok {
+				ops = append(ops, Operation{Op: "remove", Path: path + "/" + escapePointer(key)})
+			}
+		}
+		for _, key := range sortedKeys(typedTo) {
+			keyPath := path + "/" + escapePointer(key)
+			if value, ok := typedFrom[key]; ok {
+				ops = diff(keyPath, value, typedTo[key], ops)
+			} else {
+				ops = append(ops, Operation{Op: "add", Path: keyPath, Value: typedTo[key]})
+			}
+		}
+		return ops
+
+	case []interface{}:
+		typedTo, ok := to.([]interface{})
+		if !ok || len(typedTo) < len(typedFrom) {
+			break
+		}
+
+		if len(typedTo) > len(typedFrom) && !reflect.DeepEqual(typedFrom, typedTo[:len(typedFrom)]) {
+			break
+		}
+
+		for i := range typedFrom {
+			ops = diff(path+"/"+strconv.Itoa(i), typedFrom[i], typedTo[i], ops)
+		}
+		for _, item := range typedTo[len(typedFrom):] {
+			ops = append(ops, Operation{Op: "add", Path: path + "/-", Value: item})
+		}
+		return ops
+	}
+
+	if path == "" {
+		path = "/"
+	}
+	return append(ops, Operation{Op: "replace", Path: path, Value: to})
+}
+
+// escapePointer escape a JSON pointer token
+func escapePointer(token string) string {
+	return strings.Replace(strings.Replace(token, "~", "~0", -1), "/", "~1", -1)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
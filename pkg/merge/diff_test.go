@@ -0,0 +1,116 @@
+package merge
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ghodss/yaml"
+	"github.com/kylelemons/godebug/pretty"
+)
+
+func TestDiff(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		from     interface{}
+		to       interface{}
+		expected []Operation
+	}{
+		{
+			name:     "it should return no operation for identical documents",
+			from:     map[string]interface{}{"a": "b"},
+			to:       map[string]interface{}{"a": "b"},
+			expected: nil,
+		},
+		{
+			name: "it should add, remove and replace map keys",
+			from: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]interface{}{"old": "1", "example.com/key": "a"},
+				},
+				"spec": map[string]interface{}{"replicas": 1},
+			},
+			to: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"annotations": map[string]interface{}{"example.com/key": "b"},
+				},
+				"spec": map[string]interface{}{"replicas": 2, "paused": true},
+			},
+			expected: []Operation{
+				{Op: "remove", Path: "/metadata/annotations/old"},
+				{Op: "replace", Path: "/metadata/annotations/example.com~1key", Value: "b"},
+				{Op: "add", Path: "/spec/paused", Value: true},
+				{Op: "replace", Path: "/spec/replicas", Value: 2},
+			},
+		},
+		{
+			name: "it should append list items",
+			from: map[string]interface{}{
+				"args": []interface{}{"--a"},
+			},
+			to: map[string]interface{}{
+				"args": []interface{}{"--a", "--b"},
+			},
+			expected: []Operation{
+				{Op: "add", Path: "/args/-", Value: "--b"},
+			},
+		},
+		{
+			name: "it should replace lists which cannot be patched item by item",
+			from: map[string]interface{}{
+				"args": []interface{}{"--a", "--b"},
+			},
+			to: map[string]interface{}{
+				"args": []interface{}{"--b"},
+			},
+			expected: []Operation{
+				{Op: "replace", Path: "/args", Value: []interface{}{"--b"}},
+			},
+		},
+	} {
+		t.Run(fmt.Sprintf("%s", test.name), func(t *testing.T) {
+			output := Diff(test.from, test.to)
+			if diff := pretty.Compare(output, test.expected); diff != "" {
+				t.Errorf("%s, diff: (-got +want)\n%s", test.name, diff)
+			}
+		})
+	}
+}
+
+func TestMarshalOperation(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		operation Operation
+		expected  string
+	}{
+		{
+			name:      "it should write a null value",
+			operation: Operation{Op: "add", Path: "/spec/selector"},
+			expected:  "op: add\npath: /spec/selector\nvalue: null\n",
+		},
+		{
+			name:      "it should write a false value",
+			operation: Operation{Op: "replace", Path: "/spec/paused", Value: false},
+			expected:  "op: replace\npath: /spec/paused\nvalue: false\n",
+		},
+		{
+			name:      "it should write an empty value",
+			operation: Operation{Op: "test", Path: "/metadata/name", Value: ""},
+			expected:  "op: test\npath: /metadata/name\nvalue: \"\"\n",
+		},
+		{
+			name:      "it should not write the value of a remove",
+			operation: Operation{Op: "remove", Path: "/spec/replicas"},
+			expected:  "op: remove\npath: /spec/replicas\n",
+		},
+	} {
+		t.Run(fmt.Sprintf("%s", test.name), func(t *testing.T) {
+			output, err := yaml.Marshal(test.operation)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := pretty.Compare(string(output), test.expected); diff != "" {
+				t.Errorf("%s, diff: (-got +want)\n%s", test.name, diff)
+			}
+		})
+	}
+}
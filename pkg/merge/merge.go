@@ -0,0 +1,527 @@
+// Package merge three-way merge the files of a generated package with the
+// local edits made since the previous conversion
+package merge
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	yaml "gopkg.in/yaml.v3"
+)
+
+const (
+	// LocalMarker start the local side of a conflict
+	LocalMarker = "<<<<<<< local"
+
+	// SeparatorMarker separate the local side from the chart side
+	SeparatorMarker = "======="
+
+	// ChartMarker end the chart side of a conflict
+	ChartMarker = ">>>>>>> chart"
+
+	placeholderFormat = "__helm_convert_conflict_%d__"
+)
+
+// Options configure how documents are merged
+type Options struct {
+	// SetLists merge lists of scalars as sets: items added or removed by the
+	// chart are added or removed from the local list. Otherwise lists of
+	// scalars are replaced as a whole.
+	SetLists bool
+}
+
+// conflict is a value modified differently in the local and the chart version
+type conflict struct {
+	placeholder string
+	key         *yaml.Node
+	local, new  *yaml.Node
+}
+
+type merger struct {
+	options   *Options
+	conflicts []*conflict
+}
+
+// Text merge a file as a whole, if both the local and the chart version
+// changed the result contains both versions surrounded by conflict markers
+func Text(base, local, new []byte) ([]byte, bool) {
+	switch {
+	case bytes.Equal(local, new), bytes.Equal(base, local):
+		return new, false
+	case bytes.Equal(base, new):
+		return local, false
+	}
+
+	var buf bytes.Buffer
+	writeConflict(&buf, local, new)
+	return buf.Bytes(), true
+}
+
+// Documents merge multi-document YAML files. Documents are matched by
+// apiVersion, kind, namespace and name, or by position if they don't have a
+// kind. Maps are merged key by key, lists of maps with a name are merged item
+// by item. The second returned value is true if the result contains conflict
+// markers.
+func Documents(base, local, new []byte, options *Options) ([]byte, bool, error) {
+	if options == nil {
+		options = &Options{}
+	}
+
+	baseDocs, err := decodeDocuments(base)
+	if err != nil {
+		return nil, false, fmt.Errorf("couldn't parse base version: %v", err)
+	}
+	localDocs, err := decodeDocuments(local)
+	if err != nil {
+		return nil, false, fmt.Errorf("couldn't parse local version: %v", err)
+	}
+	newDocs, err := decodeDocuments(new)
+	if err != nil {
+		return nil, false, fmt.Errorf("couldn't parse chart version: %v", err)
+	}
+
+	baseIndex := indexDocuments(baseDocs)
+	newIndex := indexDocuments(newDocs)
+
+	// local documents first, then documents added by the chart
+	keys := make([]string, 0, len(localDocs)+len(newDocs))
+	localIndex := make(map[string]*yaml.Node, len(localDocs))
+	for i, doc := range localDocs {
+		key := documentKey(doc, i)
+		localIndex[key] = doc
+		keys = append(keys, key)
+	}
+	for i, doc := range newDocs {
+		if key := documentKey(doc, i); localIndex[key] == nil {
+			keys = append(keys, key)
+		}
+	}
+
+	var output bytes.Buffer
+	conflicts := false
+	first := true
+	for _, key := range keys {
+		b, l, n := baseIndex[key], localIndex[key], newIndex[key]
+
+		m := &merger{options: options}
+		merged, ok := m.merge(content(b), content(l), content(n))
+
+		var buf bytes.Buffer
+		if !ok {
+			localDoc, err := encode(l)
+			if err != nil {
+				return nil, false, err
+			}
+			newDoc, err := encode(n)
+			if err != nil {
+				return nil, false, err
+			}
+			writeConflict(&buf, localDoc, newDoc)
+			conflicts = true
+		} else if merged != nil {
+			doc := l
+			if doc == nil {
+				doc = n
+			}
+			doc.Content = []*yaml.Node{merged}
+
+			data, err := encode(doc)
+			if err != nil {
+				return nil, false, err
+			}
+			data, err = m.expand(data)
+			if err != nil {
+				return nil, false, err
+			}
+			buf.Write(data)
+			conflicts = conflicts || len(m.conflicts) > 0
+		} else {
+			// removed
+			continue
+		}
+
+		if !first {
+			output.WriteString("---\n")
+		}
+		output.Write(buf.Bytes())
+		first = false
+	}
+
+	return output.Bytes(), conflicts, nil
+}
+
+// merge three-way merge a node, nil means the node doesn't exist. The second
+// returned value is false if the node cannot be merged.
+func (m *merger) merge(base, local, new *yaml.Node) (*yaml.Node, bool) {
+	switch {
+	case equal(local, new):
+		return local, true
+	case equal(base, local):
+		return new, true
+	case equal(base, new):
+		return local, true
+	case local == nil || new == nil || local.Kind != new.Kind:
+		return nil, false
+	case local.Kind == yaml.MappingNode:
+		if base != nil && base.Kind != yaml.MappingNode {
+			base = nil
+		}
+		return m.mergeMaps(base, local, new), true
+	case local.Kind == yaml.SequenceNode:
+		if base != nil && base.Kind != yaml.SequenceNode {
+			base = nil
+		}
+		if isNamedList(base) && isNamedList(local) && isNamedList(new) {
+			return m.mergeNamedLists(base, local, new)
+		}
+		if m.options.SetLists && isScalarList(base) && isScalarList(local) && isScalarList(new) {
+			return mergeSets(base, local, new), true
+		}
+	}
+	return nil, false
+}
+
+// mergeMaps merge maps key by key, keys which cannot be merged are replaced by
+// a placeholder expanded into conflict markers once the document is encoded
+func (m *merger) mergeMaps(base, local, new *yaml.Node) *yaml.Node {
+	result := *local
+	result.Content = nil
+
+	keys := make([]*yaml.Node, 0, len(local.Content)/2+len(new.Content)/2)
+	for i := 0; i < len(local.Content); i += 2 {
+		keys = append(keys, local.Content[i])
+	}
+	for i := 0; i < len(new.Content); i += 2 {
+		if mapValue(local, new.Content[i].Value) == nil {
+			keys = append(keys, new.Content[i])
+		}
+	}
+
+	for _, key := range keys {
+		l := mapValue(local, key.Value)
+		n := mapValue(new, key.Value)
+		merged, ok := m.merge(mapValue(base, key.Value), l, n)
+		if !ok {
+			c := &conflict{
+				placeholder: fmt.Sprintf(placeholderFormat, len(m.conflicts)),
+				key:         key,
+				local:       l,
+				new:         n,
+			}
+			m.conflicts = append(m.conflicts, c)
+			merged = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: c.placeholder}
+		}
+		if merged != nil {
+			result.Content = append(result.Content, key, merged)
+		}
+	}
+
+	return &result
+}
+
+// mergeNamedLists merge lists of maps item by item, items are matched by name
+func (m *merger) mergeNamedLists(base, local, new *yaml.Node) (*yaml.Node, bool) {
+	conflicts := len(m.conflicts)
+
+	result := *local
+	result.Content = nil
+
+	items := append([]*yaml.Node{}, local.Content...)
+	for _, item := range new.Content {
+		if listItem(local, itemName(item)) == nil {
+			items = append(items, item)
+		}
+	}
+
+	for _, item := range items {
+		name := itemName(item)
+		merged, ok := m.merge(listItem(base, name), listItem(local, name), listItem(new, name))
+		if !ok {
+			// conflicts found in the items are part of the list conflict
+			m.conflicts = m.conflicts[:conflicts]
+			return nil, false
+		}
+		if merged != nil {
+			result.Content = append(result.Content, merged)
+		}
+	}
+
+	return &result, true
+}
+
+// mergeSets keep the local items, remove the items removed by the chart and
+// add the ones added by the chart
+func mergeSets(base, local, new *yaml.Node) *yaml.Node {
+	result := *local
+	result.Content = nil
+
+	for _, item := range local.Content {
+		if listItem(base, item.Value) != nil && listItem(new, item.Value) == nil {
+			continue
+		}
+		result.Content = append(result.Content, item)
+	}
+	for _, item := range new.Content {
+		if listItem(base, item.Value) == nil && listItem(local, item.Value) == nil {
+			result.Content = append(result.Content, item)
+		}
+	}
+
+	return &result
+}
+
+// expand replace conflict placeholders by the local and chart versions of the
+// value surrounded by conflict markers
+func (m *merger) expand(data []byte) ([]byte, error) {
+	if len(m.conflicts) == 0 {
+		return data, nil
+	}
+
+	placeholders := make(map[string]*conflict, len(m.conflicts))
+	for _, c := range m.conflicts {
+		placeholders[c.placeholder] = c
+	}
+
+	var buf bytes.Buffer
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		var c *conflict
+		for placeholder, candidate := range placeholders {
+			if strings.HasSuffix(strings.TrimRight(line, "\n"), placeholder) {
+				c = candidate
+			}
+		}
+		if c == nil {
+			buf.WriteString(line)
+			continue
+		}
+
+		// prefix is the indentation and the list item dash, if any
+		prefix := line[:len(line)-len(strings.TrimLeft(line, " -"))]
+
+		localSide, err := encodeField(c.key, c.local, prefix)
+		if err != nil {
+			return nil, err
+		}
+		newSide, err := encodeField(c.key, c.new, prefix)
+		if err != nil {
+			return nil, err
+		}
+		writeConflict(&buf, localSide, newSide)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// encodeField encode a key and its value, the first line is prefixed by
+// prefix, the following ones are indented to align with the first one
+func encodeField(key, value *yaml.Node, prefix string) ([]byte, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	data, err := encode(&yaml.Node{Kind: yaml.MappingNode, Content: []*yaml.Node{key, value}})
+	if err != nil {
+		return nil, err
+	}
+
+	indent := strings.Repeat(" ", len(prefix))
+	var buf bytes.Buffer
+	for i, line := range strings.SplitAfter(string(data), "\n") {
+		if line == "" {
+			continue
+		}
+		if i == 0 {
+			buf.WriteString(prefix)
+		} else {
+			buf.WriteString(indent)
+		}
+		buf.WriteString(line)
+	}
+	return buf.Bytes(), nil
+}
+
+// writeConflict write both versions surrounded by conflict markers
+func writeConflict(buf *bytes.Buffer, local, new []byte) {
+	buf.WriteString(LocalMarker + "\n")
+	writeLines(buf, local)
+	buf.WriteString(SeparatorMarker + "\n")
+	writeLines(buf, new)
+	buf.WriteString(ChartMarker + "\n")
+}
+
+func writeLines(buf *bytes.Buffer, data []byte) {
+	buf.Write(data)
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		buf.WriteByte('\n')
+	}
+}
+
+// decodeDocuments decode a multi-document YAML file, empty documents are
+// ignored
+func decodeDocuments(data []byte) ([]*yaml.Node, error) {
+	var docs []*yaml.Node
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		doc := &yaml.Node{}
+		err := decoder.Decode(doc)
+		if err == io.EOF {
+			return docs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if content(doc) != nil {
+			docs = append(docs, doc)
+		}
+	}
+}
+
+func indexDocuments(docs []*yaml.Node) map[string]*yaml.Node {
+	index := make(map[string]*yaml.Node, len(docs))
+	for i, doc := range docs {
+		index[documentKey(doc, i)] = doc
+	}
+	return index
+}
+
+// documentKey identify a document by apiVersion, kind, namespace and name, or
+// by position if it doesn't have a kind
+func documentKey(doc *yaml.Node, index int) string {
+	root := content(doc)
+	kind := scalarValue(mapValue(root, "kind"))
+	if kind == "" {
+		return fmt.Sprintf("#%d", index)
+	}
+	metadata := mapValue(root, "metadata")
+	return strings.Join([]string{
+		scalarValue(mapValue(root, "apiVersion")),
+		kind,
+		scalarValue(mapValue(metadata, "namespace")),
+		scalarValue(mapValue(metadata, "name")),
+	}, "|")
+}
+
+func encode(node *yaml.Node) ([]byte, error) {
+	if node == nil {
+		return nil, nil
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(node); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// content return the root node of a document
+func content(doc *yaml.Node) *yaml.Node {
+	if doc == nil || len(doc.Content) == 0 {
+		return nil
+	}
+	return doc.Content[0]
+}
+
+// equal compare the values of two nodes, ignoring style and comments
+func equal(a, b *yaml.Node) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	var va, vb interface{}
+	if err := a.Decode(&va); err != nil {
+		return false
+	}
+	if err := b.Decode(&vb); err != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// mapValue return the value of a key, nil if not found
+func mapValue(node *yaml.Node, key string) *yaml.Node {
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
+
+func scalarValue(node *yaml.Node) string {
+	if node == nil || node.Kind != yaml.ScalarNode {
+		return ""
+	}
+	return node.Value
+}
+
+// itemName return the identity of a list item, its name for a map, its value
+// for a scalar
+func itemName(node *yaml.Node) string {
+	if node.Kind == yaml.ScalarNode {
+		return node.Value
+	}
+	return scalarValue(mapValue(node, "name"))
+}
+
+// listItem return the item of a list matching a name
+func listItem(list *yaml.Node, name string) *yaml.Node {
+	if list == nil {
+		return nil
+	}
+	for _, item := range list.Content {
+		if itemName(item) == name {
+			return item
+		}
+	}
+	return nil
+}
+
+// isNamedList return true if all the items of a list are maps with a unique
+// name, a nil or empty list is considered as named
+func isNamedList(list *yaml.Node) bool {
+	if list == nil {
+		return true
+	}
+	names := make(map[string]struct{}, len(list.Content))
+	for _, item := range list.Content {
+		if item.Kind != yaml.MappingNode {
+			return false
+		}
+		name := itemName(item)
+		if _, ok := names[name]; ok || name == "" {
+			return false
+		}
+		names[name] = struct{}{}
+	}
+	return true
+}
+
+// isScalarList return true if all the items of a list are unique scalars, a
+// nil list is considered as a list of scalars
+func isScalarList(list *yaml.Node) bool {
+	if list == nil {
+		return true
+	}
+	values := make(map[string]struct{}, len(list.Content))
+	for _, item := range list.Content {
+		if item.Kind != yaml.ScalarNode {
+			return false
+		}
+		if _, ok := values[item.Value]; ok {
+			return false
+		}
+		values[item.Value] = struct{}{}
+	}
+	return true
+}
@@ -0,0 +1,224 @@
+package merge
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestDocuments(t *testing.T) {
+	for _, test := range []struct {
+		name      string
+		options   *Options
+		base      string
+		local     string
+		new       string
+		expected  string
+		conflicts bool
+	}{
+		{
+			name: "it should keep local edits and apply chart changes",
+			base: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+        - name: web
+          image: nginx:1.15
+`,
+			local: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  # scaled for production
+  replicas: 3
+  template:
+    spec:
+      containers:
+        - name: web
+          image: nginx:1.15
+        - name: sidecar
+          image: envoy
+`,
+			new: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  replicas: 1
+  template:
+    spec:
+      containers:
+        - name: web
+          image: nginx:1.16
+`,
+			expected: `apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: web
+spec:
+  # scaled for production
+  replicas: 3
+  template:
+    spec:
+      containers:
+        - name: web
+          image: nginx:1.16
+        - name: sidecar
+          image: envoy
+`,
+		},
+		{
+			name: "it should report conflicting changes with markers",
+			base: `kind: Service
+metadata:
+  name: web
+spec:
+  ports:
+    - name: http
+      port: 80
+`,
+			local: `kind: Service
+metadata:
+  name: web
+spec:
+  ports:
+    - name: http
+      port: 8080
+`,
+			new: `kind: Service
+metadata:
+  name: web
+spec:
+  ports:
+    - name: http
+      port: 9090
+`,
+			expected: `kind: Service
+metadata:
+  name: web
+spec:
+  ports:
+    - name: http
+<<<<<<< local
+      port: 8080
+=======
+      port: 9090
+>>>>>>> chart
+`,
+			conflicts: true,
+		},
+		{
+			name: "it should add and remove documents",
+			base: `kind: Service
+metadata:
+  name: old
+---
+kind: Service
+metadata:
+  name: web
+`,
+			local: `kind: Service
+metadata:
+  name: old
+---
+kind: Service
+metadata:
+  name: web
+---
+kind: ConfigMap
+metadata:
+  name: mine
+`,
+			new: `kind: Service
+metadata:
+  name: web
+---
+kind: Service
+metadata:
+  name: metrics
+`,
+			expected: `kind: Service
+metadata:
+  name: web
+---
+kind: ConfigMap
+metadata:
+  name: mine
+---
+kind: Service
+metadata:
+  name: metrics
+`,
+		},
+		{
+			name:    "it should merge lists of scalars as sets",
+			options: &Options{SetLists: true},
+			base: `resources:
+  - a.yaml
+  - b.yaml
+`,
+			local: `resources:
+  - a.yaml
+  - b.yaml
+  - patch.yaml
+`,
+			new: `resources:
+  - a.yaml
+  - c.yaml
+`,
+			expected: `resources:
+  - a.yaml
+  - patch.yaml
+  - c.yaml
+`,
+		},
+		{
+			name: "it should conflict on a document modified locally and removed by the chart",
+			base: `kind: Service
+metadata:
+  name: web
+spec:
+  type: ClusterIP
+`,
+			local: `kind: Service
+metadata:
+  name: web
+spec:
+  type: NodePort
+`,
+			new: ``,
+			expected: `<<<<<<< local
+kind: Service
+metadata:
+  name: web
+spec:
+  type: NodePort
+=======
+>>>>>>> chart
+`,
+			conflicts: true,
+		},
+	} {
+		t.Run(fmt.Sprintf("%s", test.name), func(t *testing.T) {
+			output, conflicts, err := Documents([]byte(test.base), []byte(test.local), []byte(test.new), test.options)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if conflicts != test.conflicts {
+				t.Errorf("expected conflicts to be %v, got %v", test.conflicts, conflicts)
+			}
+			if string(output) != test.expected {
+				t.Fatalf(
+					"expected: \n%v\ngot:\n%v",
+					test.expected,
+					string(output),
+				)
+			}
+		})
+	}
+}
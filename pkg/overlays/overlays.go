@@ -0,0 +1,218 @@
+// Package overlays split the conversions of a chart for several Kubernetes
+// versions into a base and one kustomize overlay per version
+package overlays
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/ContainerSolutions/helm-convert/pkg/components"
+	"github.com/ContainerSolutions/helm-convert/pkg/types"
+	"github.com/ContainerSolutions/helm-convert/pkg/utils"
+	"github.com/Masterminds/semver"
+	"sigs.k8s.io/kustomize/pkg/image"
+	"sigs.k8s.io/kustomize/pkg/resid"
+	ktypes "sigs.k8s.io/kustomize/pkg/types"
+)
+
+const (
+	// BaseDirectory is the directory containing the resources shared by all
+	// the Kubernetes versions
+	BaseDirectory = "base"
+
+	// Directory is the directory containing the overlays
+	Directory = "overlays"
+)
+
+// Conversion is the conversion of a chart rendered for a Kubernetes version
+type Conversion struct {
+	// KubeVersion is the Kubernetes version, ie: 1.21
+	KubeVersion string
+
+	// Config is the kustomization config gathered by the transformers
+	Config *ktypes.Kustomization
+
+	// Resources are the transformed resources
+	Resources *types.Resources
+}
+
+// ParseVersions parse a list of Kubernetes versions, ie: 1.21 or v1.25.3
+func ParseVersions(list []string) ([]string, error) {
+	versions := make([]string, 0, len(list))
+	seen := make(map[string]struct{}, len(list))
+	for _, v := range list {
+		v = strings.TrimSpace(v)
+		if _, err := semver.NewVersion(v); err != nil {
+			return nil, fmt.Errorf("invalid Kubernetes version '%s'", v)
+		}
+		if _, ok := seen[Name(v)]; ok {
+			return nil, fmt.Errorf("Kubernetes version '%s' is given twice", v)
+		}
+		seen[Name(v)] = struct{}{}
+		versions = append(versions, v)
+	}
+	return versions, nil
+}
+
+// Name return the name of the overlay of a Kubernetes version, ie: k8s-1.21
+func Name(kubeVersion string) string {
+	return "k8s-" + strings.TrimPrefix(strings.TrimSpace(kubeVersion), "v")
+}
+
+// Split return the base, containing the resources, generators and images
+// identical in all the conversions, and an overlay per conversion containing
+// the other ones. The name prefix is only set by the overlays, setting it in
+// the base as well would prefix the base resources twice.
+func Split(conversions []*Conversion, namer *utils.FileNamer) (*ktypes.Kustomization,
+	*types.Resources, []*types.Overlay, error) {
+	if len(conversions) == 0 {
+		return nil, nil, nil, fmt.Errorf("no conversion to split")
+	}
+	first := conversions[0]
+
+	base := types.NewResources()
+	base.Report = first.Resources.Report
+	for id, res := range first.Resources.ResMap {
+		shared := true
+		for _, c := range conversions[1:] {
+			other, ok := c.Resources.ResMap[id]
+			if !ok || !reflect.DeepEqual(res.Map(), other.Map()) {
+				shared = false
+				break
+			}
+		}
+		if shared {
+			base.ResMap[id] = res
+			base.Origins[id] = first.Resources.Origins[id]
+			base.FieldOrders[id] = first.Resources.FieldOrders[id]
+		}
+	}
+
+	config := *first.Config
+	config.NamePrefix = ""
+	config.ConfigMapGenerator = nil
+	config.SecretGenerator = nil
+	config.Images = nil
+
+	for _, args := range first.Config.ConfigMapGenerator {
+		shared := true
+		for _, c := range conversions[1:] {
+			shared = shared && containsConfigMap(c.Config.ConfigMapGenerator, args)
+		}
+		if shared {
+			config.ConfigMapGenerator = append(config.ConfigMapGenerator, args)
+			copySourceFiles(base, args.DataSources, first.Resources)
+		}
+	}
+	for _, args := range first.Config.SecretGenerator {
+		shared := true
+		for _, c := range conversions[1:] {
+			shared = shared && containsSecret(c.Config.SecretGenerator, args)
+		}
+		if shared {
+			config.SecretGenerator = append(config.SecretGenerator, args)
+			copySourceFiles(base, args.DataSources, first.Resources)
+		}
+	}
+	for _, image := range first.Config.Images {
+		shared := true
+		for _, c := range conversions[1:] {
+			shared = shared && containsImage(c.Config.Images, image)
+		}
+		if shared {
+			config.Images = append(config.Images, image)
+		}
+	}
+
+	// resources are only listed if the resources transformer listed them
+	if len(first.Config.Resources) > 0 {
+		filenames, err := filePaths(base, namer)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		config.Resources = nil
+		seen := make(map[string]struct{}, len(filenames))
+		for _, filename := range filenames {
+			if _, ok := seen[filename]; !ok {
+				seen[filename] = struct{}{}
+				config.Resources = append(config.Resources, filename)
+			}
+		}
+		sort.Strings(config.Resources)
+	}
+
+	overlays := make([]*types.Overlay, 0, len(conversions))
+	for _, c := range conversions {
+		diff, err := components.Diff(Name(c.KubeVersion), &config, c.Config, base, c.Resources, namer)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		overlay := types.NewOverlay(diff.Name)
+		overlay.Resources = diff.Resources
+		overlay.Config.Namespace = c.Config.Namespace
+		overlay.Config.NamePrefix = c.Config.NamePrefix
+		overlay.Config.CommonLabels = diff.Config.CommonLabels
+		overlay.Config.CommonAnnotations = diff.Config.CommonAnnotations
+		overlay.Config.Resources = diff.Config.Resources
+		overlay.Config.ConfigMapGenerator = diff.Config.ConfigMapGenerator
+		overlay.Config.SecretGenerator = diff.Config.SecretGenerator
+		overlay.Config.Images = diff.Config.Images
+		overlays = append(overlays, overlay)
+	}
+
+	return &config, base, overlays, nil
+}
+
+func containsConfigMap(list []ktypes.ConfigMapArgs, args ktypes.ConfigMapArgs) bool {
+	for _, item := range list {
+		if reflect.DeepEqual(item, args) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsSecret(list []ktypes.SecretArgs, args ktypes.SecretArgs) bool {
+	for _, item := range list {
+		if reflect.DeepEqual(item, args) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsImage(list []image.Image, item image.Image) bool {
+	for _, i := range list {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
+
+// copySourceFiles add the files referenced by a generator to the base
+func copySourceFiles(base *types.Resources, sources ktypes.DataSources, resources *types.Resources) {
+	paths := append([]string{}, sources.FileSources...)
+	if sources.EnvSource != "" {
+		paths = append(paths, sources.EnvSource)
+	}
+
+	for _, p := range paths {
+		if i := strings.Index(p, "="); i >= 0 {
+			p = p[i+1:]
+		}
+		if data, ok := resources.SourceFiles[p]; ok {
+			base.SourceFiles[p] = data
+		}
+	}
+}
+
+func filePaths(resources *types.Resources, namer *utils.FileNamer) (map[resid.ResId]string, error) {
+	if namer != nil {
+		return namer.FilePaths(resources)
+	}
+	return utils.GetResourceFileNames(resources.ResMap)
+}
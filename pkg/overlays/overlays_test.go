@@ -0,0 +1,181 @@
+package overlays
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ContainerSolutions/helm-convert/pkg/types"
+	"github.com/ContainerSolutions/helm-convert/pkg/utils"
+	"github.com/kylelemons/godebug/pretty"
+	"sigs.k8s.io/kustomize/k8sdeps/kunstruct"
+	"sigs.k8s.io/kustomize/pkg/gvk"
+	"sigs.k8s.io/kustomize/pkg/image"
+	"sigs.k8s.io/kustomize/pkg/resid"
+	"sigs.k8s.io/kustomize/pkg/resmap"
+	"sigs.k8s.io/kustomize/pkg/resource"
+	ktypes "sigs.k8s.io/kustomize/pkg/types"
+)
+
+func TestSplit(t *testing.T) {
+	var rf = resource.NewFactory(kunstruct.NewKunstructuredFactoryImpl())
+	var deploy = gvk.Gvk{Group: "apps", Version: "v1", Kind: "Deployment"}
+	var ingressV1 = gvk.Gvk{Group: "networking.k8s.io", Version: "v1", Kind: "Ingress"}
+	var ingressV1beta1 = gvk.Gvk{Group: "extensions", Version: "v1beta1", Kind: "Ingress"}
+	var psp = gvk.Gvk{Group: "policy", Version: "v1beta1", Kind: "PodSecurityPolicy"}
+
+	newResource := func(apiVersion, kind string) *resource.Resource {
+		return rf.FromMap(map[string]interface{}{
+			"apiVersion": apiVersion,
+			"kind":       kind,
+			"metadata":   map[string]interface{}{"name": "web"},
+		})
+	}
+	newConfig := func(version string) *ktypes.Kustomization {
+		return &ktypes.Kustomization{
+			Namespace:    "web",
+			NamePrefix:   "release-",
+			CommonLabels: map[string]string{"app": "web"},
+			Resources:    []string{"filled-by-the-resources-transformer.yaml"},
+			ConfigMapGenerator: []ktypes.ConfigMapArgs{
+				{GeneratorArgs: ktypes.GeneratorArgs{Name: "shared", DataSources: ktypes.DataSources{
+					FileSources: []string{"files/shared/config.yaml"},
+				}}},
+				{GeneratorArgs: ktypes.GeneratorArgs{Name: "version", DataSources: ktypes.DataSources{
+					LiteralSources: []string{"version=" + version},
+				}}},
+			},
+			Images: []image.Image{{Name: "nginx", NewTag: "1.25"}},
+		}
+	}
+
+	conversions := []*Conversion{
+		{
+			KubeVersion: "1.18",
+			Config:      newConfig("18"),
+			Resources: &types.Resources{
+				ResMap: resmap.ResMap{
+					resid.NewResId(deploy, "web"):         newResource("apps/v1", "Deployment"),
+					resid.NewResId(ingressV1beta1, "web"): newResource("extensions/v1beta1", "Ingress"),
+					resid.NewResId(psp, "web"):            newResource("policy/v1beta1", "PodSecurityPolicy"),
+				},
+				SourceFiles: map[string]string{"files/shared/config.yaml": "port: 80"},
+			},
+		},
+		{
+			KubeVersion: "v1.28",
+			Config:      newConfig("28"),
+			Resources: &types.Resources{
+				ResMap: resmap.ResMap{
+					resid.NewResId(deploy, "web"):    newResource("apps/v1", "Deployment"),
+					resid.NewResId(ingressV1, "web"): newResource("networking.k8s.io/v1", "Ingress"),
+				},
+				SourceFiles: map[string]string{"files/shared/config.yaml": "port: 80"},
+			},
+		},
+	}
+
+	config, base, overlays, err := Split(conversions, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedConfig := &ktypes.Kustomization{
+		Namespace:    "web",
+		CommonLabels: map[string]string{"app": "web"},
+		Resources:    []string{"web-deploy.yaml"},
+		ConfigMapGenerator: []ktypes.ConfigMapArgs{
+			{GeneratorArgs: ktypes.GeneratorArgs{Name: "shared", DataSources: ktypes.DataSources{
+				FileSources: []string{"files/shared/config.yaml"},
+			}}},
+		},
+		Images: []image.Image{{Name: "nginx", NewTag: "1.25"}},
+	}
+	if diff := pretty.Compare(config, expectedConfig); diff != "" {
+		t.Errorf("base config, diff: (-got +want)\n%s", diff)
+	}
+	if diff := pretty.Compare(base.SourceFiles, map[string]string{"files/shared/config.yaml": "port: 80"}); diff != "" {
+		t.Errorf("base source files, diff: (-got +want)\n%s", diff)
+	}
+	if len(base.ResMap) != 1 || base.ResMap[resid.NewResId(deploy, "web")] == nil {
+		t.Errorf("expected the deployment in the base, got %v", utils.SortedResIds(base.ResMap))
+	}
+
+	expectedOverlays := []*ktypes.Kustomization{
+		{
+			Namespace:    "web",
+			NamePrefix:   "release-",
+			CommonLabels: map[string]string{"app": "web"},
+			Resources:    []string{"web-ing.yaml", "web-psp.yaml"},
+			ConfigMapGenerator: []ktypes.ConfigMapArgs{
+				{GeneratorArgs: ktypes.GeneratorArgs{Name: "version", DataSources: ktypes.DataSources{
+					LiteralSources: []string{"version=18"},
+				}}},
+			},
+		},
+		{
+			Namespace:    "web",
+			NamePrefix:   "release-",
+			CommonLabels: map[string]string{"app": "web"},
+			Resources:    []string{"web-ing.yaml"},
+			ConfigMapGenerator: []ktypes.ConfigMapArgs{
+				{GeneratorArgs: ktypes.GeneratorArgs{Name: "version", DataSources: ktypes.DataSources{
+					LiteralSources: []string{"version=28"},
+				}}},
+			},
+		},
+	}
+	if len(overlays) != len(expectedOverlays) {
+		t.Fatalf("expected %d overlays, got %d", len(expectedOverlays), len(overlays))
+	}
+	for i, overlay := range overlays {
+		if diff := pretty.Compare(overlay.Config, expectedOverlays[i]); diff != "" {
+			t.Errorf("overlay %s, diff: (-got +want)\n%s", overlay.Name, diff)
+		}
+	}
+	if overlays[0].Name != "k8s-1.18" || overlays[1].Name != "k8s-1.28" {
+		t.Errorf("got overlays %s and %s, want k8s-1.18 and k8s-1.28", overlays[0].Name, overlays[1].Name)
+	}
+}
+
+func TestParseVersions(t *testing.T) {
+	for _, test := range []struct {
+		name          string
+		input         []string
+		expected      []string
+		expectedError string
+	}{
+		{
+			name:     "it should parse minor and patch versions",
+			input:    []string{"1.21", " v1.25.3"},
+			expected: []string{"1.21", "v1.25.3"},
+		},
+		{
+			name:          "it should fail with an invalid version",
+			input:         []string{"1.21", "latest"},
+			expectedError: "invalid Kubernetes version 'latest'",
+		},
+		{
+			name:          "it should fail with a duplicated version",
+			input:         []string{"1.21", "v1.21"},
+			expectedError: "Kubernetes version 'v1.21' is given twice",
+		},
+	} {
+		t.Run(fmt.Sprintf("%s", test.name), func(t *testing.T) {
+			versions, err := ParseVersions(test.input)
+			if test.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedError) {
+					t.Fatalf("%s: expected error %q, got %v", test.name, test.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if diff := pretty.Compare(versions, test.expected); diff != "" {
+				t.Errorf("%s, diff: (-got +want)\n%s", test.name, diff)
+			}
+		})
+	}
+}
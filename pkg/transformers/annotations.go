@@ -1,36 +1,223 @@
 package transformers
 
 import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
 	"github.com/ContainerSolutions/helm-convert/pkg/types"
 	"github.com/ContainerSolutions/helm-convert/pkg/utils"
+	"github.com/golang/glog"
+	"k8s.io/helm/pkg/hooks"
 	ktypes "sigs.k8s.io/kustomize/pkg/types"
 )
 
+// Lifecycle is the tool the Helm lifecycle annotations are translated for
+type Lifecycle string
+
+const (
+	// LifecycleNone only remove the Helm lifecycle annotations
+	LifecycleNone Lifecycle = ""
+
+	// LifecycleArgoCD translate the Helm lifecycle annotations to Argo CD
+	LifecycleArgoCD Lifecycle = "argocd"
+
+	// LifecycleFlux translate the Helm lifecycle annotations to Flux
+	LifecycleFlux Lifecycle = "flux"
+
+	// LifecycleKapp translate the Helm lifecycle annotations to kapp
+	LifecycleKapp Lifecycle = "kapp"
+)
+
+// Lifecycles is the list of supported lifecycle translations
+var Lifecycles = []Lifecycle{LifecycleArgoCD, LifecycleFlux, LifecycleKapp}
+
+// ParseLifecycle return the lifecycle translation with the given name, an
+// empty name only remove the annotations
+func ParseLifecycle(s string) (Lifecycle, error) {
+	if s == "" {
+		return LifecycleNone, nil
+	}
+	for _, lifecycle := range Lifecycles {
+		if string(lifecycle) == s {
+			return lifecycle, nil
+		}
+	}
+	return LifecycleNone, fmt.Errorf("unknown lifecycle '%s', expected one of %v", s, Lifecycles)
+}
+
+// ResourcePolicyAnno is the Helm annotation preventing the deletion of a
+// resource
+const ResourcePolicyAnno = "helm.sh/resource-policy"
+
+// argoCDHooks map the Helm hooks to Argo CD hooks
+var argoCDHooks = map[string]string{
+	hooks.PreInstall:  "PreSync",
+	hooks.PreUpgrade:  "PreSync",
+	hooks.PostInstall: "PostSync",
+	hooks.PostUpgrade: "PostSync",
+}
+
+// argoCDHookDeletePolicies map the Helm hook delete policies to Argo CD
+var argoCDHookDeletePolicies = map[string]string{
+	hooks.BeforeHookCreation: "BeforeHookCreation",
+	hooks.HookSucceeded:      "HookSucceeded",
+	hooks.HookFailed:         "HookFailed",
+}
+
+const kappChangeGroup = "helm-convert.io/hook-weight-%d"
+
 type annotationsTransformer struct {
-	keys []string
+	keys      []string
+	lifecycle Lifecycle
 }
 
 var _ Transformer = &annotationsTransformer{}
 
-// NewAnnotationsTransformer constructs a annotationsTransformer.
-func NewAnnotationsTransformer(keys []string) Transformer {
-	return &annotationsTransformer{keys}
+// NewAnnotationsTransformer constructs a annotationsTransformer. The Helm
+// lifecycle annotations are translated for the given tool before being
+// removed.
+func NewAnnotationsTransformer(keys []string, lifecycle Lifecycle) Transformer {
+	if lifecycle != LifecycleNone {
+		keys = append(append([]string{}, keys...), ResourcePolicyAnno)
+	}
+	return &annotationsTransformer{keys, lifecycle}
 }
 
-// Transform remove given annotations from manifests
+// Transform translate the Helm lifecycle annotations then remove given
+// annotations from manifests
 func (t *annotationsTransformer) Transform(config *ktypes.Kustomization, resources *types.Resources) error {
+	// kapp orders the hooks by change groups, each weight waits for the
+	// previous one
+	var weights []int
+	if t.lifecycle == LifecycleKapp {
+		weights = hookWeights(resources)
+	}
+
 	// TODO: retrieve common annotations for config.CommonAnnotations
-RESOURCES_LOOP:
-	for id := range resources.ResMap {
+	for _, id := range utils.SortedResIds(resources.ResMap) {
 		obj := resources.ResMap[id].Map()
 
-		for _, key := range t.keys {
-			err := utils.RecursivelyRemoveKey("annotations", key, obj)
-			if err != nil {
-				continue RESOURCES_LOOP
-			}
+		if t.lifecycle != LifecycleNone {
+			t.translate(resources.Report, resources.Describe(id), obj, weights)
 		}
+
+		removeKeys(resources.Report.AnnotationRemoved, id, []string{"annotations"}, t.keys, obj)
 	}
 
 	return nil
 }
+
+// translate add the annotations equivalent to the Helm lifecycle
+// annotations of a resource
+func (t *annotationsTransformer) translate(report *types.Report, id string, obj map[string]interface{}, weights []int) {
+	metadata, ok := obj["metadata"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	annotations, ok := metadata["annotations"].(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	get := func(key string) string {
+		value, _ := annotations[key].(string)
+		return strings.TrimSpace(value)
+	}
+	keep := get(ResourcePolicyAnno) == "keep"
+	hook := get(hooks.HookAnno)
+	weight := get(hooks.HookWeightAnno)
+	deletePolicy := get(hooks.HookDeleteAnno)
+
+	switch t.lifecycle {
+	case LifecycleArgoCD:
+		if keep {
+			appendAnnotation(annotations, "argocd.argoproj.io/sync-options", "Prune=false,Delete=false")
+		}
+		if hook != "" {
+			if argoHook := translateList(hook, argoCDHooks); argoHook != "" {
+				annotations["argocd.argoproj.io/hook"] = argoHook
+			} else {
+				report.Warningf("Hook %s of %s has no Argo CD equivalent", hook, id)
+			}
+		}
+		if weight != "" {
+			annotations["argocd.argoproj.io/sync-wave"] = weight
+		}
+		if deletePolicy != "" {
+			if argoPolicy := translateList(deletePolicy, argoCDHookDeletePolicies); argoPolicy != "" {
+				annotations["argocd.argoproj.io/hook-delete-policy"] = argoPolicy
+			} else {
+				report.Warningf("Hook delete policy %s of %s has no Argo CD equivalent", deletePolicy, id)
+			}
+		}
+
+	case LifecycleFlux:
+		if keep {
+			annotations["kustomize.toolkit.fluxcd.io/prune"] = "disabled"
+		}
+		if weight != "" || deletePolicy != "" {
+			glog.V(4).Infof("Hook weight and delete policy of %s have no Flux equivalent", id)
+		}
+
+	case LifecycleKapp:
+		if keep {
+			annotations["kapp.k14s.io/delete-strategy"] = "orphan"
+		}
+		if w, err := strconv.Atoi(weight); err == nil {
+			annotations["kapp.k14s.io/change-group"] = fmt.Sprintf(kappChangeGroup, w)
+			i := sort.SearchInts(weights, w)
+			if i > 0 {
+				annotations["kapp.k14s.io/change-rule"] = fmt.Sprintf("upsert after upserting "+kappChangeGroup, weights[i-1])
+			}
+		}
+		if deletePolicy != "" {
+			glog.V(4).Infof("Hook delete policy of %s has no kapp equivalent", id)
+		}
+	}
+}
+
+// hookWeights return the sorted list of distinct hook weights
+func hookWeights(resources *types.Resources) []int {
+	seen := make(map[int]struct{})
+	var weights []int
+	for _, res := range resources.ResMap {
+		w, err := strconv.Atoi(strings.TrimSpace(res.GetAnnotations()[hooks.HookWeightAnno]))
+		if err != nil {
+			continue
+		}
+		if _, ok := seen[w]; !ok {
+			seen[w] = struct{}{}
+			weights = append(weights, w)
+		}
+	}
+	sort.Ints(weights)
+	return weights
+}
+
+// translateList translate a comma separated list of values, unknown values
+// are ignored and duplicates removed
+func translateList(list string, mapping map[string]string) string {
+	var values []string
+	seen := make(map[string]struct{})
+	for _, item := range strings.Split(list, ",") {
+		value, ok := mapping[strings.TrimSpace(item)]
+		if !ok {
+			continue
+		}
+		if _, ok := seen[value]; !ok {
+			seen[value] = struct{}{}
+			values = append(values, value)
+		}
+	}
+	return strings.Join(values, ",")
+}
+
+// appendAnnotation append a value to a comma separated annotation
+func appendAnnotation(annotations map[string]interface{}, key, value string) {
+	if existing, ok := annotations[key].(string); ok && existing != "" {
+		value = existing + "," + value
+	}
+	annotations[key] = value
+}
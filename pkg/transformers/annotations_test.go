@@ -22,13 +22,16 @@ type annotationsTransformerArgs struct {
 func TestAnnotationsRun(t *testing.T) {
 	var ingress = gvk.Gvk{Kind: "Ingress"}
 	var deploy = gvk.Gvk{Group: "apps", Version: "v1", Kind: "Deployment"}
+	var job = gvk.Gvk{Group: "batch", Version: "v1", Kind: "Job"}
+	var pvc = gvk.Gvk{Version: "v1", Kind: "PersistentVolumeClaim"}
 	var rf = resource.NewFactory(kunstruct.NewKunstructuredFactoryImpl())
 
 	for _, test := range []struct {
-		name     string
-		keys     []string
-		input    *annotationsTransformerArgs
-		expected *annotationsTransformerArgs
+		name      string
+		keys      []string
+		lifecycle Lifecycle
+		input     *annotationsTransformerArgs
+		expected  *annotationsTransformerArgs
 	}{
 		{
 			name: "it should remove matching annotations",
@@ -114,9 +117,178 @@ func TestAnnotationsRun(t *testing.T) {
 				},
 			},
 		},
+		{
+			name:      "it should translate the lifecycle annotations for Argo CD",
+			keys:      []string{"helm.sh/hook", "helm.sh/hook-weight", "helm.sh/hook-delete-policy"},
+			lifecycle: LifecycleArgoCD,
+			input: &annotationsTransformerArgs{
+				config: &ktypes.Kustomization{},
+				resources: &types.Resources{
+					ResMap: resmap.ResMap{
+						resid.NewResId(job, "migrate"): rf.FromMap(
+							map[string]interface{}{
+								"kind": "Job",
+								"metadata": map[string]interface{}{
+									"name": "migrate",
+									"annotations": map[string]interface{}{
+										"helm.sh/hook":               "pre-install,pre-upgrade",
+										"helm.sh/hook-weight":        "-5",
+										"helm.sh/hook-delete-policy": "before-hook-creation,hook-succeeded",
+									},
+								},
+							}),
+						resid.NewResId(pvc, "data"): rf.FromMap(
+							map[string]interface{}{
+								"kind": "PersistentVolumeClaim",
+								"metadata": map[string]interface{}{
+									"name": "data",
+									"annotations": map[string]interface{}{
+										"helm.sh/resource-policy":         "keep",
+										"argocd.argoproj.io/sync-options": "Replace=true",
+									},
+								},
+							}),
+					},
+				},
+			},
+			expected: &annotationsTransformerArgs{
+				config: &ktypes.Kustomization{},
+				resources: &types.Resources{
+					ResMap: resmap.ResMap{
+						resid.NewResId(job, "migrate"): rf.FromMap(
+							map[string]interface{}{
+								"kind": "Job",
+								"metadata": map[string]interface{}{
+									"name": "migrate",
+									"annotations": map[string]interface{}{
+										"argocd.argoproj.io/hook":               "PreSync",
+										"argocd.argoproj.io/sync-wave":          "-5",
+										"argocd.argoproj.io/hook-delete-policy": "BeforeHookCreation,HookSucceeded",
+									},
+								},
+							}),
+						resid.NewResId(pvc, "data"): rf.FromMap(
+							map[string]interface{}{
+								"kind": "PersistentVolumeClaim",
+								"metadata": map[string]interface{}{
+									"name": "data",
+									"annotations": map[string]interface{}{
+										"argocd.argoproj.io/sync-options": "Replace=true,Prune=false,Delete=false",
+									},
+								},
+							}),
+					},
+				},
+			},
+		},
+		{
+			name:      "it should disable pruning of kept resources with Flux",
+			keys:      []string{"helm.sh/hook"},
+			lifecycle: LifecycleFlux,
+			input: &annotationsTransformerArgs{
+				config: &ktypes.Kustomization{},
+				resources: &types.Resources{
+					ResMap: resmap.ResMap{
+						resid.NewResId(pvc, "data"): rf.FromMap(
+							map[string]interface{}{
+								"kind": "PersistentVolumeClaim",
+								"metadata": map[string]interface{}{
+									"name":        "data",
+									"annotations": map[string]interface{}{"helm.sh/resource-policy": "keep"},
+								},
+							}),
+					},
+				},
+			},
+			expected: &annotationsTransformerArgs{
+				config: &ktypes.Kustomization{},
+				resources: &types.Resources{
+					ResMap: resmap.ResMap{
+						resid.NewResId(pvc, "data"): rf.FromMap(
+							map[string]interface{}{
+								"kind": "PersistentVolumeClaim",
+								"metadata": map[string]interface{}{
+									"name":        "data",
+									"annotations": map[string]interface{}{"kustomize.toolkit.fluxcd.io/prune": "disabled"},
+								},
+							}),
+					},
+				},
+			},
+		},
+		{
+			name:      "it should order the hooks by weight with kapp change groups",
+			keys:      []string{"helm.sh/hook", "helm.sh/hook-weight"},
+			lifecycle: LifecycleKapp,
+			input: &annotationsTransformerArgs{
+				config: &ktypes.Kustomization{},
+				resources: &types.Resources{
+					ResMap: resmap.ResMap{
+						resid.NewResId(job, "migrate"): rf.FromMap(
+							map[string]interface{}{
+								"kind": "Job",
+								"metadata": map[string]interface{}{
+									"name":        "migrate",
+									"annotations": map[string]interface{}{"helm.sh/hook": "pre-install", "helm.sh/hook-weight": "-5"},
+								},
+							}),
+						resid.NewResId(job, "seed"): rf.FromMap(
+							map[string]interface{}{
+								"kind": "Job",
+								"metadata": map[string]interface{}{
+									"name":        "seed",
+									"annotations": map[string]interface{}{"helm.sh/hook": "pre-install", "helm.sh/hook-weight": "10"},
+								},
+							}),
+						resid.NewResId(pvc, "data"): rf.FromMap(
+							map[string]interface{}{
+								"kind": "PersistentVolumeClaim",
+								"metadata": map[string]interface{}{
+									"name":        "data",
+									"annotations": map[string]interface{}{"helm.sh/resource-policy": "keep"},
+								},
+							}),
+					},
+				},
+			},
+			expected: &annotationsTransformerArgs{
+				config: &ktypes.Kustomization{},
+				resources: &types.Resources{
+					ResMap: resmap.ResMap{
+						resid.NewResId(job, "migrate"): rf.FromMap(
+							map[string]interface{}{
+								"kind": "Job",
+								"metadata": map[string]interface{}{
+									"name":        "migrate",
+									"annotations": map[string]interface{}{"kapp.k14s.io/change-group": "helm-convert.io/hook-weight--5"},
+								},
+							}),
+						resid.NewResId(job, "seed"): rf.FromMap(
+							map[string]interface{}{
+								"kind": "Job",
+								"metadata": map[string]interface{}{
+									"name": "seed",
+									"annotations": map[string]interface{}{
+										"kapp.k14s.io/change-group": "helm-convert.io/hook-weight-10",
+										"kapp.k14s.io/change-rule":  "upsert after upserting helm-convert.io/hook-weight--5",
+									},
+								},
+							}),
+						resid.NewResId(pvc, "data"): rf.FromMap(
+							map[string]interface{}{
+								"kind": "PersistentVolumeClaim",
+								"metadata": map[string]interface{}{
+									"name":        "data",
+									"annotations": map[string]interface{}{"kapp.k14s.io/delete-strategy": "orphan"},
+								},
+							}),
+					},
+				},
+			},
+		},
 	} {
 		t.Run(fmt.Sprintf("%s", test.name), func(t *testing.T) {
-			lt := NewAnnotationsTransformer(test.keys)
+			lt := NewAnnotationsTransformer(test.keys, test.lifecycle)
 			err := lt.Transform(test.input.config, test.input.resources)
 
 			if err != nil {
@@ -133,3 +305,36 @@ func TestAnnotationsRun(t *testing.T) {
 		})
 	}
 }
+
+func TestAnnotationsUnknownDeletePolicy(t *testing.T) {
+	var job = gvk.Gvk{Group: "batch", Version: "v1", Kind: "Job"}
+	var rf = resource.NewFactory(kunstruct.NewKunstructuredFactoryImpl())
+
+	resources := types.NewResources()
+	resources.Report = types.NewReport("web", "1.0.0")
+	resources.ResMap[resid.NewResId(job, "migrate")] = rf.FromMap(map[string]interface{}{
+		"kind": "Job",
+		"metadata": map[string]interface{}{
+			"name": "migrate",
+			"annotations": map[string]interface{}{
+				"helm.sh/hook":               "pre-install",
+				"helm.sh/hook-delete-policy": "hook-removed",
+			},
+		},
+	})
+
+	lt := NewAnnotationsTransformer([]string{"helm.sh/hook", "helm.sh/hook-delete-policy"}, LifecycleArgoCD)
+	if err := lt.Transform(&ktypes.Kustomization{}, resources); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]string{"argocd.argoproj.io/hook": "PreSync"}
+	if diff := pretty.Compare(resources.ResMap[resid.NewResId(job, "migrate")].GetAnnotations(), expected); diff != "" {
+		t.Errorf("annotations, diff: (-got +want)\n%s", diff)
+	}
+
+	warnings := []string{"Hook delete policy hook-removed of Job/migrate has no Argo CD equivalent"}
+	if diff := pretty.Compare(resources.Report.Warnings, warnings); diff != "" {
+		t.Errorf("warnings, diff: (-got +want)\n%s", diff)
+	}
+}
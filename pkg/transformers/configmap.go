@@ -1,23 +1,34 @@
 package transformers
 
 import (
+	"encoding/base64"
+	"fmt"
+
 	"github.com/ContainerSolutions/helm-convert/pkg/types"
+	"github.com/ContainerSolutions/helm-convert/pkg/utils"
 	"github.com/golang/glog"
 	ktypes "sigs.k8s.io/kustomize/pkg/types"
 )
 
-type configMapTransformer struct{}
+type configMapTransformer struct {
+	options *DataSourceOptions
+}
 
 var _ Transformer = &configMapTransformer{}
 
-// NewConfigMapTransformer constructs a configMapTransformer.
-func NewConfigMapTransformer() Transformer {
-	return &configMapTransformer{}
+// NewConfigMapTransformer constructs a configMapTransformer. The options
+// configure how keys are classified, nil means defaults.
+func NewConfigMapTransformer(options *DataSourceOptions) Transformer {
+	return &configMapTransformer{options}
 }
 
 // Transform retrieve configmap from manifests and store them as configMapGenerator in the kustomization.yaml
 func (t *configMapTransformer) Transform(config *ktypes.Kustomization, resources *types.Resources) error {
-	for id, res := range resources.ResMap {
+	// iterate in a deterministic order so that source directories are
+	// always attributed to the same resource
+	for _, id := range utils.SortedResIds(resources.ResMap) {
+		res := resources.ResMap[id]
+
 		kind, err := res.GetFieldValue("kind")
 		if err != nil {
 			return err
@@ -34,21 +45,26 @@ func (t *configMapTransformer) Transform(config *ktypes.Kustomization, resources
 
 		obj := resources.ResMap[id].Map()
 
-		if _, found := obj["data"]; !found {
-			glog.V(8).Infof("Data field from configmap '%s' was not found", name)
-			continue
-		}
+		data, _ := obj["data"].(map[string]interface{})
+		binaryData, _ := obj["binaryData"].(map[string]interface{})
 
-		if obj["data"] == nil {
+		if len(data) == 0 && len(binaryData) == 0 {
 			glog.V(8).Infof("Data field from configmap '%s' is empty", name)
 			continue
 		}
 
-		data := obj["data"].(map[string]interface{})
-		dataMap := make(map[string]string, len(data))
+		dataMap := make(map[string]string, len(data)+len(binaryData))
 		for key, value := range data {
 			dataMap[key] = value.(string)
 		}
+		for key, value := range binaryData {
+			decoded, err := base64.StdEncoding.DecodeString(value.(string))
+			if err != nil {
+				return fmt.Errorf("couldn't base64 decode the binary key '%s' of %s with value '%v'",
+					key, resources.Describe(id), value)
+			}
+			dataMap[key] = string(decoded)
+		}
 
 		configMapArg := ktypes.ConfigMapArgs{
 			GeneratorArgs: ktypes.GeneratorArgs{
@@ -56,10 +72,21 @@ func (t *configMapTransformer) Transform(config *ktypes.Kustomization, resources
 			},
 		}
 
-		configMapArg.GeneratorArgs.DataSources = TransformDataSource(name, dataMap, resources.SourceFiles)
+		// keep the namespace so that resources with the same name in different
+		// namespaces don't collide
+		if namespace, err := res.GetFieldValue("metadata.namespace"); err == nil {
+			configMapArg.GeneratorArgs.Namespace = namespace
+		}
+
+		dataSources, err := TransformDataSource(name, dataMap, resources.SourceFiles, t.options)
+		if err != nil {
+			return err
+		}
+		configMapArg.GeneratorArgs.DataSources = dataSources
+		resources.Report.Generator(reportDataSource("ConfigMap", configMapArg.GeneratorArgs, dataMap))
 
 		config.ConfigMapGenerator = append(config.ConfigMapGenerator, configMapArg)
-		delete(resources.ResMap, res.Id())
+		delete(resources.ResMap, id)
 	}
 
 	return nil
@@ -68,7 +68,7 @@ spring.datasource.password=pass123
 										"SOME_ENV=development",
 										"somekey=not a file",
 									},
-									FileSources: []string{"configmap1-application.properties"},
+									FileSources: []string{"files/configmap1/application.properties"},
 								},
 							},
 						},
@@ -77,7 +77,7 @@ spring.datasource.password=pass123
 				resources: &types.Resources{
 					ResMap: resmap.ResMap{},
 					SourceFiles: map[string]string{
-						"configmap1-application.properties": `
+						"files/configmap1/application.properties": `
 app.name=My app
 spring.jpa.hibernate.ddl-auto=update
 spring.datasource.url=jdbc:mysql://<db_ip>:3306/db_example
@@ -88,12 +88,53 @@ spring.datasource.password=pass123
 				},
 			},
 		},
+		{
+			name: "it should convert configmaps whose namespace was moved to the kustomization",
+			input: &configMapTransformerArgs{
+				config: &ktypes.Kustomization{Namespace: "staging"},
+				resources: &types.Resources{
+					ResMap: resmap.ResMap{
+						resid.NewResIdWithPrefixNamespace(configmap, "configmap1", "", "staging"): rf.FromMap(
+							map[string]interface{}{
+								"apiVersion": "v1",
+								"kind":       "ConfigMap",
+								"metadata": map[string]interface{}{
+									"name": "configmap1",
+								},
+								"data": map[string]interface{}{
+									"somekey": "not a file",
+								},
+							}),
+					},
+					SourceFiles: map[string]string{},
+				},
+			},
+			expected: &configMapTransformerArgs{
+				config: &ktypes.Kustomization{
+					Namespace: "staging",
+					ConfigMapGenerator: []ktypes.ConfigMapArgs{
+						ktypes.ConfigMapArgs{
+							GeneratorArgs: ktypes.GeneratorArgs{
+								Name: "configmap1",
+								DataSources: ktypes.DataSources{
+									LiteralSources: []string{"somekey=not a file"},
+								},
+							},
+						},
+					},
+				},
+				resources: &types.Resources{
+					ResMap:      resmap.ResMap{},
+					SourceFiles: map[string]string{},
+				},
+			},
+		},
 	} {
 		t.Run(fmt.Sprintf("%s", test.name), func(t *testing.T) {
 			res := types.NewResources()
 			res.ResMap = test.input.resources.ResMap
 
-			lt := NewConfigMapTransformer()
+			lt := NewConfigMapTransformer(nil)
 			err := lt.Transform(test.input.config, res)
 
 			if err != nil {
@@ -1,56 +1,287 @@
 package transformers
 
 import (
+	"encoding/json"
 	"fmt"
+	"path"
+	"path/filepath"
 	"regexp"
 	"sort"
 	"strings"
+	"unicode/utf8"
 
+	"github.com/ContainerSolutions/helm-convert/pkg/types"
+	"github.com/ghodss/yaml"
 	"github.com/golang/glog"
 	ktypes "sigs.k8s.io/kustomize/pkg/types"
 )
 
-var regexpEnv = regexp.MustCompile("^[A-Z0-9_]+$")
+var (
+	regexpEnv        = regexp.MustCompile("^[A-Z0-9_]+$")
+	regexpProperties = regexp.MustCompile(`^\s*[\w.\-]+\s*[=:]`)
+	regexpINISection = regexp.MustCompile(`^\s*\[[^\]]+\]\s*$`)
+)
+
+// SourceFilesDirectory is the directory where files and environment files
+// extracted from ConfigMaps and Secrets are stored, each resource having its
+// own sub-directory
+const SourceFilesDirectory = "files"
+
+// DataSourceType define how a key of a ConfigMap or Secret is converted
+type DataSourceType string
+
+const (
+	// DataSourceLiteral store the key as literal (key=value)
+	DataSourceLiteral DataSourceType = "literal"
+
+	// DataSourceFile store the key as an external file
+	DataSourceFile DataSourceType = "file"
+
+	// DataSourceEnv store all the keys of a resource as an environment file,
+	// it can only be used as an override for all the keys ("*")
+	DataSourceEnv DataSourceType = "env"
+)
+
+// DataSourceTypes is the list of supported data source types
+var DataSourceTypes = []DataSourceType{DataSourceLiteral, DataSourceFile, DataSourceEnv}
+
+// ContentType is the type of content detected from a ConfigMap or Secret
+// value
+type ContentType string
+
+// List of detected content types
+const (
+	ContentText       ContentType = "text"
+	ContentJSON       ContentType = "json"
+	ContentYAML       ContentType = "yaml"
+	ContentProperties ContentType = "properties"
+	ContentINI        ContentType = "ini"
+	ContentPEM        ContentType = "pem"
+	ContentBinary     ContentType = "binary"
+)
+
+// fileExtensions map known file extensions to their content type
+var fileExtensions = map[string]ContentType{
+	".cer":        ContentPEM,
+	".cert":       ContentPEM,
+	".cfg":        ContentINI,
+	".conf":       ContentText,
+	".crt":        ContentPEM,
+	".ini":        ContentINI,
+	".json":       ContentJSON,
+	".key":        ContentPEM,
+	".pem":        ContentPEM,
+	".properties": ContentProperties,
+	".sh":         ContentText,
+	".toml":       ContentText,
+	".txt":        ContentText,
+	".xml":        ContentText,
+	".yaml":       ContentYAML,
+	".yml":        ContentYAML,
+}
+
+// DataSourceOptions configure how keys of ConfigMaps and Secrets are
+// classified
+type DataSourceOptions struct {
+	// MaxLiteralLength is the maximum length of a value converted as literal,
+	// longer values are converted as file. Zero means no limit.
+	MaxLiteralLength int `json:"maxLiteralLength,omitempty"`
+
+	// Overrides force the data source type of a key, the key "*" match all
+	// the keys of a resource
+	Overrides map[string]DataSourceType `json:"overrides,omitempty"`
+
+	// Resources define options for a given resource name, they take
+	// precedence over the global options
+	Resources map[string]*DataSourceOptions `json:"resources,omitempty"`
+}
+
+// Validate return an error if an override has an unknown type or forces a key
+// alone to be converted as environment file
+func (o *DataSourceOptions) Validate() error {
+	if o == nil {
+		return nil
+	}
+	if o.MaxLiteralLength < 0 {
+		return fmt.Errorf("maxLiteralLength must be positive, got %d", o.MaxLiteralLength)
+	}
+
+	keys := make([]string, 0, len(o.Overrides))
+	for key := range o.Overrides {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		t := o.Overrides[key]
+		valid := false
+		for _, dt := range DataSourceTypes {
+			if dt == t {
+				valid = true
+			}
+		}
+		switch {
+		case !valid:
+			return fmt.Errorf("unknown data source type '%s' for key '%s', expected one of %v", t, key, DataSourceTypes)
+		case t == DataSourceEnv && key != "*":
+			return fmt.Errorf("key '%s' cannot be converted as %s, an environment file contains all the keys of a resource, use \"*\": %s",
+				key, DataSourceEnv, DataSourceEnv)
+		case t == DataSourceEnv && len(o.Overrides) > 1:
+			return fmt.Errorf("\"*\": %s cannot be combined with overrides of other keys", DataSourceEnv)
+		}
+	}
+
+	names := make([]string, 0, len(o.Resources))
+	for name := range o.Resources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := o.Resources[name].Validate(); err != nil {
+			return fmt.Errorf("resource %s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// forResource return the options which apply to a given resource name
+func (o *DataSourceOptions) forResource(resourceName string) *DataSourceOptions {
+	if o == nil {
+		return &DataSourceOptions{}
+	}
+
+	options := &DataSourceOptions{
+		MaxLiteralLength: o.MaxLiteralLength,
+		Overrides:        make(map[string]DataSourceType, len(o.Overrides)),
+	}
+	for key, value := range o.Overrides {
+		options.Overrides[key] = value
+	}
+
+	if r, ok := o.Resources[resourceName]; ok && r != nil {
+		if r.MaxLiteralLength != 0 {
+			options.MaxLiteralLength = r.MaxLiteralLength
+		}
+		for key, value := range r.Overrides {
+			options.Overrides[key] = value
+		}
+	}
+
+	return options
+}
+
+// override return the data source type forced for a given key, if any
+func (o *DataSourceOptions) override(key string) (DataSourceType, bool) {
+	if t, ok := o.Overrides[key]; ok {
+		return t, true
+	}
+	if t, ok := o.Overrides["*"]; ok && t != DataSourceEnv {
+		return t, true
+	}
+	return "", false
+}
 
 // TransformDataSource return a Kustomize DataSource from a given ConfigMap.Data or
 // Secret.Data. If all keys from the resource matches an environment variable
-// format, the resource is converted as EnvFile. If keys contains a file
-// extension and the value is multiline then the file is stored as FileSources,
-// otherwise LiteralSources.
+// format, the resource is converted as EnvFile. Each other key is classified
+// based on its name and content, see ClassifyDataSource. Keys are never
+// dropped: a value which can't be stored as literal is stored as file.
+// Files are stored in a directory named after the resource, see
+// SourceDirectory. An error is returned if the resource is forced to be
+// converted as environment file but a key can't be stored in it.
 func TransformDataSource(resourceName string, input map[string]string,
-	sourceFiles map[string]string) (dataSources ktypes.DataSources) {
+	sourceFiles map[string]string, options *DataSourceOptions) (dataSources ktypes.DataSources, err error) {
 
 	if len(input) == 0 {
 		return
 	}
 
-	if isEnvFile(input) {
-		envFilename := fmt.Sprintf("%s.env", resourceName)
+	options = options.forResource(resourceName)
+	directory := SourceDirectory(resourceName, sourceFiles)
+
+	envFile, err := isEnvFile(input, options)
+	if err != nil {
+		return dataSources, fmt.Errorf("%s cannot be converted as environment file: %v", resourceName, err)
+	}
+	if envFile {
+		envFilename := path.Join(directory, fmt.Sprintf("%s.env", resourceName))
 		envFile := TransformEnvDataSource(input)
 		sourceFiles[envFilename] = envFile
 		dataSources.EnvSource = envFilename
 
 		glog.V(8).Infof("Converting '%s' as environment file with filename '%s'",
 			resourceName, envFilename)
-	} else {
-		for key, value := range TransformFileDataSource(input) {
-			filename := fmt.Sprintf("%s-%s", resourceName, key)
-			sourceFiles[filename] = value
-			dataSources.FileSources = append(dataSources.FileSources, filename)
+
+		return
+	}
+
+	keys := make([]string, 0, len(input))
+	for key := range input {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		value := input[key]
+		dataSourceType, contentType := ClassifyDataSource(key, value, options)
+
+		glog.V(8).Infof("Classifying key '%s' from resource '%s' as %s with %s content",
+			key, resourceName, dataSourceType, contentType)
+
+		if dataSourceType == DataSourceLiteral {
+			dataSources.LiteralSources = append(dataSources.LiteralSources,
+				fmt.Sprintf("%s=%s", key, value))
+			continue
 		}
-		dataSources.LiteralSources = TransformLiteralDataSource(input)
 
-		sort.Strings(dataSources.FileSources)
-		sort.Strings(dataSources.LiteralSources)
+		// add an extension matching the content if the key doesn't have one
+		// and no other key already use this name
+		name := key
+		if ext := contentTypeExtension(contentType); ext != "" && filepath.Ext(key) == "" {
+			if _, exists := input[key+ext]; !exists {
+				name = key + ext
+			}
+		}
 
-		glog.V(8).Infof("Converting %d file(s) as external file and %d literal(s) "+
-			"from resource '%s'", len(dataSources.FileSources),
-			len(dataSources.LiteralSources), resourceName)
+		filename := path.Join(directory, name)
+		sourceFiles[filename] = value
+		dataSources.FileSources = append(dataSources.FileSources, fileSource(key, filename))
 	}
 
+	sort.Strings(dataSources.FileSources)
+	sort.Strings(dataSources.LiteralSources)
+
+	glog.V(8).Infof("Converting %d file(s) as external file and %d literal(s) "+
+		"from resource '%s'", len(dataSources.FileSources),
+		len(dataSources.LiteralSources), resourceName)
+
 	return
 }
 
+// SourceDirectory return the directory where the files of a given resource are
+// stored. If the directory is already used by another resource with the same
+// name (ie: a ConfigMap and a Secret or resources from different namespaces),
+// a numeric suffix is added.
+func SourceDirectory(resourceName string, sourceFiles map[string]string) string {
+	directory := path.Join(SourceFilesDirectory, resourceName)
+	for i := 2; isDirectoryUsed(directory, sourceFiles); i++ {
+		directory = path.Join(SourceFilesDirectory, fmt.Sprintf("%s-%d", resourceName, i))
+	}
+	return directory
+}
+
+// isDirectoryUsed return true if a file from sourceFiles is in the given
+// directory
+func isDirectoryUsed(directory string, sourceFiles map[string]string) bool {
+	for filename := range sourceFiles {
+		if strings.HasPrefix(filename, directory+"/") {
+			return true
+		}
+	}
+	return false
+}
+
 // TransformEnvDataSource return an environment file from a given map
 func TransformEnvDataSource(input map[string]string) (envFile string) {
 	var envList []string
@@ -64,38 +295,137 @@ func TransformEnvDataSource(input map[string]string) (envFile string) {
 	return
 }
 
-// TransformFileDataSource return a list of files from a given map
-func TransformFileDataSource(input map[string]string) (files map[string]string) {
-	files = make(map[string]string)
-	for key, value := range input {
-		if isFileExtension(key) && isMultiline(value) {
-			files[key] = value
-		}
+// ClassifyDataSource return how a key should be stored and the type of its
+// content. Overrides take precedence, then binary, multiline, structured
+// content or keys with a known file extension are stored as file. Remaining
+// values are stored as literal unless they exceed the maximum literal length.
+func ClassifyDataSource(key, value string, options *DataSourceOptions) (DataSourceType, ContentType) {
+	if options == nil {
+		options = &DataSourceOptions{}
 	}
-	return
+
+	contentType := DetectContentType(key, value)
+
+	if t, ok := options.override(key); ok {
+		return t, contentType
+	}
+
+	switch {
+	case contentType == ContentBinary:
+		return DataSourceFile, contentType
+	case isMultiline(value):
+		return DataSourceFile, contentType
+	case isKnownFileExtension(key):
+		return DataSourceFile, contentType
+	case contentType == ContentJSON || contentType == ContentPEM:
+		return DataSourceFile, contentType
+	case options.MaxLiteralLength > 0 && len(value) > options.MaxLiteralLength:
+		return DataSourceFile, contentType
+	}
+
+	return DataSourceLiteral, contentType
 }
 
-// TransformLiteralDataSource return a list of literals (key=value) from a
-// given map
-func TransformLiteralDataSource(input map[string]string) (literal []string) {
-	for key, value := range input {
-		if !isFileExtension(key) && !isMultiline(value) {
-			literal = append(literal, fmt.Sprintf("%s=%s", key, value))
-		}
+// DetectContentType return the type of a value based on its content, the
+// extension of the key is used as a hint when the content is ambiguous
+func DetectContentType(key, value string) ContentType {
+	if !utf8.ValidString(value) || strings.ContainsRune(value, 0) {
+		return ContentBinary
 	}
-	return
+
+	trimmed := strings.TrimSpace(value)
+
+	if strings.HasPrefix(trimmed, "-----BEGIN ") {
+		return ContentPEM
+	}
+
+	if (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed)) {
+		return ContentJSON
+	}
+
+	if t, ok := fileExtensions[strings.ToLower(filepath.Ext(key))]; ok && t != ContentText {
+		return t
+	}
+
+	if !isMultiline(trimmed) {
+		return ContentText
+	}
+
+	if isINI(trimmed) {
+		return ContentINI
+	}
+
+	if isProperties(trimmed) {
+		return ContentProperties
+	}
+
+	if isYAML(trimmed) {
+		return ContentYAML
+	}
+
+	return ContentText
 }
 
 // isEnvFile return true if all the keys provided from a map match an
 // environment variable pattern (uppercase, underscore separated words and value
-// isn't multiline)
-func isEnvFile(input map[string]string) bool {
+// isn't multiline) and none of them is forced to another type. The override
+// "*" set to env force the resource to be converted as environment file, an
+// error is returned if a key can't be stored in it.
+func isEnvFile(input map[string]string, options *DataSourceOptions) (bool, error) {
+	if t, ok := options.Overrides["*"]; ok {
+		if t != DataSourceEnv {
+			return false, nil
+		}
+		if len(options.Overrides) > 1 {
+			return false, fmt.Errorf("\"*\": %s cannot be combined with overrides of other keys", DataSourceEnv)
+		}
+
+		keys := make([]string, 0, len(input))
+		for key := range input {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			switch {
+			case !isEnvVariable(key):
+				return false, fmt.Errorf("key '%s' is not an environment variable name", key)
+			case isMultiline(input[key]):
+				return false, fmt.Errorf("the value of key '%s' is multiline", key)
+			}
+		}
+		return true, nil
+	}
 	for key, value := range input {
+		if _, ok := options.Overrides[key]; ok {
+			return false, nil
+		}
 		if !isEnvVariable(key) || isMultiline(value) {
-			return false
+			return false, nil
+		}
+		if t, _ := ClassifyDataSource(key, value, options); t != DataSourceLiteral {
+			return false, nil
 		}
 	}
-	return true
+	return true, nil
+}
+
+// fileSource return a file source, the key is prefixed when it differs from
+// the name of the file so that the original key is preserved
+func fileSource(key, filename string) string {
+	if filepath.Base(filename) == key {
+		return filename
+	}
+	return fmt.Sprintf("%s=%s", key, filename)
+}
+
+// contentTypeExtension return the file extension of a given content type
+func contentTypeExtension(t ContentType) string {
+	switch t {
+	case ContentJSON, ContentYAML, ContentProperties, ContentINI, ContentPEM:
+		return "." + string(t)
+	}
+	return ""
 }
 
 // isMultiline return true if the provided value contains one of more line break
@@ -103,9 +433,11 @@ func isMultiline(s string) bool {
 	return strings.Contains(s, "\n")
 }
 
-// isFileExtension return true if the provided string contains a dot
-func isFileExtension(s string) bool {
-	return strings.Contains(s, ".")
+// isKnownFileExtension return true if the provided string ends with a known
+// file extension
+func isKnownFileExtension(s string) bool {
+	_, ok := fileExtensions[strings.ToLower(filepath.Ext(s))]
+	return ok
 }
 
 // isEnvVariable return true if the provided string match an environment
@@ -113,3 +445,96 @@ func isFileExtension(s string) bool {
 func isEnvVariable(key string) bool {
 	return regexpEnv.MatchString(key)
 }
+
+// isINI return true if the content contains at least one section header and
+// all the other significant lines are key/value pairs
+func isINI(s string) bool {
+	hasSection := false
+	for _, line := range significantLines(s) {
+		if regexpINISection.MatchString(line) {
+			hasSection = true
+			continue
+		}
+		if !regexpProperties.MatchString(line) {
+			return false
+		}
+	}
+	return hasSection
+}
+
+// isProperties return true if all significant lines are key=value pairs
+func isProperties(s string) bool {
+	lines := significantLines(s)
+	if len(lines) == 0 {
+		return false
+	}
+	for _, line := range lines {
+		if !regexpProperties.MatchString(line) || strings.HasSuffix(strings.TrimSpace(line), ":") {
+			return false
+		}
+		if !strings.Contains(line, "=") {
+			return false
+		}
+	}
+	return true
+}
+
+// isYAML return true if the content can be parsed as a YAML map or list
+func isYAML(s string) bool {
+	var out interface{}
+	if err := yaml.Unmarshal([]byte(s), &out); err != nil {
+		return false
+	}
+	switch out.(type) {
+	case map[string]interface{}, []interface{}:
+		return true
+	}
+	return false
+}
+
+// significantLines return the lines which are neither empty nor comments
+func significantLines(s string) (lines []string) {
+	for _, line := range strings.Split(s, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") || strings.HasPrefix(trimmed, ";") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return
+}
+
+// reportDataSource describe for the report how each key of a ConfigMap or
+// Secret converted to a generator is stored
+func reportDataSource(kind string, args ktypes.GeneratorArgs, input map[string]string) types.GeneratorReport {
+	report := types.GeneratorReport{Kind: kind, Name: args.Name, Namespace: args.Namespace}
+
+	keys := make([]string, 0, len(input))
+	for key := range input {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	files := make(map[string]string, len(args.FileSources))
+	for _, source := range args.FileSources {
+		if i := strings.Index(source, "="); i >= 0 {
+			files[source[:i]] = source[i+1:]
+		} else {
+			files[filepath.Base(source)] = source
+		}
+	}
+
+	for _, key := range keys {
+		ds := types.DataSourceReport{Key: key, Content: string(DetectContentType(key, input[key]))}
+		switch {
+		case args.EnvSource != "":
+			ds.Type, ds.File = string(DataSourceEnv), args.EnvSource
+		case files[key] != "":
+			ds.Type, ds.File = string(DataSourceFile), files[key]
+		default:
+			ds.Type = string(DataSourceLiteral)
+		}
+		report.Keys = append(report.Keys, ds)
+	}
+	return report
+}
@@ -2,6 +2,7 @@ package transformers
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/kylelemons/godebug/pretty"
@@ -14,8 +15,10 @@ func TestTransformDataSource(t *testing.T) {
 		resourceName       string
 		input              map[string]string
 		sourceFiles        map[string]string
+		options            *DataSourceOptions
 		expectedSourceFile map[string]string
 		expectedOutput     ktypes.DataSources
+		expectedError      string
 	}{
 		{
 			name:         "it should detect file source and literal",
@@ -28,15 +31,15 @@ func TestTransformDataSource(t *testing.T) {
 				"file1.yaml": "content",
 			},
 			expectedSourceFile: map[string]string{
-				"file1.yaml":            "content",
-				"my-configmap-name.txt": "multi\nline",
+				"file1.yaml":                  "content",
+				"files/my-configmap/name.txt": "multi\nline",
 			},
 			expectedOutput: ktypes.DataSources{
 				LiteralSources: []string{
 					"somevar=single line",
 				},
 				FileSources: []string{
-					"my-configmap-name.txt",
+					"files/my-configmap/name.txt",
 				},
 			},
 		},
@@ -51,16 +54,132 @@ func TestTransformDataSource(t *testing.T) {
 				"file1.yaml": "content",
 			},
 			expectedSourceFile: map[string]string{
-				"file1.yaml":       "content",
-				"my-configmap.env": "NODE_ENV=production\nSOMEENV=blop",
+				"file1.yaml":                          "content",
+				"files/my-configmap/my-configmap.env": "NODE_ENV=production\nSOMEENV=blop",
 			},
 			expectedOutput: ktypes.DataSources{
-				EnvSource: "my-configmap.env",
+				EnvSource: "files/my-configmap/my-configmap.env",
 			},
 		},
+		{
+			name:         "it should not reuse the directory of another resource",
+			resourceName: "my-configmap",
+			input: map[string]string{
+				"name.txt": "multi\nline",
+			},
+			sourceFiles: map[string]string{
+				"files/my-configmap/name.txt": "content",
+			},
+			expectedSourceFile: map[string]string{
+				"files/my-configmap/name.txt":   "content",
+				"files/my-configmap-2/name.txt": "multi\nline",
+			},
+			expectedOutput: ktypes.DataSources{
+				FileSources: []string{
+					"files/my-configmap-2/name.txt",
+				},
+			},
+		},
+		{
+			name:         "it should never drop a key",
+			resourceName: "my-configmap",
+			input: map[string]string{
+				"config.json": `{"debug": true}`,
+				"script":      "#!/bin/sh\necho hello",
+				"settings":    "[server]\nport=8080",
+				"binary":      "\x00\x01",
+			},
+			sourceFiles: map[string]string{},
+			expectedSourceFile: map[string]string{
+				"files/my-configmap/binary":       "\x00\x01",
+				"files/my-configmap/config.json":  `{"debug": true}`,
+				"files/my-configmap/script":       "#!/bin/sh\necho hello",
+				"files/my-configmap/settings.ini": "[server]\nport=8080",
+			},
+			expectedOutput: ktypes.DataSources{
+				FileSources: []string{
+					"files/my-configmap/binary",
+					"files/my-configmap/config.json",
+					"files/my-configmap/script",
+					"settings=files/my-configmap/settings.ini",
+				},
+			},
+		},
+		{
+			name:         "it should apply per resource threshold and overrides",
+			resourceName: "my-configmap",
+			input: map[string]string{
+				"short":    "abc",
+				"long":     "abcdefghijkl",
+				"app.yaml": "enabled: true",
+			},
+			sourceFiles: map[string]string{},
+			options: &DataSourceOptions{
+				MaxLiteralLength: 100,
+				Resources: map[string]*DataSourceOptions{
+					"my-configmap": &DataSourceOptions{
+						MaxLiteralLength: 5,
+						Overrides: map[string]DataSourceType{
+							"app.yaml": DataSourceLiteral,
+						},
+					},
+				},
+			},
+			expectedSourceFile: map[string]string{
+				"files/my-configmap/long": "abcdefghijkl",
+			},
+			expectedOutput: ktypes.DataSources{
+				LiteralSources: []string{
+					"app.yaml=enabled: true",
+					"short=abc",
+				},
+				FileSources: []string{
+					"files/my-configmap/long",
+				},
+			},
+		},
+		{
+			name:         "it should fail to force a multiline value in an env file",
+			resourceName: "my-configmap",
+			input: map[string]string{
+				"NODE_ENV": "production",
+				"SCRIPT":   "echo a\necho b",
+			},
+			sourceFiles: map[string]string{},
+			options: &DataSourceOptions{
+				Overrides: map[string]DataSourceType{"*": DataSourceEnv},
+			},
+			expectedError: "my-configmap cannot be converted as environment file: the value of key 'SCRIPT' is multiline",
+		},
+		{
+			name:         "it should fail to force a key which is not a variable name in an env file",
+			resourceName: "my-configmap",
+			input: map[string]string{
+				"NODE_ENV": "production",
+				"app.conf": "debug",
+			},
+			sourceFiles: map[string]string{},
+			options: &DataSourceOptions{
+				Resources: map[string]*DataSourceOptions{
+					"my-configmap": &DataSourceOptions{
+						Overrides: map[string]DataSourceType{"*": DataSourceEnv},
+					},
+				},
+			},
+			expectedError: "key 'app.conf' is not an environment variable name",
+		},
 	} {
 		t.Run(fmt.Sprintf("%s", test.name), func(t *testing.T) {
-			output := TransformDataSource(test.resourceName, test.input, test.sourceFiles)
+			output, err := TransformDataSource(test.resourceName, test.input, test.sourceFiles, test.options)
+			if test.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedError) {
+					t.Fatalf("%s: expected error %q, got %v", test.name, test.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
 			if diff := pretty.Compare(output, test.expectedOutput); diff != "" {
 				t.Errorf("%s, diff: (-got +want)\n%s", test.name, diff)
 			}
@@ -70,3 +189,135 @@ func TestTransformDataSource(t *testing.T) {
 		})
 	}
 }
+
+func TestValidateDataSourceOptions(t *testing.T) {
+	for _, test := range []struct {
+		name          string
+		options       *DataSourceOptions
+		expectedError string
+	}{
+		{
+			name: "it should accept valid overrides",
+			options: &DataSourceOptions{
+				Overrides: map[string]DataSourceType{"*": DataSourceLiteral, "config": DataSourceFile},
+				Resources: map[string]*DataSourceOptions{
+					"my-secret": &DataSourceOptions{
+						Overrides: map[string]DataSourceType{"*": DataSourceEnv},
+					},
+				},
+			},
+		},
+		{
+			name: "it should reject an unknown type",
+			options: &DataSourceOptions{
+				Overrides: map[string]DataSourceType{"config": "files"},
+			},
+			expectedError: "unknown data source type 'files' for key 'config', expected one of [literal file env]",
+		},
+		{
+			name: "it should reject an env override of a single key",
+			options: &DataSourceOptions{
+				Resources: map[string]*DataSourceOptions{
+					"my-configmap": &DataSourceOptions{
+						Overrides: map[string]DataSourceType{"NODE_ENV": DataSourceEnv},
+					},
+				},
+			},
+			expectedError: "resource my-configmap: key 'NODE_ENV' cannot be converted as env",
+		},
+		{
+			name: "it should reject an env override combined with other overrides",
+			options: &DataSourceOptions{
+				Overrides: map[string]DataSourceType{"*": DataSourceEnv, "config": DataSourceFile},
+			},
+			expectedError: `"*": env cannot be combined with overrides of other keys`,
+		},
+		{
+			name:          "it should reject a negative threshold",
+			options:       &DataSourceOptions{MaxLiteralLength: -1},
+			expectedError: "maxLiteralLength must be positive, got -1",
+		},
+	} {
+		t.Run(fmt.Sprintf("%s", test.name), func(t *testing.T) {
+			err := test.options.Validate()
+			if test.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedError) {
+					t.Fatalf("%s: expected error %q, got %v", test.name, test.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestDetectContentType(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		key      string
+		value    string
+		expected ContentType
+	}{
+		{
+			name:     "it should detect json",
+			key:      "config",
+			value:    `{"a": [1, 2]}`,
+			expected: ContentJSON,
+		},
+		{
+			name:     "it should detect yaml",
+			key:      "config",
+			value:    "a:\n  b: c\n",
+			expected: ContentYAML,
+		},
+		{
+			name:     "it should detect properties",
+			key:      "config",
+			value:    "# comment\na.b=c\nd=e\n",
+			expected: ContentProperties,
+		},
+		{
+			name:     "it should detect ini",
+			key:      "config",
+			value:    "[section]\na = b\n",
+			expected: ContentINI,
+		},
+		{
+			name:     "it should detect pem",
+			key:      "ca",
+			value:    "-----BEGIN CERTIFICATE-----\nabc\n-----END CERTIFICATE-----\n",
+			expected: ContentPEM,
+		},
+		{
+			name:     "it should detect binary",
+			key:      "data",
+			value:    string([]byte{0xff, 0xfe, 0x00}),
+			expected: ContentBinary,
+		},
+		{
+			name:     "it should use the key extension as hint",
+			key:      "values.yaml",
+			value:    "enabled: true",
+			expected: ContentYAML,
+		},
+		{
+			name:     "it should fallback to text",
+			key:      "motd",
+			value:    "hello world",
+			expected: ContentText,
+		},
+	} {
+		t.Run(fmt.Sprintf("%s", test.name), func(t *testing.T) {
+			output := DetectContentType(test.key, test.value)
+			if output != test.expected {
+				t.Fatalf(
+					"expected: \n %v\ngot:\n %v",
+					test.expected,
+					output,
+				)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package transformers
+
+// kubernetesDefaults contains the default values set by the Kubernetes API
+// server for known kinds. Keys are dot separated paths relative to the root of
+// the resource, a path segment suffixed by [] is applied to each item of a
+// list.
+var kubernetesDefaults = map[string]map[string]interface{}{
+	"CronJob": {
+		"spec.concurrencyPolicy":          "Allow",
+		"spec.suspend":                    false,
+		"spec.successfulJobsHistoryLimit": 3,
+		"spec.failedJobsHistoryLimit":     1,
+	},
+	"DaemonSet": {
+		"spec.revisionHistoryLimit": 10,
+		"spec.updateStrategy.type":  "RollingUpdate",
+	},
+	"Deployment": {
+		"spec.progressDeadlineSeconds":               600,
+		"spec.revisionHistoryLimit":                  10,
+		"spec.strategy.type":                         "RollingUpdate",
+		"spec.strategy.rollingUpdate.maxSurge":       "25%",
+		"spec.strategy.rollingUpdate.maxUnavailable": "25%",
+	},
+	"Job": {
+		"spec.completions":  1,
+		"spec.parallelism":  1,
+		"spec.backoffLimit": 6,
+	},
+	"PersistentVolumeClaim": {
+		"spec.volumeMode": "Filesystem",
+	},
+	"Service": {
+		"spec.sessionAffinity":  "None",
+		"spec.type":             "ClusterIP",
+		"spec.ports[].protocol": "TCP",
+	},
+	"StatefulSet": {
+		"spec.podManagementPolicy":  "OrderedReady",
+		"spec.revisionHistoryLimit": 10,
+		"spec.updateStrategy.type":  "RollingUpdate",
+	},
+}
+
+// podSpecDefaults contains the default values of a PodSpec, paths are relative
+// to the PodSpec.
+var podSpecDefaults = map[string]interface{}{
+	"dnsPolicy":                                    "ClusterFirst",
+	"restartPolicy":                                "Always",
+	"schedulerName":                                "default-scheduler",
+	"terminationGracePeriodSeconds":                30,
+	"containers[].terminationMessagePath":          "/dev/termination-log",
+	"containers[].terminationMessagePolicy":        "File",
+	"containers[].ports[].protocol":                "TCP",
+	"initContainers[].terminationMessagePath":      "/dev/termination-log",
+	"initContainers[].terminationMessagePolicy":    "File",
+	"initContainers[].ports[].protocol":            "TCP",
+	"volumes[].configMap.defaultMode":              420,
+	"volumes[].secret.defaultMode":                 420,
+	"volumes[].projected.defaultMode":              420,
+	"volumes[].downwardAPI.defaultMode":            420,
+	"containers[].livenessProbe.failureThreshold":  3,
+	"containers[].livenessProbe.periodSeconds":     10,
+	"containers[].livenessProbe.successThreshold":  1,
+	"containers[].livenessProbe.timeoutSeconds":    1,
+	"containers[].readinessProbe.failureThreshold": 3,
+	"containers[].readinessProbe.periodSeconds":    10,
+	"containers[].readinessProbe.successThreshold": 1,
+	"containers[].readinessProbe.timeoutSeconds":   1,
+}
+
+// podSpecPaths map a kind to the path of its PodSpec
+var podSpecPaths = map[string]string{
+	"CronJob":               "spec.jobTemplate.spec.template.spec",
+	"DaemonSet":             "spec.template.spec",
+	"Deployment":            "spec.template.spec",
+	"Job":                   "spec.template.spec",
+	"Pod":                   "spec",
+	"ReplicaSet":            "spec.template.spec",
+	"ReplicationController": "spec.template.spec",
+	"StatefulSet":           "spec.template.spec",
+}
+
+// serverSideFields are fields populated by the API server which should never
+// be part of a manifest
+var serverSideFields = []string{
+	"status",
+	"metadata.creationTimestamp",
+	"metadata.generation",
+	"metadata.managedFields",
+	"metadata.resourceVersion",
+	"metadata.selfLink",
+	"metadata.uid",
+}
+
+// preservedEmptyFields are keys for which an empty value has a meaning and
+// should therefore not be removed
+var preservedEmptyFields = map[string]struct{}{
+	"emptyDir":          {},
+	"namespaceSelector": {},
+	"podSelector":       {},
+}
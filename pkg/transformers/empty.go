@@ -30,6 +30,10 @@ func (t *emptyTransformer) Transform(config *ktypes.Kustomization, resources *ty
 
 func (t *emptyTransformer) emptyRecursive(obj map[string]interface{}) (bool, error) {
 	for key := range obj {
+		if _, ok := preservedEmptyFields[key]; ok {
+			continue
+		}
+
 		switch typedV := obj[key].(type) {
 		case map[string]interface{}:
 			if len(typedV) == 0 {
@@ -0,0 +1,189 @@
+package transformers
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ContainerSolutions/helm-convert/pkg/types"
+	"github.com/ghodss/yaml"
+	"github.com/kylelemons/godebug/diff"
+	ktypes "sigs.k8s.io/kustomize/pkg/types"
+)
+
+// explainContext is the number of unchanged lines around each change
+const explainContext = 3
+
+// Explanation is the change made by a transformer
+type Explanation struct {
+	// Step is the position of the transformer in the pipeline, from 1
+	Step int
+
+	// Name of the transformer, ie: configmap
+	Name string
+
+	// Duration of the transformation
+	Duration time.Duration
+
+	// Diff is the unified diff of the kustomization, the resources and the
+	// source files, empty if the transformer didn't change anything
+	Diff string
+}
+
+// Explainer receives the explanation of each transformer
+type Explainer func(*Explanation) error
+
+// NewExplainMultiTransformer constructs a multiTransformer which snapshot
+// the kustomization and the resources before and after each transformer, the
+// differences are given to the explainer.
+func NewExplainMultiTransformer(t []Transformer, explainer Explainer) Transformer {
+	r := NewMultiTransformer(t).(*multiTransformer)
+	r.explainer = explainer
+	return r
+}
+
+// snapshot return the kustomization, each resource and each source file as
+// YAML documents keyed by a path
+func snapshot(config *ktypes.Kustomization, resources *types.Resources) map[string]string {
+	docs := make(map[string]string, len(resources.ResMap)+len(resources.SourceFiles)+1)
+	docs["kustomization.yaml"] = marshalSnapshot(config)
+	for id, res := range resources.ResMap {
+		docs["resources/"+types.ResourceRef(id)+".yaml"] = marshalSnapshot(res.Map())
+	}
+	for filename, content := range resources.SourceFiles {
+		docs[filename] = content
+	}
+	return docs
+}
+
+func marshalSnapshot(obj interface{}) string {
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		return fmt.Sprintf("# %v\n", err)
+	}
+	return string(data)
+}
+
+// snapshotDiff return the unified diff between two snapshots
+func snapshotDiff(before, after map[string]string) string {
+	names := make([]string, 0, len(before)+len(after))
+	for name := range before {
+		names = append(names, name)
+	}
+	for name := range after {
+		if _, ok := before[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var b bytes.Buffer
+	for _, name := range names {
+		a, inBefore := before[name]
+		c, inAfter := after[name]
+		if inBefore && inAfter && a == c {
+			continue
+		}
+
+		from, to := "a/"+name, "b/"+name
+		if !inBefore {
+			from = "/dev/null"
+		}
+		if !inAfter {
+			to = "/dev/null"
+		}
+		fmt.Fprintf(&b, "--- %s\n+++ %s\n", from, to)
+		b.WriteString(unifiedDiff(a, c))
+	}
+	return b.String()
+}
+
+type diffLine struct {
+	op   byte
+	text string
+}
+
+// unifiedDiff return the hunks of the unified diff between two texts
+func unifiedDiff(a, b string) string {
+	var lines []diffLine
+	for _, c := range diff.DiffChunks(splitLines(a), splitLines(b)) {
+		for _, line := range c.Deleted {
+			lines = append(lines, diffLine{'-', line})
+		}
+		for _, line := range c.Added {
+			lines = append(lines, diffLine{'+', line})
+		}
+		for _, line := range c.Equal {
+			lines = append(lines, diffLine{' ', line})
+		}
+	}
+
+	var out bytes.Buffer
+	for start := 0; start < len(lines); {
+		// find the next change and extend the hunk until the changes are
+		// separated by more than twice the context
+		first := start
+		for first < len(lines) && lines[first].op == ' ' {
+			first++
+		}
+		if first == len(lines) {
+			break
+		}
+		last := first
+		for i := first; i < len(lines) && i <= last+2*explainContext; i++ {
+			if lines[i].op != ' ' {
+				last = i
+			}
+		}
+
+		from := first - explainContext
+		if from < start {
+			from = start
+		}
+		to := last + explainContext + 1
+		if to > len(lines) {
+			to = len(lines)
+		}
+
+		aStart, bStart := 1, 1
+		for _, line := range lines[:from] {
+			if line.op != '+' {
+				aStart++
+			}
+			if line.op != '-' {
+				bStart++
+			}
+		}
+		aLen, bLen := 0, 0
+		for _, line := range lines[from:to] {
+			if line.op != '+' {
+				aLen++
+			}
+			if line.op != '-' {
+				bLen++
+			}
+		}
+		if aLen == 0 {
+			aStart--
+		}
+		if bLen == 0 {
+			bStart--
+		}
+
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", aStart, aLen, bStart, bLen)
+		for _, line := range lines[from:to] {
+			fmt.Fprintf(&out, "%c%s\n", line.op, line.text)
+		}
+		start = to
+	}
+	return out.String()
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
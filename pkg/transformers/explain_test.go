@@ -0,0 +1,128 @@
+package transformers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ContainerSolutions/helm-convert/pkg/types"
+	"github.com/kylelemons/godebug/pretty"
+	"sigs.k8s.io/kustomize/k8sdeps/kunstruct"
+	"sigs.k8s.io/kustomize/pkg/gvk"
+	"sigs.k8s.io/kustomize/pkg/resid"
+	"sigs.k8s.io/kustomize/pkg/resource"
+	ktypes "sigs.k8s.io/kustomize/pkg/types"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		a        string
+		b        string
+		expected string
+	}{
+		{
+			name: "it should group the changes in hunks with context",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n14\n15\n",
+			b:    "1\nx\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n13\n15\n16\n",
+			expected: `@@ -1,5 +1,5 @@
+ 1
+-2
++x
+ 3
+ 4
+ 5
+@@ -11,5 +11,5 @@
+ 11
+ 12
+ 13
+-14
+ 15
++16
+`,
+		},
+		{
+			name:     "it should diff a new file",
+			a:        "",
+			b:        "a\nb\n",
+			expected: "@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name: "it should return nothing for identical texts",
+			a:    "a\n",
+			b:    "a\n",
+		},
+	} {
+		t.Run(fmt.Sprintf("%s", test.name), func(t *testing.T) {
+			output := unifiedDiff(test.a, test.b)
+			if diff := pretty.Compare(output, test.expected); diff != "" {
+				t.Errorf("%s, diff: (-got +want)\n%s", test.name, diff)
+			}
+		})
+	}
+}
+
+func TestExplainMultiTransformer(t *testing.T) {
+	var rf = resource.NewFactory(kunstruct.NewKunstructuredFactoryImpl())
+	var service = gvk.Gvk{Version: "v1", Kind: "Service"}
+
+	for _, test := range []struct {
+		name     string
+		expected []*Explanation
+	}{
+		{
+			name: "it should explain the changes made by each transformer",
+			expected: []*Explanation{
+				{
+					Step: 1,
+					Name: "namespace",
+					Diff: `--- a/kustomization.yaml
++++ b/kustomization.yaml
+@@ -1,1 +1,1 @@
+-{}
++namespace: web
+--- a/resources/Service/web/web.yaml
++++ b/resources/Service/web/web.yaml
+@@ -2,4 +2,3 @@
+ kind: Service
+ metadata:
+   name: web
+-  namespace: web
+`,
+				},
+				{
+					Step: 2,
+					Name: "empty",
+				},
+			},
+		},
+	} {
+		t.Run(fmt.Sprintf("%s", test.name), func(t *testing.T) {
+			resources := types.NewResources()
+			resources.ResMap[resid.NewResIdWithPrefixNamespace(service, "web", "", "web")] = rf.FromMap(
+				map[string]interface{}{
+					"apiVersion": "v1",
+					"kind":       "Service",
+					"metadata":   map[string]interface{}{"name": "web", "namespace": "web"},
+				})
+
+			var output []*Explanation
+			mt := NewExplainMultiTransformer([]Transformer{
+				NewNamespaceTransformer(),
+				NewEmptyTransformer(),
+			}, func(e *Explanation) error {
+				e.Duration = 0
+				output = append(output, e)
+				return nil
+			})
+
+			err := mt.Transform(&ktypes.Kustomization{}, resources)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if diff := pretty.Compare(output, test.expected); diff != "" {
+				t.Errorf("%s, diff: (-got +want)\n%s", test.name, diff)
+			}
+		})
+	}
+}
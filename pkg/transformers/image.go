@@ -33,6 +33,9 @@ func (pt *imageTransformer) Transform(config *ktypes.Kustomization, resources *t
 	sort.Slice(config.Images, func(i, j int) bool {
 		return imageString(config.Images[i]) < imageString(config.Images[j])
 	})
+	for _, image := range config.Images {
+		resources.Report.Image(imageString(image))
+	}
 
 	return nil
 }
@@ -100,19 +100,16 @@ RESOURCES_LOOP:
 	}
 
 	config.CommonLabels = commonLabels
+	resources.Report.CommonLabels(commonLabels)
 
 	return nil
 }
 
 func (t *labelsTransformer) removeLabels(config *ktypes.Kustomization, resources *types.Resources) error {
 	paths := []string{"matchLabels", "labels", "selector"}
-	for id := range resources.ResMap {
+	for _, id := range utils.SortedResIds(resources.ResMap) {
 		obj := resources.ResMap[id].Map()
-		for _, path := range paths {
-			for _, key := range t.keys {
-				utils.RecursivelyRemoveKey(path, key, obj)
-			}
-		}
+		removeKeys(resources.Report.LabelRemoved, id, paths, t.keys, obj)
 	}
 	return nil
 }
@@ -1,33 +1,89 @@
 package transformers
 
 import (
+	"time"
+
 	"github.com/ContainerSolutions/helm-convert/pkg/types"
 	ktypes "sigs.k8s.io/kustomize/pkg/types"
 )
 
 // multiTransformer contains a list of transformers
 type multiTransformer struct {
-	transformers []Transformer
+	transformers []TransformerV2
+	explainer    Explainer
 }
 
 var _ Transformer = &multiTransformer{}
+var _ TransformerV2 = &multiTransformer{}
 
 // NewMultiTransformer constructs a multiTransformer
 func NewMultiTransformer(t []Transformer) Transformer {
 	r := &multiTransformer{
-		transformers: make([]Transformer, len(t)),
+		transformers: make([]TransformerV2, len(t)),
+	}
+	for i := range t {
+		r.transformers[i] = AdaptTransformer(t[i])
+	}
+	return r
+}
+
+// NewMultiTransformerV2 constructs a multiTransformer running transformers
+// with a context, the explainer is optional
+func NewMultiTransformerV2(t []TransformerV2, explainer Explainer) TransformerV2 {
+	r := &multiTransformer{
+		transformers: make([]TransformerV2, len(t)),
+		explainer:    explainer,
 	}
 	copy(r.transformers, t)
 	return r
 }
 
-// Transform prepends the name prefix
+// Transform run each transformer in order with an empty context, the
+// artifacts are discarded
 func (o *multiTransformer) Transform(config *ktypes.Kustomization, resources *types.Resources) error {
-	for _, t := range o.transformers {
-		err := t.Transform(config, resources)
+	_, err := o.Apply(NewContext(nil, "", "", nil), config, resources)
+	return err
+}
+
+// Apply run each transformer in order and merge their results, the changes
+// and warnings of each transformer are recorded in its own section of the
+// report
+func (o *multiTransformer) Apply(ctx *Context, config *ktypes.Kustomization, resources *types.Resources) (*Result, error) {
+	result := &Result{Artifacts: types.NewArtifacts()}
+	for i, t := range o.transformers {
+		name := Name(t)
+
+		var before map[string]string
+		if o.explainer != nil {
+			before = snapshot(config, resources)
+		}
+		start := time.Now()
+
+		resources.Report.Begin(name)
+		r, err := t.Apply(ctx.withLogger("["+name+"] "), config, resources)
+		if err == nil && r != nil {
+			for _, warning := range r.Warnings {
+				resources.Report.Warningf("[%s] %s", name, warning)
+			}
+			result.Warnings = append(result.Warnings, r.Warnings...)
+			result.Artifacts.Merge(r.Artifacts)
+		}
+		resources.Report.End()
 		if err != nil {
-			return err
+			return nil, err
+		}
+
+		if o.explainer != nil {
+			err = o.explainer(&Explanation{
+				Step:     i + 1,
+				Name:     name,
+				Duration: time.Since(start),
+				Diff:     snapshotDiff(before, snapshot(config, resources)),
+			})
+			if err != nil {
+				return nil, err
+			}
 		}
 	}
-	return nil
+	return result, nil
 }
@@ -0,0 +1,128 @@
+package transformers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ContainerSolutions/helm-convert/pkg/types"
+	"github.com/kylelemons/godebug/pretty"
+	"sigs.k8s.io/kustomize/k8sdeps/kunstruct"
+	"sigs.k8s.io/kustomize/pkg/gvk"
+	"sigs.k8s.io/kustomize/pkg/resid"
+	"sigs.k8s.io/kustomize/pkg/resource"
+	ktypes "sigs.k8s.io/kustomize/pkg/types"
+)
+
+func TestMultiTransformerReport(t *testing.T) {
+	var rf = resource.NewFactory(kunstruct.NewKunstructuredFactoryImpl())
+	var deploy = gvk.Gvk{Group: "apps", Version: "v1", Kind: "Deployment"}
+	var configMap = gvk.Gvk{Version: "v1", Kind: "ConfigMap"}
+
+	newResources := func() *types.Resources {
+		resources := types.NewResources()
+		resources.ResMap[resid.NewResId(deploy, "web")] = rf.FromMap(map[string]interface{}{
+			"apiVersion": "apps/v1",
+			"kind":       "Deployment",
+			"metadata": map[string]interface{}{
+				"name":        "web",
+				"labels":      map[string]interface{}{"app": "web", "heritage": "Tiller"},
+				"annotations": map[string]interface{}{"helm.sh/hook-weight": "1"},
+			},
+			"spec": map[string]interface{}{
+				"template": map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"labels": map[string]interface{}{"app": "web", "heritage": "Tiller"},
+					},
+					"spec": map[string]interface{}{
+						"containers": []interface{}{
+							map[string]interface{}{"name": "web", "image": "nginx:1.15"},
+						},
+					},
+				},
+			},
+		})
+		resources.ResMap[resid.NewResId(configMap, "web")] = rf.FromMap(map[string]interface{}{
+			"apiVersion": "v1",
+			"kind":       "ConfigMap",
+			"metadata": map[string]interface{}{
+				"name":   "web",
+				"labels": map[string]interface{}{"app": "web"},
+			},
+			"data": map[string]interface{}{
+				"mode":        "production",
+				"config.json": "{\"debug\": false}",
+			},
+		})
+		return resources
+	}
+
+	for _, test := range []struct {
+		name     string
+		report   *types.Report
+		expected *types.Report
+	}{
+		{
+			name:   "it should record the changes of each transformer",
+			report: types.NewReport("web", "1.0.0"),
+			expected: &types.Report{
+				Chart:   "web",
+				Version: "1.0.0",
+				Transformers: []*types.TransformerReport{
+					{
+						Name: "labels",
+						LabelsRemoved: []types.Removal{
+							{Resource: "Deployment/web", Key: "heritage", Value: "Tiller"},
+						},
+						CommonLabels: map[string]string{"app": "web"},
+					},
+					{
+						Name: "annotations",
+						AnnotationsRemoved: []types.Removal{
+							{Resource: "Deployment/web", Key: "helm.sh/hook-weight", Value: "1"},
+						},
+					},
+					{
+						Name:   "image",
+						Images: []string{"nginx:1.15"},
+					},
+					{
+						Name: "configmap",
+						Generators: []types.GeneratorReport{
+							{
+								Kind: "ConfigMap",
+								Name: "web",
+								Keys: []types.DataSourceReport{
+									{Key: "config.json", Type: "file", Content: "json", File: "files/web/config.json"},
+									{Key: "mode", Type: "literal", Content: "text"},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "it should transform the resources without report",
+		},
+	} {
+		t.Run(fmt.Sprintf("%s", test.name), func(t *testing.T) {
+			resources := newResources()
+			resources.Report = test.report
+
+			mt := NewMultiTransformer([]Transformer{
+				NewLabelsTransformer([]string{"heritage"}),
+				NewAnnotationsTransformer([]string{"helm.sh/hook-weight"}, LifecycleNone),
+				NewImageTransformer(),
+				NewConfigMapTransformer(nil),
+			})
+			err := mt.Transform(&ktypes.Kustomization{}, resources)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if diff := pretty.Compare(test.report, test.expected); diff != "" {
+				t.Errorf("%s, diff: (-got +want)\n%s", test.name, diff)
+			}
+		})
+	}
+}
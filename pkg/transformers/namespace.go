@@ -16,20 +16,7 @@ func NewNamespaceTransformer() Transformer {
 
 // Transform set the namespace if all resources have the same namespace
 func (t *namespaceTransformer) Transform(config *ktypes.Kustomization, resources *types.Resources) error {
-	var namespace string
-	for _, res := range resources.ResMap {
-		resNamespace, err := res.GetFieldValue("metadata.namespace")
-		if err != nil {
-			continue
-		}
-
-		if namespace != "" && namespace != resNamespace {
-			return nil
-		}
-
-		namespace = resNamespace
-	}
-
+	namespace := CommonNamespace(resources)
 	if namespace != "" {
 		// Delete the namespace key if it is globally set
 		for id, res := range resources.ResMap {
@@ -44,7 +31,27 @@ func (t *namespaceTransformer) Transform(config *ktypes.Kustomization, resources
 		}
 
 		config.Namespace = namespace
+		resources.Report.Namespace(namespace)
 	}
 
 	return nil
 }
+
+// CommonNamespace return the namespace of the resources if all the resources
+// having a namespace share it, an empty string otherwise
+func CommonNamespace(resources *types.Resources) string {
+	var namespace string
+	for _, res := range resources.ResMap {
+		resNamespace, err := res.GetFieldValue("metadata.namespace")
+		if err != nil {
+			continue
+		}
+
+		if namespace != "" && namespace != resNamespace {
+			return ""
+		}
+
+		namespace = resNamespace
+	}
+	return namespace
+}
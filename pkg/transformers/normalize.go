@@ -0,0 +1,123 @@
+package transformers
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ContainerSolutions/helm-convert/pkg/types"
+	ktypes "sigs.k8s.io/kustomize/pkg/types"
+)
+
+type normalizeTransformer struct{}
+
+var _ Transformer = &normalizeTransformer{}
+
+// NewNormalizeTransformer constructs a normalizeTransformer.
+func NewNormalizeTransformer() Transformer {
+	return &normalizeTransformer{}
+}
+
+// Transform removes server-side fields, fields equal to their Kubernetes
+// default value and empty maps and lists from manifests
+func (t *normalizeTransformer) Transform(config *ktypes.Kustomization, resources *types.Resources) error {
+	for id := range resources.ResMap {
+		obj := resources.ResMap[id].Map()
+
+		for _, path := range serverSideFields {
+			removeDefault(obj, strings.Split(path, "."), nil, true)
+		}
+
+		kind, _ := obj["kind"].(string)
+
+		for path, value := range kubernetesDefaults[kind] {
+			removeDefault(obj, strings.Split(path, "."), value, false)
+		}
+
+		if podSpecPath, ok := podSpecPaths[kind]; ok {
+			for path, value := range podSpecDefaults {
+				removeDefault(obj, strings.Split(podSpecPath+"."+path, "."), value, false)
+			}
+		}
+
+		pruneEmpty(obj)
+	}
+
+	return nil
+}
+
+// removeDefault deletes the field at the given path if its value equals the
+// provided default value. If force is true, the field is deleted whatever its
+// value.
+func removeDefault(obj map[string]interface{}, path []string, value interface{}, force bool) {
+	key := path[0]
+	isList := strings.HasSuffix(key, "[]")
+	key = strings.TrimSuffix(key, "[]")
+
+	current, found := obj[key]
+	if !found {
+		return
+	}
+
+	if len(path) == 1 {
+		if force || isDefaultValue(current, value) {
+			delete(obj, key)
+		}
+		return
+	}
+
+	if !isList {
+		if m, ok := current.(map[string]interface{}); ok {
+			removeDefault(m, path[1:], value, force)
+		}
+		return
+	}
+
+	if list, ok := current.([]interface{}); ok {
+		for _, item := range list {
+			if m, ok := item.(map[string]interface{}); ok {
+				removeDefault(m, path[1:], value, force)
+			}
+		}
+	}
+}
+
+// isDefaultValue compare a manifest value with a default value, numbers are
+// compared by their string representation since they can either be decoded as
+// integers or floats
+func isDefaultValue(current, value interface{}) bool {
+	switch current.(type) {
+	case map[string]interface{}, []interface{}:
+		return false
+	}
+	return fmt.Sprint(current) == fmt.Sprint(value)
+}
+
+// pruneEmpty recursively removes nil values, empty maps and empty lists. Items
+// of a list are never removed since an empty item can be meaningful (ie: allow
+// all rule in a NetworkPolicy).
+func pruneEmpty(obj map[string]interface{}) {
+	for key := range obj {
+		if _, ok := preservedEmptyFields[key]; ok {
+			continue
+		}
+
+		switch typedV := obj[key].(type) {
+		case map[string]interface{}:
+			pruneEmpty(typedV)
+			if len(typedV) == 0 {
+				delete(obj, key)
+			}
+		case []interface{}:
+			for _, item := range typedV {
+				if m, ok := item.(map[string]interface{}); ok {
+					pruneEmpty(m)
+				}
+			}
+			if len(typedV) == 0 {
+				delete(obj, key)
+			}
+		case nil:
+			delete(obj, key)
+		}
+	}
+}
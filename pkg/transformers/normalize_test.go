@@ -0,0 +1,176 @@
+package transformers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ContainerSolutions/helm-convert/pkg/types"
+	"github.com/kylelemons/godebug/pretty"
+	"sigs.k8s.io/kustomize/k8sdeps/kunstruct"
+	"sigs.k8s.io/kustomize/pkg/gvk"
+	"sigs.k8s.io/kustomize/pkg/resid"
+	"sigs.k8s.io/kustomize/pkg/resmap"
+	"sigs.k8s.io/kustomize/pkg/resource"
+	ktypes "sigs.k8s.io/kustomize/pkg/types"
+)
+
+type normalizeTransformerArgs struct {
+	config    *ktypes.Kustomization
+	resources *types.Resources
+}
+
+func TestNormalizeRun(t *testing.T) {
+	var service = gvk.Gvk{Version: "v1", Kind: "Service"}
+	var deploy = gvk.Gvk{Group: "apps", Version: "v1", Kind: "Deployment"}
+	var rf = resource.NewFactory(kunstruct.NewKunstructuredFactoryImpl())
+
+	for _, test := range []struct {
+		name     string
+		input    *normalizeTransformerArgs
+		expected *normalizeTransformerArgs
+	}{
+		{
+			name: "it should remove server-side fields, default values and empty values",
+			input: &normalizeTransformerArgs{
+				config: &ktypes.Kustomization{},
+				resources: &types.Resources{
+					ResMap: resmap.ResMap{
+						resid.NewResId(service, "service1"): rf.FromMap(
+							map[string]interface{}{
+								"apiVersion": "v1",
+								"kind":       "Service",
+								"metadata": map[string]interface{}{
+									"name":              "service1",
+									"creationTimestamp": nil,
+								},
+								"spec": map[string]interface{}{
+									"type":            "ClusterIP",
+									"sessionAffinity": "None",
+									"ports": []interface{}{
+										map[string]interface{}{
+											"port":     float64(80),
+											"protocol": "TCP",
+										},
+										map[string]interface{}{
+											"port":     float64(53),
+											"protocol": "UDP",
+										},
+									},
+									"externalIPs": []interface{}{},
+								},
+								"status": map[string]interface{}{
+									"loadBalancer": map[string]interface{}{},
+								},
+							}),
+						resid.NewResId(deploy, "deploy1"): rf.FromMap(
+							map[string]interface{}{
+								"apiVersion": "apps/v1",
+								"kind":       "Deployment",
+								"metadata": map[string]interface{}{
+									"name": "deploy1",
+								},
+								"spec": map[string]interface{}{
+									"replicas":             float64(1),
+									"revisionHistoryLimit": float64(10),
+									"strategy":             map[string]interface{}{},
+									"template": map[string]interface{}{
+										"metadata": map[string]interface{}{
+											"creationTimestamp": nil,
+										},
+										"spec": map[string]interface{}{
+											"dnsPolicy":                     "ClusterFirst",
+											"terminationGracePeriodSeconds": 30,
+											"containers": []interface{}{
+												map[string]interface{}{
+													"name":                     "container-1",
+													"terminationMessagePath":   "/dev/termination-log",
+													"terminationMessagePolicy": "FallbackToLogsOnError",
+													"args":                     []interface{}{},
+												},
+											},
+											"volumes": []interface{}{
+												map[string]interface{}{
+													"name":     "tmp",
+													"emptyDir": map[string]interface{}{},
+												},
+											},
+										},
+									},
+								},
+							}),
+					},
+				},
+			},
+			expected: &normalizeTransformerArgs{
+				config: &ktypes.Kustomization{},
+				resources: &types.Resources{
+					ResMap: resmap.ResMap{
+						resid.NewResId(service, "service1"): rf.FromMap(
+							map[string]interface{}{
+								"apiVersion": "v1",
+								"kind":       "Service",
+								"metadata": map[string]interface{}{
+									"name": "service1",
+								},
+								"spec": map[string]interface{}{
+									"ports": []interface{}{
+										map[string]interface{}{
+											"port": float64(80),
+										},
+										map[string]interface{}{
+											"port":     float64(53),
+											"protocol": "UDP",
+										},
+									},
+								},
+							}),
+						resid.NewResId(deploy, "deploy1"): rf.FromMap(
+							map[string]interface{}{
+								"apiVersion": "apps/v1",
+								"kind":       "Deployment",
+								"metadata": map[string]interface{}{
+									"name": "deploy1",
+								},
+								"spec": map[string]interface{}{
+									"replicas": float64(1),
+									"template": map[string]interface{}{
+										"spec": map[string]interface{}{
+											"containers": []interface{}{
+												map[string]interface{}{
+													"name":                     "container-1",
+													"terminationMessagePolicy": "FallbackToLogsOnError",
+												},
+											},
+											"volumes": []interface{}{
+												map[string]interface{}{
+													"name":     "tmp",
+													"emptyDir": map[string]interface{}{},
+												},
+											},
+										},
+									},
+								},
+							}),
+					},
+				},
+			},
+		},
+	} {
+		t.Run(fmt.Sprintf("%s", test.name), func(t *testing.T) {
+			lt := NewNormalizeTransformer()
+			err := lt.Transform(test.input.config, test.input.resources)
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if diff := pretty.Compare(test.input.config, test.expected.config); diff != "" {
+				t.Errorf("%s, diff: (-got +want)\n%s", test.name, diff)
+			}
+
+			if diff := pretty.Compare(test.input.resources, test.expected.resources); diff != "" {
+				t.Errorf("%s, diff: (-got +want)\n%s", test.name, diff)
+			}
+		})
+	}
+}
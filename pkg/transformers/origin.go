@@ -0,0 +1,42 @@
+package transformers
+
+import (
+	"fmt"
+
+	"github.com/ContainerSolutions/helm-convert/pkg/types"
+	ktypes "sigs.k8s.io/kustomize/pkg/types"
+)
+
+// OriginAnnotation is the annotation recording the template which rendered a
+// resource, in the format of the kustomize origin annotation
+const OriginAnnotation = "config.kubernetes.io/origin"
+
+type originTransformer struct{}
+
+var _ Transformer = &originTransformer{}
+
+// NewOriginTransformer constructs an originTransformer
+func NewOriginTransformer() Transformer {
+	return &originTransformer{}
+}
+
+// Transform annotate each resource with the path of the template which
+// rendered it and its position in the rendered documents
+func (t *originTransformer) Transform(config *ktypes.Kustomization, resources *types.Resources) error {
+	for id, res := range resources.ResMap {
+		origin := resources.Origins[id]
+		if origin == nil || origin.Template == "" {
+			continue
+		}
+
+		annotations := res.GetAnnotations()
+		if annotations == nil {
+			annotations = make(map[string]string, 1)
+		}
+		annotations[OriginAnnotation] = fmt.Sprintf("path: %s\ndocumentIndex: %d\n",
+			origin.Template, origin.DocumentIndex)
+		res.SetAnnotations(annotations)
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package transformers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ContainerSolutions/helm-convert/pkg/types"
+	"github.com/kylelemons/godebug/pretty"
+	"sigs.k8s.io/kustomize/k8sdeps/kunstruct"
+	"sigs.k8s.io/kustomize/pkg/gvk"
+	"sigs.k8s.io/kustomize/pkg/resid"
+	"sigs.k8s.io/kustomize/pkg/resmap"
+	"sigs.k8s.io/kustomize/pkg/resource"
+	ktypes "sigs.k8s.io/kustomize/pkg/types"
+)
+
+func TestOriginRun(t *testing.T) {
+	var rf = resource.NewFactory(kunstruct.NewKunstructuredFactoryImpl())
+	var service = gvk.Gvk{Version: "v1", Kind: "Service"}
+
+	for _, test := range []struct {
+		name     string
+		origin   *types.Origin
+		input    map[string]interface{}
+		expected map[string]interface{}
+	}{
+		{
+			name:   "it should annotate the resource with its template",
+			origin: &types.Origin{Template: "mychart/templates/web.yaml", DocumentIndex: 1},
+			input: map[string]interface{}{
+				"kind": "Service",
+				"metadata": map[string]interface{}{
+					"name":        "web",
+					"annotations": map[string]interface{}{"prometheus.io/scrape": "true"},
+				},
+			},
+			expected: map[string]interface{}{
+				"kind": "Service",
+				"metadata": map[string]interface{}{
+					"name": "web",
+					"annotations": map[string]interface{}{
+						"prometheus.io/scrape":        "true",
+						"config.kubernetes.io/origin": "path: mychart/templates/web.yaml\ndocumentIndex: 1\n",
+					},
+				},
+			},
+		},
+		{
+			name: "it should not annotate resources without origin",
+			input: map[string]interface{}{
+				"kind":     "Service",
+				"metadata": map[string]interface{}{"name": "web"},
+			},
+			expected: map[string]interface{}{
+				"kind":     "Service",
+				"metadata": map[string]interface{}{"name": "web"},
+			},
+		},
+	} {
+		t.Run(fmt.Sprintf("%s", test.name), func(t *testing.T) {
+			id := resid.NewResId(service, "web")
+			resources := &types.Resources{
+				ResMap:  resmap.ResMap{id: rf.FromMap(test.input)},
+				Origins: map[resid.ResId]*types.Origin{},
+			}
+			if test.origin != nil {
+				resources.Origins[id] = test.origin
+			}
+
+			err := NewOriginTransformer().Transform(&ktypes.Kustomization{}, resources)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if diff := pretty.Compare(resources.ResMap[id].Map(), test.expected); diff != "" {
+				t.Errorf("%s, diff: (-got +want)\n%s", test.name, diff)
+			}
+		})
+	}
+}
@@ -5,25 +5,43 @@ import (
 
 	"github.com/ContainerSolutions/helm-convert/pkg/types"
 	"github.com/ContainerSolutions/helm-convert/pkg/utils"
+	"sigs.k8s.io/kustomize/pkg/resid"
 	ktypes "sigs.k8s.io/kustomize/pkg/types"
 )
 
-type resourcesTransformer struct{}
+type resourcesTransformer struct {
+	namer *utils.FileNamer
+}
 
 var _ Transformer = &resourcesTransformer{}
 
-// NewResourcesTransformer constructs a resourcesTransformer.
-func NewResourcesTransformer() Transformer {
-	return &resourcesTransformer{}
+// NewResourcesTransformer constructs a resourcesTransformer. The file namer
+// define the path of each resource, nil means the flat layout with default
+// filenames.
+func NewResourcesTransformer(namer *utils.FileNamer) Transformer {
+	return &resourcesTransformer{namer}
 }
 
 // Transform retrieve all manifests name and store them as resources in the kustomization.yaml
 func (t *resourcesTransformer) Transform(config *ktypes.Kustomization, resources *types.Resources) error {
-	for id, res := range resources.ResMap {
-		filename, err := utils.GetResourceFileName(id, res)
-		if err != nil {
-			return err
+	var filenames map[resid.ResId]string
+	var err error
+	if t.namer != nil {
+		filenames, err = t.namer.FilePaths(resources)
+	} else {
+		filenames, err = utils.GetResourceFileNames(resources.ResMap)
+	}
+	if err != nil {
+		return err
+	}
+
+	// several resources can share the same file with the by-template layout
+	seen := make(map[string]struct{}, len(filenames))
+	for _, filename := range filenames {
+		if _, ok := seen[filename]; ok {
+			continue
 		}
+		seen[filename] = struct{}{}
 		config.Resources = append(config.Resources, filename)
 	}
 
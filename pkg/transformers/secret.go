@@ -6,21 +6,29 @@ import (
 	"sort"
 
 	"github.com/ContainerSolutions/helm-convert/pkg/types"
+	"github.com/ContainerSolutions/helm-convert/pkg/utils"
 	ktypes "sigs.k8s.io/kustomize/pkg/types"
 )
 
-type secretTransformer struct{}
+type secretTransformer struct {
+	options *DataSourceOptions
+}
 
 var _ Transformer = &secretTransformer{}
 
-// NewSecretTransformer constructs a secretTransformer.
-func NewSecretTransformer() Transformer {
-	return &secretTransformer{}
+// NewSecretTransformer constructs a secretTransformer. The options configure
+// how keys are classified, nil means defaults.
+func NewSecretTransformer(options *DataSourceOptions) Transformer {
+	return &secretTransformer{options}
 }
 
 // Transform retrieve secrets from manifests and store them as secretGenerator in the kustomization.yaml
 func (t *secretTransformer) Transform(config *ktypes.Kustomization, resources *types.Resources) error {
-	for id, res := range resources.ResMap {
+	// iterate in a deterministic order so that source directories are
+	// always attributed to the same resource
+	for _, id := range utils.SortedResIds(resources.ResMap) {
+		res := resources.ResMap[id]
+
 		kind, err := res.GetFieldValue("kind")
 		if err != nil {
 			continue
@@ -42,10 +50,8 @@ func (t *secretTransformer) Transform(config *ktypes.Kustomization, resources *t
 
 		obj := resources.ResMap[id].Map()
 
-		var data map[string]interface{}
-		if _, found := obj["data"]; found && obj["data"] != nil {
-			data = obj["data"].(map[string]interface{})
-		}
+		data, _ := obj["data"].(map[string]interface{})
+		stringData, _ := obj["stringData"].(map[string]interface{})
 
 		secretArg := ktypes.SecretArgs{
 			GeneratorArgs: ktypes.GeneratorArgs{
@@ -54,19 +60,36 @@ func (t *secretTransformer) Transform(config *ktypes.Kustomization, resources *t
 			Type: secretType,
 		}
 
-		dataDecoded := make(map[string]string, len(data))
+		dataDecoded := make(map[string]string, len(data)+len(stringData))
 		for key, value := range data {
 			decoded, err := base64.StdEncoding.DecodeString(value.(string))
 			if err != nil {
-				return fmt.Errorf("couldn't base64 decode the secret key '%s' with value '%v'", key, value)
+				return fmt.Errorf("couldn't base64 decode the key '%s' of %s with value '%v'",
+					key, resources.Describe(id), value)
 			}
 			dataDecoded[key] = string(decoded)
 		}
 
-		secretArg.GeneratorArgs.DataSources = TransformDataSource(name, dataDecoded, resources.SourceFiles)
+		// stringData takes precedence over data, like the API server does
+		for key, value := range stringData {
+			dataDecoded[key] = value.(string)
+		}
+
+		// keep the namespace so that resources with the same name in different
+		// namespaces don't collide
+		if namespace, err := res.GetFieldValue("metadata.namespace"); err == nil {
+			secretArg.GeneratorArgs.Namespace = namespace
+		}
+
+		dataSources, err := TransformDataSource(name, dataDecoded, resources.SourceFiles, t.options)
+		if err != nil {
+			return err
+		}
+		secretArg.GeneratorArgs.DataSources = dataSources
+		resources.Report.Generator(reportDataSource("Secret", secretArg.GeneratorArgs, dataDecoded))
 
 		config.SecretGenerator = append(config.SecretGenerator, secretArg)
-		delete(resources.ResMap, res.Id())
+		delete(resources.ResMap, id)
 	}
 
 	// sort by name
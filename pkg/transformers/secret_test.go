@@ -93,7 +93,7 @@ func TestSecretRun(t *testing.T) {
 							GeneratorArgs: ktypes.GeneratorArgs{
 								Name: "secret1",
 								DataSources: ktypes.DataSources{
-									EnvSource: "secret1.env",
+									EnvSource: "files/secret1/secret1.env",
 								},
 							},
 							Type: string(corev1.SecretTypeOpaque),
@@ -103,8 +103,8 @@ func TestSecretRun(t *testing.T) {
 								Name: "secret2",
 								DataSources: ktypes.DataSources{
 									FileSources: []string{
-										"secret2-tls.cert",
-										"secret2-tls.key",
+										"files/secret2/tls.cert",
+										"files/secret2/tls.key",
 									},
 								},
 							},
@@ -124,9 +124,9 @@ func TestSecretRun(t *testing.T) {
 				resources: &types.Resources{
 					ResMap: resmap.ResMap{},
 					SourceFiles: map[string]string{
-						"secret1.env":      "DB_PASSWORD=password\nDB_USERNAME=admin",
-						"secret2-tls.cert": string(cert),
-						"secret2-tls.key":  string(key),
+						"files/secret1/secret1.env": "DB_PASSWORD=password\nDB_USERNAME=admin",
+						"files/secret2/tls.cert":    string(cert),
+						"files/secret2/tls.key":     string(key),
 					},
 				},
 			},
@@ -136,7 +136,7 @@ func TestSecretRun(t *testing.T) {
 			res := types.NewResources()
 			res.ResMap = test.input.resources.ResMap
 
-			lt := NewSecretTransformer()
+			lt := NewSecretTransformer(nil)
 			err := lt.Transform(test.input.config, res)
 
 			if err != nil {
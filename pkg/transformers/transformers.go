@@ -2,7 +2,12 @@
 package transformers
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/ContainerSolutions/helm-convert/pkg/types"
+	"github.com/ContainerSolutions/helm-convert/pkg/utils"
+	"sigs.k8s.io/kustomize/pkg/resid"
 	ktypes "sigs.k8s.io/kustomize/pkg/types"
 )
 
@@ -12,3 +17,40 @@ type Transformer interface {
 	// resources that can be labelled.
 	Transform(*ktypes.Kustomization, *types.Resources) error
 }
+
+// Name return the name of a Transformer or TransformerV2 used by
+// --skip-transformers, ie: configmap for the configMapTransformer
+func Name(t interface{}) string {
+	if a, ok := t.(*transformerAdapter); ok {
+		t = a.transformer
+	}
+	return strings.ToLower(
+		strings.TrimSuffix(
+			strings.TrimPrefix(
+				fmt.Sprintf("%T", t),
+				"*transformers."),
+			"Transformer"))
+}
+
+// removeKeys remove the keys from all the maps named after one of the paths
+// in a resource, each distinct removed value is recorded
+func removeKeys(record func(resource, key, value string), id resid.ResId, paths, keys []string,
+	obj map[string]interface{}) error {
+	ref := types.ResourceRef(id)
+	for _, key := range keys {
+		seen := make(map[string]struct{})
+		for _, path := range paths {
+			for _, value := range utils.RecursivelyFindKey(path, key, obj) {
+				v := fmt.Sprint(value)
+				if _, ok := seen[v]; !ok {
+					seen[v] = struct{}{}
+					record(ref, key, v)
+				}
+			}
+			if err := utils.RecursivelyRemoveKey(path, key, obj); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
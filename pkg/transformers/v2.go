@@ -0,0 +1,111 @@
+package transformers
+
+import (
+	"fmt"
+
+	"github.com/ContainerSolutions/helm-convert/pkg/types"
+	"github.com/golang/glog"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	ktypes "sigs.k8s.io/kustomize/pkg/types"
+)
+
+// A TransformerV2 modifies an instance of resources knowing the chart being
+// converted. Besides the kustomization and the resources, it can return
+// warnings and add files, patches and components to the package.
+type TransformerV2 interface {
+	// Apply modifies data in the arguments and return the warnings and
+	// artifacts of the transformation
+	Apply(*Context, *ktypes.Kustomization, *types.Resources) (*Result, error)
+}
+
+// Context is the conversion given to a TransformerV2
+type Context struct {
+	// Metadata of the converted chart
+	Metadata *chart.Metadata
+
+	// ReleaseName is the name of the release the chart is rendered with
+	ReleaseName string
+
+	// Namespace is the namespace the chart is rendered with
+	Namespace string
+
+	// Options are free form options given to the transformers, ie:
+	// --transformer-option key=value
+	Options map[string]string
+
+	// Logger of the transformer
+	Logger Logger
+}
+
+// Logger logs the messages of a transformer
+type Logger interface {
+	Infof(format string, args ...interface{})
+	Warningf(format string, args ...interface{})
+}
+
+// Result is the output of a TransformerV2
+type Result struct {
+	// Warnings are recorded in the report and logged
+	Warnings []string
+
+	// Artifacts added to the package, nil if none
+	Artifacts *types.Artifacts
+}
+
+// NewContext constructs a Context logging with glog
+func NewContext(metadata *chart.Metadata, releaseName, namespace string, options map[string]string) *Context {
+	if metadata == nil {
+		metadata = &chart.Metadata{}
+	}
+	if options == nil {
+		options = map[string]string{}
+	}
+	return &Context{
+		Metadata:    metadata,
+		ReleaseName: releaseName,
+		Namespace:   namespace,
+		Options:     options,
+		Logger:      glogLogger{},
+	}
+}
+
+// withLogger return a copy of the context logging with the given prefix
+func (c *Context) withLogger(prefix string) *Context {
+	ctx := *c
+	ctx.Logger = glogLogger{prefix}
+	return &ctx
+}
+
+// glogLogger log with glog, info messages at level 4
+type glogLogger struct {
+	prefix string
+}
+
+func (l glogLogger) Infof(format string, args ...interface{}) {
+	glog.V(4).Info(l.prefix + fmt.Sprintf(format, args...))
+}
+
+func (l glogLogger) Warningf(format string, args ...interface{}) {
+	glog.WarningDepth(1, l.prefix+fmt.Sprintf(format, args...))
+}
+
+// transformerAdapter adapt a Transformer to a TransformerV2
+type transformerAdapter struct {
+	transformer Transformer
+}
+
+var _ TransformerV2 = &transformerAdapter{}
+
+// AdaptTransformer constructs a TransformerV2 from a Transformer, the context
+// is ignored and the result is always empty
+func AdaptTransformer(t Transformer) TransformerV2 {
+	if v2, ok := t.(TransformerV2); ok {
+		return v2
+	}
+	return &transformerAdapter{t}
+}
+
+// Apply run the adapted transformer
+func (a *transformerAdapter) Apply(ctx *Context, config *ktypes.Kustomization, resources *types.Resources) (*Result, error) {
+	return &Result{}, a.transformer.Transform(config, resources)
+}
@@ -0,0 +1,103 @@
+package transformers
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ContainerSolutions/helm-convert/pkg/types"
+	"github.com/kylelemons/godebug/pretty"
+	"k8s.io/helm/pkg/proto/hapi/chart"
+	ktypes "sigs.k8s.io/kustomize/pkg/types"
+)
+
+// contextTransformer is a TransformerV2 recording the context it receives
+type contextTransformer struct {
+	name     string
+	received *Context
+}
+
+func (t *contextTransformer) Apply(ctx *Context, config *ktypes.Kustomization, resources *types.Resources) (*Result, error) {
+	t.received = ctx
+	ctx.Logger.Infof("applying %s", t.name)
+
+	artifacts := types.NewArtifacts()
+	artifacts.Files["files/"+t.name+".txt"] = []byte(ctx.Options[t.name])
+	return &Result{
+		Warnings:  []string{fmt.Sprintf("%s of %s", t.name, ctx.Metadata.Name)},
+		Artifacts: artifacts,
+	}, nil
+}
+
+func TestMultiTransformerV2(t *testing.T) {
+	for _, test := range []struct {
+		name           string
+		transformers   func() []TransformerV2
+		expected       *Result
+		expectedReport *types.Report
+	}{
+		{
+			name: "it should merge the warnings and artifacts of each transformer",
+			transformers: func() []TransformerV2 {
+				return []TransformerV2{
+					&contextTransformer{name: "first"},
+					AdaptTransformer(NewNamespaceTransformer()),
+					&contextTransformer{name: "second"},
+				}
+			},
+			expected: &Result{
+				Warnings: []string{"first of web", "second of web"},
+				Artifacts: &types.Artifacts{
+					Files: map[string][]byte{
+						"files/first.txt":  []byte("1"),
+						"files/second.txt": []byte("2"),
+					},
+				},
+			},
+			expectedReport: &types.Report{
+				Chart: "web",
+				Transformers: []*types.TransformerReport{
+					{Name: "context", Warnings: []string{"[context] first of web"}},
+					{Name: "namespace"},
+					{Name: "context", Warnings: []string{"[context] second of web"}},
+				},
+			},
+		},
+	} {
+		t.Run(fmt.Sprintf("%s", test.name), func(t *testing.T) {
+			resources := types.NewResources()
+			resources.Report = types.NewReport("web", "")
+			ctx := NewContext(&chart.Metadata{Name: "web"}, "release", "default",
+				map[string]string{"first": "1", "second": "2"})
+
+			transformers := test.transformers()
+			result, err := NewMultiTransformerV2(transformers, nil).Apply(ctx, &ktypes.Kustomization{}, resources)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if diff := pretty.Compare(result, test.expected); diff != "" {
+				t.Errorf("%s, diff: (-got +want)\n%s", test.name, diff)
+			}
+			if diff := pretty.Compare(resources.Report, test.expectedReport); diff != "" {
+				t.Errorf("%s, report diff: (-got +want)\n%s", test.name, diff)
+			}
+
+			received := transformers[0].(*contextTransformer).received
+			if received.ReleaseName != "release" || received.Namespace != "default" {
+				t.Errorf("%s, unexpected context %+v", test.name, received)
+			}
+		})
+	}
+}
+
+func TestAdaptTransformer(t *testing.T) {
+	if _, ok := AdaptTransformer(NewNamespaceTransformer()).(*transformerAdapter); !ok {
+		t.Errorf("expected the namespace transformer to be wrapped")
+	}
+	if got := Name(AdaptTransformer(NewNamespaceTransformer())); got != "namespace" {
+		t.Errorf("expected the adapted transformer to be named namespace, got %s", got)
+	}
+	if _, ok := AdaptTransformer(NewMultiTransformer(nil)).(*multiTransformer); !ok {
+		t.Errorf("expected a TransformerV2 not to be wrapped")
+	}
+}
@@ -0,0 +1,52 @@
+package types
+
+// Artifacts are the files added to the package by the transformers besides
+// the manifests, the source files and the kustomization
+type Artifacts struct {
+	// Files are written as is, the key being the path relative to the
+	// package, ie: files/logo.png
+	Files map[string][]byte
+
+	// Patches are written and applied by the kustomization
+	Patches []PatchArtifact
+
+	// Components are written in the components directory
+	Components []*Component
+}
+
+// PatchArtifact is a patch applied by the kustomization
+type PatchArtifact struct {
+	// Path of the patch relative to the package, ie: patches/web.yaml
+	Path string
+
+	// Target is the resource a list of JSON patch operations applies to, nil
+	// for a strategic merge patch
+	Target *PatchTarget
+
+	// Patch is a list of JSON patch operations or a strategic merge patch
+	Patch interface{}
+}
+
+// NewArtifacts constructs a new empty Artifacts
+func NewArtifacts() *Artifacts {
+	return &Artifacts{
+		Files: make(map[string][]byte),
+	}
+}
+
+// Merge add the artifacts of other, files with the same path are replaced
+func (a *Artifacts) Merge(other *Artifacts) {
+	if other == nil {
+		return
+	}
+	for p, data := range other.Files {
+		a.Files[p] = data
+	}
+	a.Patches = append(a.Patches, other.Patches...)
+	a.Components = append(a.Components, other.Components...)
+}
+
+// Empty return true if there are no artifacts
+func (a *Artifacts) Empty() bool {
+	return a == nil || len(a.Files) == 0 && len(a.Patches) == 0 && len(a.Components) == 0
+}
@@ -0,0 +1,75 @@
+package types
+
+import (
+	"sigs.k8s.io/kustomize/pkg/image"
+	ktypes "sigs.k8s.io/kustomize/pkg/types"
+)
+
+const (
+	// ComponentAPIVersion is the API version of kustomize components
+	ComponentAPIVersion = "kustomize.config.k8s.io/v1alpha1"
+
+	// ComponentKind is the kind of kustomize components
+	ComponentKind = "Component"
+)
+
+// Component is a kustomize Component containing the changes made to the
+// package when a chart feature is enabled
+type Component struct {
+	// Name of the component, used as directory name
+	Name string
+
+	// Config is the kustomization.yaml of the component
+	Config *ComponentConfig
+
+	// Resources contains the resources added by the component and the source
+	// files of its generators
+	Resources *Resources
+
+	// Patches contains the patches applied by the component, the key being
+	// the filename, the value either a list of JSON patch operations or a
+	// strategic merge patch
+	Patches map[string]interface{}
+}
+
+// ComponentConfig is the kustomization.yaml of a component
+type ComponentConfig struct {
+	APIVersion         string                 `json:"apiVersion"`
+	Kind               string                 `json:"kind"`
+	CommonLabels       map[string]string      `json:"commonLabels,omitempty"`
+	Labels             []Label                `json:"labels,omitempty"`
+	CommonAnnotations  map[string]string      `json:"commonAnnotations,omitempty"`
+	Resources          []string               `json:"resources,omitempty"`
+	ConfigMapGenerator []ktypes.ConfigMapArgs `json:"configMapGenerator,omitempty"`
+	SecretGenerator    []ktypes.SecretArgs    `json:"secretGenerator,omitempty"`
+	Patches            []Patch                `json:"patches,omitempty"`
+	Images             []image.Image          `json:"images,omitempty"`
+}
+
+// Patch is a patch file and the resource it applies to
+type Patch struct {
+	Path   string       `json:"path"`
+	Target *PatchTarget `json:"target,omitempty"`
+}
+
+// PatchTarget select the resource a patch applies to
+type PatchTarget struct {
+	Group     string `json:"group,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// NewComponent constructs a new empty Component
+func NewComponent(name string) *Component {
+	return &Component{
+		Name: name,
+		Config: &ComponentConfig{
+			APIVersion: ComponentAPIVersion,
+			Kind:       ComponentKind,
+		},
+		Resources: NewResources(),
+		Patches:   make(map[string]interface{}),
+	}
+}
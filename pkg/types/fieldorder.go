@@ -0,0 +1,78 @@
+package types
+
+import (
+	"fmt"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// FieldOrder contains the order in which fields of a resource were declared in
+// the rendered template
+type FieldOrder struct {
+	// Keys is the list of keys of a map in declaration order
+	Keys []string
+
+	// Children contains the order of nested maps. For a list of maps, the
+	// order of all the items is merged.
+	Children map[string]*FieldOrder
+}
+
+// NewFieldOrder constructs a FieldOrder from an ordered YAML map
+func NewFieldOrder(m yaml.MapSlice) *FieldOrder {
+	o := &FieldOrder{}
+	o.merge(m)
+	return o
+}
+
+// Child return the order of a nested map, nil if unknown
+func (o *FieldOrder) Child(key string) *FieldOrder {
+	if o == nil {
+		return nil
+	}
+	return o.Children[key]
+}
+
+// Index return the position of a key, -1 if unknown
+func (o *FieldOrder) Index(key string) int {
+	if o == nil {
+		return -1
+	}
+	for i, k := range o.Keys {
+		if k == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// merge adds the keys of an ordered map which are not yet known
+func (o *FieldOrder) merge(m yaml.MapSlice) {
+	for _, item := range m {
+		key := fmt.Sprint(item.Key)
+		if o.Index(key) < 0 {
+			o.Keys = append(o.Keys, key)
+		}
+
+		switch typedV := item.Value.(type) {
+		case yaml.MapSlice:
+			o.child(key).merge(typedV)
+		case []interface{}:
+			for _, i := range typedV {
+				if m, ok := i.(yaml.MapSlice); ok {
+					o.child(key).merge(m)
+				}
+			}
+		}
+	}
+}
+
+// child return the order of a nested map, creating it if it doesn't exist
+func (o *FieldOrder) child(key string) *FieldOrder {
+	if o.Children == nil {
+		o.Children = make(map[string]*FieldOrder)
+	}
+	if _, ok := o.Children[key]; !ok {
+		o.Children[key] = &FieldOrder{}
+	}
+	return o.Children[key]
+}
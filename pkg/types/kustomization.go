@@ -0,0 +1,128 @@
+package types
+
+import (
+	"sigs.k8s.io/kustomize/pkg/image"
+	ktypes "sigs.k8s.io/kustomize/pkg/types"
+)
+
+const (
+	// KustomizationAPIVersion is the API version of modern kustomization files
+	KustomizationAPIVersion = "kustomize.config.k8s.io/v1beta1"
+
+	// KustomizationKind is the kind of kustomization files
+	KustomizationKind = "Kustomization"
+)
+
+// Kustomization is the kustomization.yaml schema of kustomize v3 and later,
+// the deprecated fields of ktypes.Kustomization are replaced by their modern
+// equivalent: bases by resources, patchesStrategicMerge and patchesJson6902
+// by patches, commonLabels by labels and vars by replacements
+type Kustomization struct {
+	APIVersion         string                   `json:"apiVersion"`
+	Kind               string                   `json:"kind"`
+	Namespace          string                   `json:"namespace,omitempty"`
+	NamePrefix         string                   `json:"namePrefix,omitempty"`
+	NameSuffix         string                   `json:"nameSuffix,omitempty"`
+	CommonLabels       map[string]string        `json:"commonLabels,omitempty"`
+	Labels             []Label                  `json:"labels,omitempty"`
+	CommonAnnotations  map[string]string        `json:"commonAnnotations,omitempty"`
+	Resources          []string                 `json:"resources,omitempty"`
+	HelmGlobals        *HelmGlobals             `json:"helmGlobals,omitempty"`
+	HelmCharts         []HelmChart              `json:"helmCharts,omitempty"`
+	Crds               []string                 `json:"crds,omitempty"`
+	Configurations     []string                 `json:"configurations,omitempty"`
+	ConfigMapGenerator []GeneratorArgs          `json:"configMapGenerator,omitempty"`
+	SecretGenerator    []GeneratorArgs          `json:"secretGenerator,omitempty"`
+	GeneratorOptions   *ktypes.GeneratorOptions `json:"generatorOptions,omitempty"`
+	Patches            []Patch                  `json:"patches,omitempty"`
+	Images             []image.Image            `json:"images,omitempty"`
+	Vars               []ktypes.Var             `json:"vars,omitempty"`
+	Replacements       []Replacement            `json:"replacements,omitempty"`
+	SortOptions        *SortOptions             `json:"sortOptions,omitempty"`
+}
+
+// HelmChart is a chart inflated by kustomize
+type HelmChart struct {
+	Name         string                 `json:"name"`
+	Repo         string                 `json:"repo,omitempty"`
+	Version      string                 `json:"version,omitempty"`
+	ReleaseName  string                 `json:"releaseName,omitempty"`
+	Namespace    string                 `json:"namespace,omitempty"`
+	ValuesInline map[string]interface{} `json:"valuesInline,omitempty"`
+	IncludeCRDs  bool                   `json:"includeCRDs,omitempty"`
+	KubeVersion  string                 `json:"kubeVersion,omitempty"`
+	APIVersions  []string               `json:"apiVersions,omitempty"`
+}
+
+// HelmGlobals are the settings shared by all the inflated charts
+type HelmGlobals struct {
+	// ChartHome is the directory containing the local charts
+	ChartHome string `json:"chartHome,omitempty"`
+}
+
+// Inflation describe how kustomize inflates a chart instead of vendoring the
+// rendered manifests
+type Inflation struct {
+	Chart   *HelmChart
+	Globals *HelmGlobals
+
+	// Rendered contains the resources rendered by Helm before any
+	// transformation
+	Rendered *Resources
+}
+
+// GeneratorArgs are the arguments of a configmap or secret generator, env
+// files are listed in envs
+type GeneratorArgs struct {
+	Name      string   `json:"name,omitempty"`
+	Namespace string   `json:"namespace,omitempty"`
+	Behavior  string   `json:"behavior,omitempty"`
+	Type      string   `json:"type,omitempty"`
+	Literals  []string `json:"literals,omitempty"`
+	Files     []string `json:"files,omitempty"`
+	Envs      []string `json:"envs,omitempty"`
+}
+
+// Label is a set of labels added to all resources, includeSelectors also add
+// them to the selectors and templates like commonLabels
+type Label struct {
+	Pairs            map[string]string `json:"pairs"`
+	IncludeSelectors bool              `json:"includeSelectors,omitempty"`
+	IncludeTemplates bool              `json:"includeTemplates,omitempty"`
+}
+
+// Replacement copy the value of a field of a resource to fields of other
+// resources
+type Replacement struct {
+	Source  *ReplacementSource  `json:"source"`
+	Targets []ReplacementTarget `json:"targets"`
+}
+
+// ReplacementSource select the field a replacement copies
+type ReplacementSource struct {
+	Group     string `json:"group,omitempty"`
+	Version   string `json:"version,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Namespace string `json:"namespace,omitempty"`
+	FieldPath string `json:"fieldPath,omitempty"`
+}
+
+// ReplacementTarget select the fields a replacement writes to
+type ReplacementTarget struct {
+	Select     *PatchTarget `json:"select"`
+	FieldPaths []string     `json:"fieldPaths"`
+}
+
+// SortOptions define the order of the resources in the kustomize output
+type SortOptions struct {
+	Order             string             `json:"order"`
+	LegacySortOptions *LegacySortOptions `json:"legacySortOptions,omitempty"`
+}
+
+// LegacySortOptions list the kinds output first and last with the legacy
+// order
+type LegacySortOptions struct {
+	OrderFirst []string `json:"orderFirst,omitempty"`
+	OrderLast  []string `json:"orderLast,omitempty"`
+}
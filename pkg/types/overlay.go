@@ -0,0 +1,29 @@
+package types
+
+import (
+	ktypes "sigs.k8s.io/kustomize/pkg/types"
+)
+
+// Overlay is a kustomization built on top of the base package, containing the
+// resources and generators specific to a Kubernetes version
+type Overlay struct {
+	// Name of the overlay, used as directory name
+	Name string
+
+	// Config is the kustomization.yaml of the overlay, the base is added
+	// when it is written
+	Config *ktypes.Kustomization
+
+	// Resources contains the resources added by the overlay and the source
+	// files of its generators
+	Resources *Resources
+}
+
+// NewOverlay constructs a new empty Overlay
+func NewOverlay(name string) *Overlay {
+	return &Overlay{
+		Name:      name,
+		Config:    &ktypes.Kustomization{},
+		Resources: NewResources(),
+	}
+}
@@ -0,0 +1,364 @@
+package types
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang/glog"
+	"sigs.k8s.io/kustomize/pkg/resid"
+)
+
+// Report records what a conversion did. Transformers record their changes in
+// the section of the running transformer. All the methods are no-op on a nil
+// report so that transformers don't have to check if a report is requested.
+type Report struct {
+	// Chart is the name of the converted chart
+	Chart string `json:"chart"`
+
+	// Version is the version of the converted chart
+	Version string `json:"version,omitempty"`
+
+	// Transformers is the list of transformers which ran, in order
+	Transformers []*TransformerReport `json:"transformers"`
+
+	// Resources is the list of rendered resources with their origin
+	Resources []ResourceOrigin `json:"resources,omitempty"`
+
+	// Skipped is the list of rendered manifests which are not resources
+	Skipped []SkippedManifest `json:"skipped,omitempty"`
+
+	// Hooks is the list of hooks written in their own directory
+	Hooks []RelocatedHook `json:"hooks,omitempty"`
+
+	// Warnings raised outside of transformers
+	Warnings []string `json:"warnings,omitempty"`
+
+	current *TransformerReport
+}
+
+// TransformerReport is the list of changes made by a transformer
+type TransformerReport struct {
+	Name               string            `json:"name"`
+	LabelsRemoved      []Removal         `json:"labelsRemoved,omitempty"`
+	AnnotationsRemoved []Removal         `json:"annotationsRemoved,omitempty"`
+	CommonLabels       map[string]string `json:"commonLabels,omitempty"`
+	Namespace          string            `json:"namespace,omitempty"`
+	Images             []string          `json:"images,omitempty"`
+	Generators         []GeneratorReport `json:"generators,omitempty"`
+	Warnings           []string          `json:"warnings,omitempty"`
+}
+
+// Removal is a label or annotation removed from a resource
+type Removal struct {
+	Resource string `json:"resource"`
+	Key      string `json:"key"`
+	Value    string `json:"value"`
+}
+
+// GeneratorReport is a ConfigMap or Secret converted to a generator
+type GeneratorReport struct {
+	Kind      string             `json:"kind"`
+	Name      string             `json:"name"`
+	Namespace string             `json:"namespace,omitempty"`
+	Keys      []DataSourceReport `json:"keys"`
+}
+
+// DataSourceReport describe how a key of a ConfigMap or Secret is stored
+type DataSourceReport struct {
+	Key string `json:"key"`
+
+	// Type is literal, file or env
+	Type string `json:"type"`
+
+	// Content is the detected type of content, ie: json
+	Content string `json:"content,omitempty"`
+
+	// File is the source file storing the value, empty for literals
+	File string `json:"file,omitempty"`
+}
+
+// ResourceOrigin is a rendered resource and the template which rendered it
+type ResourceOrigin struct {
+	Resource      string `json:"resource"`
+	Template      string `json:"template"`
+	DocumentIndex int    `json:"documentIndex"`
+}
+
+// SkippedManifest is a rendered template which didn't produce any resource
+type SkippedManifest struct {
+	Template string `json:"template"`
+	Reason   string `json:"reason"`
+}
+
+// RelocatedHook is a Helm hook written in its own directory
+type RelocatedHook struct {
+	Resource  string `json:"resource"`
+	Hook      string `json:"hook"`
+	Directory string `json:"directory"`
+}
+
+// ResourceRef return a short reference of a resource for reports and
+// messages, ie: Deployment/default/web
+func ResourceRef(id resid.ResId) string {
+	if id.Namespace() != "" {
+		return fmt.Sprintf("%s/%s/%s", id.Gvk().Kind, id.Namespace(), id.Name())
+	}
+	return fmt.Sprintf("%s/%s", id.Gvk().Kind, id.Name())
+}
+
+// NewReport constructs a Report
+func NewReport(chart, version string) *Report {
+	return &Report{Chart: chart, Version: version, Transformers: []*TransformerReport{}}
+}
+
+// Begin start the section of a transformer, following changes are recorded
+// in it
+func (r *Report) Begin(name string) {
+	if r == nil {
+		return
+	}
+	r.current = &TransformerReport{Name: name}
+	r.Transformers = append(r.Transformers, r.current)
+}
+
+// End close the section of the running transformer
+func (r *Report) End() {
+	if r == nil {
+		return
+	}
+	r.current = nil
+}
+
+// section return the section of the running transformer
+func (r *Report) section() *TransformerReport {
+	if r.current == nil {
+		r.Begin("")
+	}
+	return r.current
+}
+
+// LabelRemoved record a label removed from a resource
+func (r *Report) LabelRemoved(resource, key, value string) {
+	if r == nil {
+		return
+	}
+	s := r.section()
+	s.LabelsRemoved = append(s.LabelsRemoved, Removal{resource, key, value})
+}
+
+// AnnotationRemoved record an annotation removed from a resource
+func (r *Report) AnnotationRemoved(resource, key, value string) {
+	if r == nil {
+		return
+	}
+	s := r.section()
+	s.AnnotationsRemoved = append(s.AnnotationsRemoved, Removal{resource, key, value})
+}
+
+// CommonLabels record the labels moved to the kustomization
+func (r *Report) CommonLabels(labels map[string]string) {
+	if r == nil || len(labels) == 0 {
+		return
+	}
+	s := r.section()
+	s.CommonLabels = make(map[string]string, len(labels))
+	for key, value := range labels {
+		s.CommonLabels[key] = value
+	}
+}
+
+// Namespace record the namespace moved to the kustomization
+func (r *Report) Namespace(namespace string) {
+	if r == nil {
+		return
+	}
+	r.section().Namespace = namespace
+}
+
+// Image record an image extracted to the kustomization
+func (r *Report) Image(image string) {
+	if r == nil {
+		return
+	}
+	s := r.section()
+	s.Images = append(s.Images, image)
+}
+
+// Generator record a ConfigMap or Secret converted to a generator
+func (r *Report) Generator(generator GeneratorReport) {
+	if r == nil {
+		return
+	}
+	s := r.section()
+	s.Generators = append(s.Generators, generator)
+}
+
+// Resource record a rendered resource and its origin
+func (r *Report) Resource(resource string, origin *Origin) {
+	if r == nil || origin == nil {
+		return
+	}
+	r.Resources = append(r.Resources, ResourceOrigin{resource, origin.Template, origin.DocumentIndex})
+}
+
+// Skip record a rendered template which didn't produce any resource
+func (r *Report) Skip(template, reason string) {
+	if r == nil {
+		return
+	}
+	r.Skipped = append(r.Skipped, SkippedManifest{template, reason})
+}
+
+// Hook record a hook written in its own directory
+func (r *Report) Hook(resource, hook, directory string) {
+	if r == nil {
+		return
+	}
+	r.Hooks = append(r.Hooks, RelocatedHook{resource, hook, directory})
+}
+
+// Warningf log a warning and record it in the section of the running
+// transformer, or in the report if no transformer is running
+func (r *Report) Warningf(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	glog.WarningDepth(1, msg)
+	if r == nil {
+		return
+	}
+	if r.current != nil {
+		r.current.Warnings = append(r.current.Warnings, msg)
+		return
+	}
+	r.Warnings = append(r.Warnings, msg)
+}
+
+// JSON return the report as indented JSON
+func (r *Report) JSON() ([]byte, error) {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// Markdown return the report as a Markdown document, transformers which
+// didn't change anything are omitted
+func (r *Report) Markdown() []byte {
+	var b bytes.Buffer
+	title := r.Chart
+	if r.Version != "" {
+		title += " " + r.Version
+	}
+	fmt.Fprintf(&b, "# Conversion report: %s\n", title)
+
+	for _, t := range r.Transformers {
+		if t.empty() {
+			continue
+		}
+		fmt.Fprintf(&b, "\n## %s\n", t.Name)
+
+		writeRemovals(&b, "Labels removed", t.LabelsRemoved)
+		writeRemovals(&b, "Annotations removed", t.AnnotationsRemoved)
+
+		if len(t.CommonLabels) > 0 {
+			b.WriteString("\nCommon labels:\n\n")
+			keys := make([]string, 0, len(t.CommonLabels))
+			for key := range t.CommonLabels {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				fmt.Fprintf(&b, "- `%s: %s`\n", key, t.CommonLabels[key])
+			}
+		}
+
+		if t.Namespace != "" {
+			fmt.Fprintf(&b, "\nNamespace: `%s`\n", t.Namespace)
+		}
+
+		if len(t.Images) > 0 {
+			b.WriteString("\nImages:\n\n")
+			for _, image := range t.Images {
+				fmt.Fprintf(&b, "- `%s`\n", image)
+			}
+		}
+
+		for _, g := range t.Generators {
+			name := g.Name
+			if g.Namespace != "" {
+				name = g.Namespace + "/" + g.Name
+			}
+			fmt.Fprintf(&b, "\n%s `%s`:\n\n", g.Kind, name)
+			b.WriteString("| Key | Type | Content | File |\n|-----|------|---------|------|\n")
+			for _, k := range g.Keys {
+				fmt.Fprintf(&b, "| %s | %s | %s | %s |\n", k.Key, k.Type, k.Content, k.File)
+			}
+		}
+
+		writeList(&b, "Warnings", t.Warnings)
+	}
+
+	if len(r.Hooks) > 0 {
+		b.WriteString("\n## Hooks\n\n| Resource | Hook | Directory |\n|----------|------|-----------|\n")
+		for _, h := range r.Hooks {
+			fmt.Fprintf(&b, "| %s | %s | %s |\n", h.Resource, h.Hook, h.Directory)
+		}
+	}
+
+	if len(r.Resources) > 0 {
+		b.WriteString("\n## Resources\n\n| Resource | Template | Document |\n|----------|----------|----------|\n")
+		for _, o := range r.Resources {
+			fmt.Fprintf(&b, "| %s | %s | %d |\n", o.Resource, o.Template, o.DocumentIndex)
+		}
+	}
+
+	if len(r.Skipped) > 0 {
+		b.WriteString("\n## Skipped\n\n| Template | Reason |\n|----------|--------|\n")
+		for _, s := range r.Skipped {
+			fmt.Fprintf(&b, "| %s | %s |\n", s.Template, s.Reason)
+		}
+	}
+
+	if len(r.Warnings) > 0 {
+		b.WriteString("\n## Warnings\n")
+		writeList(&b, "", r.Warnings)
+	}
+
+	return b.Bytes()
+}
+
+func (t *TransformerReport) empty() bool {
+	return len(t.LabelsRemoved) == 0 && len(t.AnnotationsRemoved) == 0 && len(t.CommonLabels) == 0 &&
+		t.Namespace == "" && len(t.Images) == 0 && len(t.Generators) == 0 && len(t.Warnings) == 0
+}
+
+func writeRemovals(b *bytes.Buffer, title string, removals []Removal) {
+	if len(removals) == 0 {
+		return
+	}
+	fmt.Fprintf(b, "\n%s:\n\n| Resource | Key | Value |\n|----------|-----|-------|\n", title)
+	for _, r := range removals {
+		fmt.Fprintf(b, "| %s | %s | %s |\n", r.Resource, r.Key, markdownEscape(r.Value))
+	}
+}
+
+func writeList(b *bytes.Buffer, title string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	if title != "" {
+		fmt.Fprintf(b, "\n%s:\n", title)
+	}
+	b.WriteString("\n")
+	for _, item := range items {
+		fmt.Fprintf(b, "- %s\n", item)
+	}
+}
+
+// markdownEscape escape the characters breaking a table cell
+func markdownEscape(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
@@ -1,6 +1,9 @@
 package types
 
 import (
+	"fmt"
+
+	"sigs.k8s.io/kustomize/pkg/resid"
 	"sigs.k8s.io/kustomize/pkg/resmap"
 )
 
@@ -12,6 +15,41 @@ type Resources struct {
 	// SourceFiles contains a list of file retrieved from either configmaps or
 	// secret resources. The key being the filename, and the value its content
 	SourceFiles map[string]string
+
+	// Origins contains the origin in the chart of each resource
+	Origins map[resid.ResId]*Origin
+
+	// FieldOrders contains the order in which fields of each resource were
+	// declared in the rendered template
+	FieldOrders map[resid.ResId]*FieldOrder
+
+	// Report records the changes made by the transformers, nil if no report
+	// is requested
+	Report *Report
+}
+
+// Origin describe where a resource comes from in the chart
+type Origin struct {
+	// Template is the path of the template which rendered the resource, ie:
+	// mychart/templates/deployment.yaml
+	Template string
+
+	// DocumentIndex is the position of the resource in the YAML documents
+	// rendered by the template, from 0
+	DocumentIndex int
+
+	// Hook is the value of the helm.sh/hook annotation of the resource, which
+	// is removed by the transformers, ie: pre-install,pre-upgrade
+	Hook string
+}
+
+// String return the template and document of the origin, ie:
+// mychart/templates/deployment.yaml#1
+func (o *Origin) String() string {
+	if o == nil || o.Template == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s#%d", o.Template, o.DocumentIndex)
 }
 
 // NewResources constructs a new Resources
@@ -19,5 +57,17 @@ func NewResources() *Resources {
 	return &Resources{
 		ResMap:      resmap.ResMap{},
 		SourceFiles: make(map[string]string),
+		Origins:     make(map[resid.ResId]*Origin),
+		FieldOrders: make(map[resid.ResId]*FieldOrder),
+	}
+}
+
+// Describe return a reference of a resource followed by its origin for error
+// messages, ie: Deployment/web (mychart/templates/deployment.yaml#0)
+func (r *Resources) Describe(id resid.ResId) string {
+	ref := ResourceRef(id)
+	if origin := r.Origins[id].String(); origin != "" {
+		return fmt.Sprintf("%s (%s)", ref, origin)
 	}
+	return ref
 }
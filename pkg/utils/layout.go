@@ -0,0 +1,254 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+
+	"github.com/ContainerSolutions/helm-convert/pkg/types"
+	"sigs.k8s.io/kustomize/pkg/resid"
+	"sigs.k8s.io/kustomize/pkg/resource"
+)
+
+// Layout define how resource manifests are organised in the destination
+// directory
+type Layout string
+
+const (
+	// LayoutFlat write all the manifests at the root of the destination
+	LayoutFlat Layout = "flat"
+
+	// LayoutByKind write manifests in a directory named after their kind
+	LayoutByKind Layout = "by-kind"
+
+	// LayoutByComponent write manifests in a directory named after their
+	// component label (app.kubernetes.io/component or component)
+	LayoutByComponent Layout = "by-component"
+
+	// LayoutByTemplate mirror the structure of the chart templates, resources
+	// rendered by the same template are written in the same file
+	LayoutByTemplate Layout = "by-template"
+)
+
+// Layouts is the list of supported layouts
+var Layouts = []Layout{LayoutFlat, LayoutByKind, LayoutByComponent, LayoutByTemplate}
+
+// DefaultFilenameTemplate is the template used to name manifest files
+const DefaultFilenameTemplate = "{{ .Name }}-{{ .Abbreviation }}.yaml"
+
+// componentLabels are the labels used by the by-component layout
+var componentLabels = []string{"app.kubernetes.io/component", "component"}
+
+// defaultComponent is the directory of resources without component label
+const defaultComponent = "common"
+
+// FileNamer compute the path of resource manifests
+type FileNamer struct {
+	layout   Layout
+	template *template.Template
+}
+
+// FileNameData is the data available to the filename template
+type FileNameData struct {
+	Kind         string
+	Abbreviation string
+	Name         string
+	Namespace    string
+	Group        string
+	Version      string
+
+	// Template is the name of the template which rendered the resource
+	// without extension, ie: deployment for mychart/templates/deployment.yaml
+	Template string
+
+	// DocumentIndex is the position of the resource in the documents rendered
+	// by the template, from 0
+	DocumentIndex int
+}
+
+var defaultFileNamer = &FileNamer{
+	layout:   LayoutFlat,
+	template: template.Must(template.New("filename").Parse(DefaultFilenameTemplate)),
+}
+
+// NewFileNamer constructs a FileNamer from a layout and a Go template, an empty
+// template means DefaultFilenameTemplate
+func NewFileNamer(layout Layout, filenameTemplate string) (*FileNamer, error) {
+	if layout == "" {
+		layout = LayoutFlat
+	}
+
+	valid := false
+	for _, l := range Layouts {
+		if l == layout {
+			valid = true
+		}
+	}
+	if !valid {
+		return nil, fmt.Errorf("unknown layout '%s', expected one of %v", layout, Layouts)
+	}
+
+	if filenameTemplate == "" {
+		filenameTemplate = DefaultFilenameTemplate
+	}
+
+	tmpl, err := template.New("filename").Option("missingkey=error").Parse(filenameTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filename template: %v", err)
+	}
+
+	return &FileNamer{layout, tmpl}, nil
+}
+
+// FileName return the filename of a resource, rendered from the filename
+// template
+func (n *FileNamer) FileName(id resid.ResId, res *resource.Resource) (string, error) {
+	return n.fileName(id, res, nil)
+}
+
+// fileName return the filename of a resource rendered by the given template
+func (n *FileNamer) fileName(id resid.ResId, res *resource.Resource, origin *types.Origin) (string, error) {
+	kind, err := res.GetFieldValue("kind")
+	if err != nil {
+		return "", err
+	}
+
+	name, err := res.GetFieldValue("metadata.name")
+	if err != nil {
+		return "", err
+	}
+
+	return n.render(newFileNameData(id, kind, name, origin))
+}
+
+func newFileNameData(id resid.ResId, kind, name string, origin *types.Origin) *FileNameData {
+	data := &FileNameData{
+		Kind:         kind,
+		Abbreviation: GetKindAbbreviation(kind),
+		Name:         name,
+		Namespace:    id.Namespace(),
+		Group:        id.Gvk().Group,
+		Version:      id.Gvk().Version,
+	}
+	if origin != nil {
+		base := path.Base(origin.Template)
+		data.Template = strings.TrimSuffix(base, path.Ext(base))
+		data.DocumentIndex = origin.DocumentIndex
+	}
+	return data
+}
+
+// render the filename template, the filename must be a file path inside of
+// the package
+func (n *FileNamer) render(data *FileNameData) (string, error) {
+	var buf bytes.Buffer
+	if err := n.template.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("couldn't render filename template: %v", err)
+	}
+
+	filename := strings.ToLower(buf.String())
+	p := path.Clean(filename)
+	if strings.TrimSpace(filename) == "" || strings.HasSuffix(filename, "/") || path.IsAbs(p) ||
+		p == "." || p == ".." || strings.HasPrefix(p, "../") {
+		return "", fmt.Errorf("filename template rendered the invalid path '%s' for %s %s, it must be a file in the package",
+			filename, data.Kind, data.Name)
+	}
+	return p, nil
+}
+
+// FilePaths return the path of each resource relative to the destination
+// directory. Resources sharing the same filename (ie: same kind and name in
+// different namespaces or API groups) are disambiguated by adding their
+// namespace, group and version to their name. An error is returned if paths
+// still collide. With the by-template layout, resources rendered by the same
+// template share the same path.
+func (n *FileNamer) FilePaths(resources *types.Resources) (map[resid.ResId]string, error) {
+	paths := make(map[resid.ResId]string, len(resources.ResMap))
+	byPath := make(map[string][]resid.ResId)
+
+	for id, res := range resources.ResMap {
+		if p, ok := n.templatePath(id, resources); ok {
+			paths[id] = p
+			continue
+		}
+
+		filename, err := n.fileName(id, res, resources.Origins[id])
+		if err != nil {
+			return nil, err
+		}
+		p := path.Join(n.directory(id, res), filename)
+		paths[id] = p
+		byPath[p] = append(byPath[p], id)
+	}
+
+	for _, ids := range byPath {
+		if len(ids) < 2 {
+			continue
+		}
+
+		qualifiers := qualifyResIds(ids)
+		for _, id := range ids {
+			res := resources.ResMap[id]
+			kind, _ := res.GetFieldValue("kind")
+			filename, err := n.render(newFileNameData(id, kind, id.Name()+"-"+qualifiers[id], resources.Origins[id]))
+			if err != nil {
+				return nil, err
+			}
+			paths[id] = path.Join(n.directory(id, res), filename)
+		}
+	}
+
+	seen := make(map[string]resid.ResId, len(paths))
+	for id, p := range paths {
+		if _, ok := n.templatePath(id, resources); ok {
+			continue
+		}
+		if other, ok := seen[p]; ok {
+			return nil, fmt.Errorf("resources %s and %s would be written to the same file %s",
+				resources.Describe(id), resources.Describe(other), p)
+		}
+		seen[p] = id
+	}
+
+	return paths, nil
+}
+
+// directory return the directory of a resource depending on the layout
+func (n *FileNamer) directory(id resid.ResId, res *resource.Resource) string {
+	switch n.layout {
+	case LayoutByKind:
+		return strings.ToLower(id.Gvk().Kind)
+	case LayoutByComponent:
+		labels := res.GetLabels()
+		for _, label := range componentLabels {
+			if component, ok := labels[label]; ok && component != "" {
+				return strings.ToLower(component)
+			}
+		}
+		return defaultComponent
+	}
+	return ""
+}
+
+// templatePath return the path of the template which rendered the resource,
+// relative to the chart directory, when using the by-template layout
+func (n *FileNamer) templatePath(id resid.ResId, resources *types.Resources) (string, bool) {
+	if n.layout != LayoutByTemplate {
+		return "", false
+	}
+
+	origin, ok := resources.Origins[id]
+	if !ok || origin.Template == "" {
+		return "", false
+	}
+
+	// strip the chart name, ie: mychart/templates/deploy.yaml
+	parts := strings.SplitN(origin.Template, "/", 2)
+	if len(parts) < 2 {
+		return "", false
+	}
+
+	return parts[1], true
+}
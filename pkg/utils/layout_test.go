@@ -0,0 +1,151 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ContainerSolutions/helm-convert/pkg/types"
+	"github.com/kylelemons/godebug/pretty"
+	"sigs.k8s.io/kustomize/pkg/gvk"
+	"sigs.k8s.io/kustomize/pkg/resid"
+	"sigs.k8s.io/kustomize/pkg/resmap"
+)
+
+func TestFilePaths(t *testing.T) {
+	var deploy = gvk.Gvk{Group: "apps", Version: "v1", Kind: "Deployment"}
+	var service = gvk.Gvk{Version: "v1", Kind: "Service"}
+
+	resources := &types.Resources{
+		ResMap: resmap.ResMap{
+			resid.NewResId(deploy, "web"): rf.FromMap(
+				map[string]interface{}{
+					"apiVersion": "apps/v1",
+					"kind":       "Deployment",
+					"metadata": map[string]interface{}{
+						"name": "web",
+						"labels": map[string]interface{}{
+							"app.kubernetes.io/component": "frontend",
+						},
+					},
+				}),
+			resid.NewResId(service, "web"): rf.FromMap(
+				map[string]interface{}{
+					"apiVersion": "v1",
+					"kind":       "Service",
+					"metadata": map[string]interface{}{
+						"name": "web",
+					},
+				}),
+		},
+		Origins: map[resid.ResId]*types.Origin{
+			resid.NewResId(deploy, "web"):  &types.Origin{Template: "mychart/templates/web.yaml"},
+			resid.NewResId(service, "web"): &types.Origin{Template: "mychart/templates/web.yaml", DocumentIndex: 1},
+		},
+	}
+
+	for _, test := range []struct {
+		name             string
+		layout           Layout
+		filenameTemplate string
+		expected         map[resid.ResId]string
+		expectedError    string
+	}{
+		{
+			name:   "it should write all the files in the same directory",
+			layout: LayoutFlat,
+			expected: map[resid.ResId]string{
+				resid.NewResId(deploy, "web"):  "web-deploy.yaml",
+				resid.NewResId(service, "web"): "web-svc.yaml",
+			},
+		},
+		{
+			name:   "it should group files by kind",
+			layout: LayoutByKind,
+			expected: map[resid.ResId]string{
+				resid.NewResId(deploy, "web"):  "deployment/web-deploy.yaml",
+				resid.NewResId(service, "web"): "service/web-svc.yaml",
+			},
+		},
+		{
+			name:   "it should group files by component",
+			layout: LayoutByComponent,
+			expected: map[resid.ResId]string{
+				resid.NewResId(deploy, "web"):  "frontend/web-deploy.yaml",
+				resid.NewResId(service, "web"): "common/web-svc.yaml",
+			},
+		},
+		{
+			name:   "it should mirror the chart templates",
+			layout: LayoutByTemplate,
+			expected: map[resid.ResId]string{
+				resid.NewResId(deploy, "web"):  "templates/web.yaml",
+				resid.NewResId(service, "web"): "templates/web.yaml",
+			},
+		},
+		{
+			name:             "it should use the filename template",
+			layout:           LayoutFlat,
+			filenameTemplate: "{{ .Kind }}_{{ .Name }}.yml",
+			expected: map[resid.ResId]string{
+				resid.NewResId(deploy, "web"):  "deployment_web.yml",
+				resid.NewResId(service, "web"): "service_web.yml",
+			},
+		},
+		{
+			name:             "it should name the files after the template which rendered them",
+			layout:           LayoutFlat,
+			filenameTemplate: "{{ .Template }}-{{ .DocumentIndex }}-{{ .Abbreviation }}.yaml",
+			expected: map[resid.ResId]string{
+				resid.NewResId(deploy, "web"):  "web-0-deploy.yaml",
+				resid.NewResId(service, "web"): "web-1-svc.yaml",
+			},
+		},
+		{
+			name:             "it should reject a filename outside of the package",
+			layout:           LayoutFlat,
+			filenameTemplate: "../{{ .Name }}.yaml",
+			expectedError:    "filename template rendered the invalid path '../web.yaml'",
+		},
+		{
+			name:             "it should reject an absolute filename",
+			layout:           LayoutByKind,
+			filenameTemplate: "/tmp/{{ .Name }}.yaml",
+			expectedError:    "filename template rendered the invalid path '/tmp/web.yaml'",
+		},
+		{
+			name:             "it should reject an empty filename",
+			layout:           LayoutFlat,
+			filenameTemplate: "{{ .Namespace }}",
+			expectedError:    "filename template rendered the invalid path ''",
+		},
+		{
+			name:             "it should reject a directory",
+			layout:           LayoutFlat,
+			filenameTemplate: "{{ .Name }}/",
+			expectedError:    "filename template rendered the invalid path 'web/'",
+		},
+	} {
+		t.Run(fmt.Sprintf("%s", test.name), func(t *testing.T) {
+			namer, err := NewFileNamer(test.layout, test.filenameTemplate)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			output, err := namer.FilePaths(resources)
+			if test.expectedError != "" {
+				if err == nil || !strings.Contains(err.Error(), test.expectedError) {
+					t.Fatalf("%s: expected error %q, got %v", test.name, test.expectedError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if diff := pretty.Compare(output, test.expected); diff != "" {
+				t.Errorf("%s, diff: (-got +want)\n%s", test.name, diff)
+			}
+		})
+	}
+}
@@ -2,27 +2,73 @@
 package utils
 
 import (
-	"fmt"
 	"sort"
 	"strings"
 
+	"github.com/ContainerSolutions/helm-convert/pkg/types"
 	"sigs.k8s.io/kustomize/pkg/resid"
+	"sigs.k8s.io/kustomize/pkg/resmap"
 	"sigs.k8s.io/kustomize/pkg/resource"
 )
 
 // GetResourceFileName return a resource name from metadata.name
 func GetResourceFileName(id resid.ResId, res *resource.Resource) (string, error) {
-	kind, err := res.GetFieldValue("kind")
-	if err != nil {
-		return "", err
+	return defaultFileNamer.FileName(id, res)
+}
+
+// GetResourceFileNames return the filename of each resource from a ResMap
+// using the flat layout, see FileNamer.FilePaths.
+func GetResourceFileNames(resources resmap.ResMap) (map[resid.ResId]string, error) {
+	return defaultFileNamer.FilePaths(&types.Resources{ResMap: resources})
+}
+
+// qualifyResIds return for each id the list of fields which differ from the
+// other ids (namespace, group, version) joined by a dash
+func qualifyResIds(ids []resid.ResId) map[resid.ResId]string {
+	fields := []struct {
+		value    func(resid.ResId) string
+		fallback string
+	}{
+		{func(id resid.ResId) string { return id.Namespace() }, "default"},
+		{func(id resid.ResId) string { return strings.Replace(id.Gvk().Group, ".", "-", -1) }, "core"},
+		{func(id resid.ResId) string { return id.Gvk().Version }, ""},
 	}
 
-	name, err := res.GetFieldValue("metadata.name")
-	if err != nil {
-		return "", err
+	qualifiers := make(map[resid.ResId]string, len(ids))
+	for _, field := range fields {
+		values := make(map[string]struct{})
+		for _, id := range ids {
+			values[field.value(id)] = struct{}{}
+		}
+		if len(values) < 2 {
+			continue
+		}
+		for _, id := range ids {
+			value := field.value(id)
+			if value == "" {
+				value = field.fallback
+			}
+			if qualifiers[id] != "" {
+				value = qualifiers[id] + "-" + value
+			}
+			qualifiers[id] = value
+		}
 	}
 
-	return strings.ToLower(fmt.Sprintf("%s-%s.yaml", name, GetKindAbbreviation(kind))), nil
+	return qualifiers
+}
+
+// SortedResIds return the ids of a ResMap sorted by their string
+// representation, it is used to iterate over resources in a deterministic order
+func SortedResIds(resources resmap.ResMap) []resid.ResId {
+	ids := make([]resid.ResId, 0, len(resources))
+	for id := range resources {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i].String() < ids[j].String()
+	})
+	return ids
 }
 
 // GetKindAbbreviation return the abbreviation of a given resource
@@ -82,3 +128,28 @@ func RecursivelyRemoveKey(path, key string, obj map[string]interface{}) error {
 
 	return nil
 }
+
+// RecursivelyFindKey return the values of a given key in all the maps named
+// path, ie: the values of a label in the metadata and the pod template
+func RecursivelyFindKey(path, key string, obj map[string]interface{}) []interface{} {
+	var values []interface{}
+	for k := range obj {
+		switch typedV := obj[k].(type) {
+		case map[string]interface{}:
+			if k == path {
+				if value, exist := typedV[key]; exist {
+					values = append(values, value)
+				}
+			} else {
+				values = append(values, RecursivelyFindKey(path, key, typedV)...)
+			}
+		case []interface{}:
+			for i := range typedV {
+				if typedItem, ok := typedV[i].(map[string]interface{}); ok {
+					values = append(values, RecursivelyFindKey(path, key, typedItem)...)
+				}
+			}
+		}
+	}
+	return values
+}
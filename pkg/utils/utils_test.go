@@ -2,12 +2,14 @@ package utils
 
 import (
 	"fmt"
+	"sort"
 	"testing"
 
 	"github.com/kylelemons/godebug/pretty"
 	"sigs.k8s.io/kustomize/k8sdeps/kunstruct"
 	"sigs.k8s.io/kustomize/pkg/gvk"
 	"sigs.k8s.io/kustomize/pkg/resid"
+	"sigs.k8s.io/kustomize/pkg/resmap"
 	"sigs.k8s.io/kustomize/pkg/resource"
 )
 
@@ -58,6 +60,60 @@ func TestGetResourceFileName(t *testing.T) {
 	}
 }
 
+func TestGetResourceFileNames(t *testing.T) {
+	var deploy = gvk.Gvk{Group: "apps", Version: "v1", Kind: "Deployment"}
+	var extDeploy = gvk.Gvk{Group: "extensions", Version: "v1beta1", Kind: "Deployment"}
+	var service = gvk.Gvk{Version: "v1", Kind: "Service"}
+
+	newResource := func(apiVersion, kind, name, namespace string) *resource.Resource {
+		metadata := map[string]interface{}{
+			"name": name,
+		}
+		if namespace != "" {
+			metadata["namespace"] = namespace
+		}
+		return rf.FromMap(map[string]interface{}{
+			"apiVersion": apiVersion,
+			"kind":       kind,
+			"metadata":   metadata,
+		})
+	}
+
+	for _, test := range []struct {
+		name     string
+		input    resmap.ResMap
+		expected map[resid.ResId]string
+	}{
+		{
+			name: "it should disambiguate filenames of resources with the same kind and name",
+			input: resmap.ResMap{
+				resid.NewResIdWithPrefixNamespace(deploy, "web", "", "staging"):    newResource("apps/v1", "Deployment", "web", "staging"),
+				resid.NewResIdWithPrefixNamespace(deploy, "web", "", "production"): newResource("apps/v1", "Deployment", "web", "production"),
+				resid.NewResIdWithPrefixNamespace(extDeploy, "api", "", ""):        newResource("extensions/v1beta1", "Deployment", "api", ""),
+				resid.NewResIdWithPrefixNamespace(deploy, "api", "", ""):           newResource("apps/v1", "Deployment", "api", ""),
+				resid.NewResId(service, "web"):                                     newResource("v1", "Service", "web", ""),
+			},
+			expected: map[resid.ResId]string{
+				resid.NewResIdWithPrefixNamespace(deploy, "web", "", "staging"):    "web-staging-deploy.yaml",
+				resid.NewResIdWithPrefixNamespace(deploy, "web", "", "production"): "web-production-deploy.yaml",
+				resid.NewResIdWithPrefixNamespace(extDeploy, "api", "", ""):        "api-extensions-v1beta1-deploy.yaml",
+				resid.NewResIdWithPrefixNamespace(deploy, "api", "", ""):           "api-apps-v1-deploy.yaml",
+				resid.NewResId(service, "web"):                                     "web-svc.yaml",
+			},
+		},
+	} {
+		t.Run(fmt.Sprintf("%s", test.name), func(t *testing.T) {
+			output, err := GetResourceFileNames(test.input)
+			if err != nil {
+				t.Fatalf("expected no error, got:\n %v", err)
+			}
+			if diff := pretty.Compare(output, test.expected); diff != "" {
+				t.Errorf("%s, diff: (-got +want)\n%s", test.name, diff)
+			}
+		})
+	}
+}
+
 func TestGetKindAbbreviation(t *testing.T) {
 	for _, test := range []struct {
 		name     string
@@ -196,3 +252,60 @@ func TestRecursivelyRemoveKey(t *testing.T) {
 		})
 	}
 }
+
+func TestRecursivelyFindKey(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		input    *recursivelyRemoveKeyArgs
+		expected []interface{}
+	}{
+		{
+			name: "it should find the key in all the maps named after the path",
+			input: &recursivelyRemoveKeyArgs{
+				path: "labels",
+				key:  "label2",
+				obj: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"labels": map[string]interface{}{
+							"label1": "value1",
+							"label2": "value2",
+						},
+					},
+					"spec": map[string]interface{}{
+						"containers": []interface{}{
+							map[string]interface{}{
+								"metadata": map[string]interface{}{
+									"labels": map[string]interface{}{
+										"label2": "value3",
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+			expected: []interface{}{"value2", "value3"},
+		},
+		{
+			name: "it should return nothing if the key is missing",
+			input: &recursivelyRemoveKeyArgs{
+				path: "labels",
+				key:  "label2",
+				obj: map[string]interface{}{
+					"metadata": map[string]interface{}{
+						"labels": map[string]interface{}{"label1": "value1"},
+					},
+				},
+			},
+		},
+	} {
+		t.Run(fmt.Sprintf("%s", test.name), func(t *testing.T) {
+			output := RecursivelyFindKey(test.input.path, test.input.key, test.input.obj)
+			sort.Slice(output, func(i, j int) bool { return output[i].(string) < output[j].(string) })
+
+			if diff := pretty.Compare(output, test.expected); diff != "" {
+				t.Errorf("%s, diff: (-got +want)\n%s", test.name, diff)
+			}
+		})
+	}
+}